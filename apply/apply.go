@@ -0,0 +1,165 @@
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Stage orders the steps of a plan. Lower stages are applied first.
+type Stage int
+
+const (
+	// StageDisplay changes modes and topology, which reset everything else
+	StageDisplay Stage = iota
+	// StageHDR switches HDR, which resets gamma ramps
+	StageHDR
+	// StageGamma sets gamma ramps
+	StageGamma
+	// StageNight sets night light, which Windows applies on top of gamma
+	StageNight
+)
+
+// Step is one change of a plan, such as "HDR on" or "gamma 75%"
+type Step interface {
+	// Name describes the change, e.g. "HDR on"
+	Name() string
+	// Stage orders the step within the plan
+	Stage() Stage
+	// Capture records the state the step is about to change
+	Capture() error
+	// Preview describes what Apply would change, without changing anything
+	Preview() error
+	// Apply makes the change
+	Apply() error
+	// Rollback restores the state recorded by Capture. It is also called
+	// for the step that failed, which may have been applied partially.
+	Rollback() error
+}
+
+// Plan applies a set of steps as one transaction: if a step fails, every
+// step applied before it, and the failed step itself, is rolled back
+type Plan struct {
+	steps []Step
+
+	// OnApply and OnRollback, when set, are called after each step is
+	// applied or rolled back
+	OnApply    func(step Step, err error)
+	OnRollback func(step Step, err error)
+}
+
+// NewPlan creates a plan with the given steps
+func NewPlan(steps ...Step) *Plan {
+	return &Plan{steps: steps}
+}
+
+// Add adds a step to the plan
+func (p *Plan) Add(step Step) {
+	p.steps = append(p.steps, step)
+}
+
+// Steps returns the steps in the order they are applied: by stage, then in
+// the order they were added
+func (p *Plan) Steps() []Step {
+	steps := append([]Step(nil), p.steps...)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Stage() < steps[j].Stage()
+	})
+	return steps
+}
+
+// Len returns the number of steps in the plan
+func (p *Plan) Len() int {
+	return len(p.steps)
+}
+
+// Run captures the current state for every step, then applies the steps in
+// order. Nothing is changed when a capture fails. When a step fails, the
+// applied steps are rolled back in reverse order and an *Error is returned.
+func (p *Plan) Run() error {
+	steps := p.Steps()
+
+	for _, s := range steps {
+		if err := s.Capture(); err != nil {
+			return fmt.Errorf("failed to read current state for %s, nothing was changed: %w", s.Name(), err)
+		}
+	}
+
+	for i, s := range steps {
+		err := s.Apply()
+		if p.OnApply != nil {
+			p.OnApply(s, err)
+		}
+		if err != nil {
+			return p.rollback(steps[:i+1], err)
+		}
+	}
+	return nil
+}
+
+// Preview captures the current state for every step, then previews the
+// steps in the order Run would apply them. Nothing is changed. Steps that
+// can't be previewed don't stop the others; their errors are joined.
+func (p *Plan) Preview() error {
+	steps := p.Steps()
+
+	for _, s := range steps {
+		if err := s.Capture(); err != nil {
+			return fmt.Errorf("failed to read current state for %s: %w", s.Name(), err)
+		}
+	}
+
+	var errs []error
+	for _, s := range steps {
+		if err := s.Preview(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rollback undoes steps in reverse order, the last one being the step that
+// failed with err
+func (p *Plan) rollback(steps []Step, err error) *Error {
+	e := &Error{Step: steps[len(steps)-1].Name(), Err: err}
+
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		rerr := s.Rollback()
+		if p.OnRollback != nil {
+			p.OnRollback(s, rerr)
+		}
+		if rerr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), rerr))
+		} else {
+			e.RolledBack = append(e.RolledBack, s.Name())
+		}
+	}
+	e.RollbackErr = errors.Join(errs...)
+	return e
+}
+
+// Error is returned by Run when a step fails
+type Error struct {
+	Step        string   // Name of the failed step
+	Err         error    // Why it failed
+	RolledBack  []string // Steps restored to their previous state, in rollback order
+	RollbackErr error    // Steps that couldn't be restored, nil if all were
+}
+
+func (e *Error) Error() string {
+	msg := e.Step + " failed: " + e.Err.Error()
+	if len(e.RolledBack) > 0 {
+		msg += "; rolled back " + strings.Join(e.RolledBack, ", ")
+	}
+	if e.RollbackErr != nil {
+		msg += "; rollback failed: " + e.RollbackErr.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,206 @@
+package apply
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var (
+	errCapture  = errors.New("capture failed")
+	errApply    = errors.New("apply failed")
+	errRollback = errors.New("rollback failed")
+	errPreview  = errors.New("preview failed")
+)
+
+// stubStep records its calls in a shared log and fails where told to
+type stubStep struct {
+	name  string
+	stage Stage
+	log   *[]string
+
+	failCapture  bool
+	failApply    bool
+	failRollback bool
+	failPreview  bool
+}
+
+func (s *stubStep) Name() string { return s.name }
+func (s *stubStep) Stage() Stage { return s.stage }
+
+func (s *stubStep) call(op string, fail bool, err error) error {
+	*s.log = append(*s.log, op+" "+s.name)
+	if fail {
+		return err
+	}
+	return nil
+}
+
+func (s *stubStep) Capture() error  { return s.call("capture", s.failCapture, errCapture) }
+func (s *stubStep) Preview() error  { return s.call("preview", s.failPreview, errPreview) }
+func (s *stubStep) Apply() error    { return s.call("apply", s.failApply, errApply) }
+func (s *stubStep) Rollback() error { return s.call("rollback", s.failRollback, errRollback) }
+
+// newStubs returns a night light, gamma, HDR and mode step, added out of
+// stage order
+func newStubs(log *[]string) (night, gamma, hdr, mode *stubStep) {
+	night = &stubStep{name: "night", stage: StageNight, log: log}
+	gamma = &stubStep{name: "gamma", stage: StageGamma, log: log}
+	hdr = &stubStep{name: "hdr", stage: StageHDR, log: log}
+	mode = &stubStep{name: "mode", stage: StageDisplay, log: log}
+	return
+}
+
+// callbacks records the OnApply and OnRollback calls of a plan
+func callbacks(p *Plan) *[]string {
+	var calls []string
+	record := func(op string, s Step, err error) {
+		if err != nil {
+			op += " error"
+		}
+		calls = append(calls, op+" "+s.Name())
+	}
+	p.OnApply = func(s Step, err error) { record("applied", s, err) }
+	p.OnRollback = func(s Step, err error) { record("rolled back", s, err) }
+	return &calls
+}
+
+func TestRun(t *testing.T) {
+	var log []string
+	night, gamma, hdr, mode := newStubs(&log)
+	p := NewPlan(night, gamma)
+	p.Add(hdr)
+	p.Add(mode)
+	calls := callbacks(p)
+
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"capture mode", "capture hdr", "capture gamma", "capture night",
+		"apply mode", "apply hdr", "apply gamma", "apply night",
+	}
+	if !slices.Equal(log, want) {
+		t.Errorf("calls = %v, want %v", log, want)
+	}
+	if want := []string{"applied mode", "applied hdr", "applied gamma", "applied night"}; !slices.Equal(*calls, want) {
+		t.Errorf("callbacks = %v, want %v", *calls, want)
+	}
+	if p.Len() != 4 {
+		t.Errorf("Len = %d, want 4", p.Len())
+	}
+}
+
+func TestRunCaptureFails(t *testing.T) {
+	var log []string
+	night, gamma, hdr, mode := newStubs(&log)
+	gamma.failCapture = true
+	p := NewPlan(night, gamma, hdr, mode)
+	calls := callbacks(p)
+
+	err := p.Run()
+	var e *Error
+	if !errors.Is(err, errCapture) || errors.As(err, &e) || !strings.Contains(err.Error(), "nothing was changed") {
+		t.Fatalf("Run = %v, want a capture error before anything changed", err)
+	}
+	if want := []string{"capture mode", "capture hdr", "capture gamma"}; !slices.Equal(log, want) {
+		t.Errorf("calls = %v, want %v", log, want)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("callbacks = %v, want none", *calls)
+	}
+}
+
+func TestRunApplyFails(t *testing.T) {
+	var log []string
+	night, gamma, hdr, mode := newStubs(&log)
+	gamma.failApply = true
+	p := NewPlan(night, gamma, hdr, mode)
+	calls := callbacks(p)
+
+	err := p.Run()
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, errApply) {
+		t.Fatalf("Run = %v, want an *Error wrapping the apply error", err)
+	}
+	if e.Step != "gamma" || !slices.Equal(e.RolledBack, []string{"gamma", "hdr", "mode"}) || e.RollbackErr != nil {
+		t.Errorf("Error = %+v, want gamma failed and gamma, hdr, mode rolled back", e)
+	}
+
+	want := []string{
+		"capture mode", "capture hdr", "capture gamma", "capture night",
+		"apply mode", "apply hdr", "apply gamma",
+		"rollback gamma", "rollback hdr", "rollback mode",
+	}
+	if !slices.Equal(log, want) {
+		t.Errorf("calls = %v, want %v", log, want)
+	}
+	want = []string{
+		"applied mode", "applied hdr", "applied error gamma",
+		"rolled back gamma", "rolled back hdr", "rolled back mode",
+	}
+	if !slices.Equal(*calls, want) {
+		t.Errorf("callbacks = %v, want %v", *calls, want)
+	}
+}
+
+func TestRunRollbackFails(t *testing.T) {
+	var log []string
+	night, gamma, hdr, mode := newStubs(&log)
+	night.failApply = true
+	hdr.failRollback = true
+	p := NewPlan(night, gamma, hdr, mode)
+	calls := callbacks(p)
+
+	err := p.Run()
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Run = %v, want an *Error", err)
+	}
+	// A failed rollback doesn't stop the steps before it from being restored
+	if e.Step != "night" || !slices.Equal(e.RolledBack, []string{"night", "gamma", "mode"}) || !errors.Is(e.RollbackErr, errRollback) {
+		t.Errorf("Error = %+v, want night failed, hdr not restored", e)
+	}
+	if msg := e.Error(); !strings.Contains(msg, "rolled back night, gamma, mode") || !strings.Contains(msg, "rollback failed: hdr: ") {
+		t.Errorf("Error() = %q", msg)
+	}
+
+	want := []string{
+		"applied mode", "applied hdr", "applied gamma", "applied error night",
+		"rolled back night", "rolled back gamma", "rolled back error hdr", "rolled back mode",
+	}
+	if !slices.Equal(*calls, want) {
+		t.Errorf("callbacks = %v, want %v", *calls, want)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	var log []string
+	night, gamma, hdr, mode := newStubs(&log)
+	hdr.failPreview = true
+	night.failPreview = true
+	p := NewPlan(night, gamma, hdr, mode)
+	calls := callbacks(p)
+
+	err := p.Preview()
+	if !errors.Is(err, errPreview) || !strings.Contains(err.Error(), "hdr: ") || !strings.Contains(err.Error(), "night: ") {
+		t.Errorf("Preview = %v, want the hdr and night errors", err)
+	}
+	want := []string{
+		"capture mode", "capture hdr", "capture gamma", "capture night",
+		"preview mode", "preview hdr", "preview gamma", "preview night",
+	}
+	if !slices.Equal(log, want) {
+		t.Errorf("calls = %v, want %v", log, want)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("callbacks = %v, want none", *calls)
+	}
+
+	log = nil
+	mode.failCapture = true
+	if err := p.Preview(); !errors.Is(err, errCapture) || !slices.Equal(log, []string{"capture mode"}) {
+		t.Errorf("Preview with a failed capture = %v after %v", err, log)
+	}
+}
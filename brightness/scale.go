@@ -0,0 +1,101 @@
+// Package brightness maps brightness percentages to the levels of the
+// backends that dim a display: the gamma ramp, DDC/CI and the HDR SDR slider
+package brightness
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Scale maps a brightness percentage to a fraction of a backend's peak
+// luminance
+type Scale string
+
+const (
+	Linear    Scale = "linear"    // The percentage is the backend's own control, e.g. the SDR slider position
+	Lightness Scale = "lightness" // The percentage is CIE L* lightness
+	Power     Scale = "power"     // The percentage is the luminance raised to 1/PowerExponent
+)
+
+// Scales are the scales in the order they are listed in help
+var Scales = []Scale{Linear, Lightness, Power}
+
+// PowerExponent is the exponent of the Power scale
+const PowerExponent = 2.2
+
+// CIE L* constants: below lightnessKnee lightness is linear in luminance
+const (
+	lightnessKappa = 24389.0 / 27
+	lightnessKnee  = 8.0
+)
+
+// ParseScale parses a scale name
+func ParseScale(s string) (Scale, error) {
+	scale := Scale(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Scales {
+		if scale == known {
+			return scale, nil
+		}
+	}
+	return "", fmt.Errorf("invalid brightness scale: %s (must be 'linear', 'lightness', or 'power')", s)
+}
+
+// String returns the scale name, "linear" for the zero value
+func (s Scale) String() string {
+	if s == "" {
+		return string(Linear)
+	}
+	return string(s)
+}
+
+// Set implements flag.Value
+func (s *Scale) Set(value string) error {
+	scale, err := ParseScale(value)
+	if err != nil {
+		return err
+	}
+	*s = scale
+	return nil
+}
+
+// IsLinear reports whether the scale leaves percentages as they are
+func (s Scale) IsLinear() bool {
+	return s == "" || s == Linear
+}
+
+// Level maps a brightness percentage (0-100) to a fraction (0-1) of the
+// backend's peak luminance, so that 50% looks half as bright with a
+// perceptual scale. Backends convert it to their own control, clamped to
+// what they can reach; with Linear they take the percentage as that control.
+func (s Scale) Level(percent float64) float64 {
+	p := math.Max(0, math.Min(100, percent))
+	switch s {
+	case Lightness:
+		if p <= lightnessKnee {
+			return p / lightnessKappa
+		}
+		return math.Pow((p+16)/116, 3)
+	case Power:
+		return math.Pow(p/100, PowerExponent)
+	default:
+		return p / 100
+	}
+}
+
+// Percent is the inverse of Level: it returns the brightness percentage of a
+// fraction of the backend's peak luminance
+func (s Scale) Percent(level float64) float64 {
+	l := math.Max(0, math.Min(1, level))
+	switch s {
+	case Lightness:
+		if l <= lightnessKnee/lightnessKappa {
+			return l * lightnessKappa
+		}
+		return 116*math.Cbrt(l) - 16
+	case Power:
+		return 100 * math.Pow(l, 1/PowerExponent)
+	default:
+		return l * 100
+	}
+}
@@ -0,0 +1,96 @@
+package brightness
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		scale   Scale
+		percent float64
+		want    float64
+	}{
+		{Linear, 0, 0},
+		{Linear, 50, 0.5},
+		{"", 25, 0.25},
+
+		// CIE L* to relative luminance Y
+		{Lightness, 0, 0},
+		{Lightness, 8, 0.008856}, // The knee, 8/κ
+		{Lightness, 4, 0.004428}, // Linear below the knee
+		{Lightness, 18, 0.025180},
+		{Lightness, 50, 0.184187},
+		{Lightness, 75, 0.482781},
+		{Lightness, 100, 1},
+
+		{Power, 0, 0},
+		{Power, 10, 0.006310},
+		{Power, 50, 0.217638},
+		{Power, 80, 0.612066},
+		{Power, 100, 1},
+
+		// Out of range percentages are clamped
+		{Lightness, -10, 0},
+		{Power, 150, 1},
+		{Linear, 101, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.scale.Level(tt.percent); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: Level(%v) = %.6f, want %.6f", tt.scale, tt.percent, got, tt.want)
+		}
+	}
+
+	// Both sides of the knee meet
+	below, above := lightnessKnee/lightnessKappa, math.Pow((lightnessKnee+16)/116, 3)
+	if math.Abs(below-above) > 1e-6 {
+		t.Errorf("L* knee: %v below, %v above", below, above)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	for _, s := range Scales {
+		for p := 0.0; p <= 100; p += 0.5 {
+			if got := s.Percent(s.Level(p)); math.Abs(got-p) > 1e-9 {
+				t.Errorf("%s: Percent(Level(%v)) = %v", s, p, got)
+			}
+		}
+		if s.Percent(-0.5) != 0 || s.Percent(2) != 100 {
+			t.Errorf("%s: Percent(-0.5) = %v, Percent(2) = %v, want 0 and 100", s, s.Percent(-0.5), s.Percent(2))
+		}
+	}
+}
+
+func TestParseScale(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Scale
+		ok   bool
+	}{
+		{"linear", Linear, true},
+		{" Lightness ", Lightness, true},
+		{"POWER", Power, true},
+		{"", "", false},
+		{"log", "", false},
+		{"L*", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseScale(tt.s)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseScale(%q) = %q, %v, want %q", tt.s, got, err, tt.want)
+		}
+	}
+
+	s := Power
+	if err := s.Set("cubic"); err == nil || s != Power {
+		t.Errorf("Set(cubic) = %v, scale %s, want an error and the scale kept", err, s)
+	}
+	if err := s.Set("lightness"); err != nil || s != Lightness {
+		t.Errorf("Set(lightness) = %v, scale %s", err, s)
+	}
+
+	var zero Scale
+	if zero.String() != "linear" || !zero.IsLinear() || !Linear.IsLinear() || Power.IsLinear() {
+		t.Errorf("zero scale is %q, IsLinear %v", zero, zero.IsLinear())
+	}
+}
@@ -0,0 +1,91 @@
+package calibrate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jipaix/lumos/gamma"
+)
+
+// TargetGamma is the gamma the fitted curve makes the display behave like
+const TargetGamma = 2.2
+
+// Answers are what the user saw on the test patterns
+type Answers struct {
+	BlackPatch int     // Lowest visible black level patch, 1-based
+	WhitePatch int     // Lowest white level patch told apart from white, 1-based
+	Gamma      float64 // Gamma whose patch blended into its stripes
+}
+
+// Params are the display parameters fitted from the answers
+type Params struct {
+	BlackLevel   float64 // Output for black, raised to the darkest invisible level
+	WhiteLevel   float64 // Output for white, lowered to the brightest level that isn't clipped
+	DisplayGamma float64 // Measured gamma of the display
+}
+
+// Fit computes the display parameters from the answers
+func Fit(a Answers) (Params, error) {
+	if a.BlackPatch < 1 || a.BlackPatch > len(BlackSteps) {
+		return Params{}, fmt.Errorf("black level patch must be between 1 and %d, got %d", len(BlackSteps), a.BlackPatch)
+	}
+	if a.WhitePatch < 1 || a.WhitePatch > len(WhiteSteps) {
+		return Params{}, fmt.Errorf("white level patch must be between 1 and %d, got %d", len(WhiteSteps), a.WhitePatch)
+	}
+	if a.Gamma < 1 || a.Gamma > 3 {
+		return Params{}, fmt.Errorf("gamma must be between 1.0 and 3.0, got %g", a.Gamma)
+	}
+
+	p := Params{WhiteLevel: 1, DisplayGamma: a.Gamma}
+
+	// Levels below the first visible patch look black, so lift black to the
+	// last invisible one and the darkest shades become visible
+	if a.BlackPatch > 1 {
+		p.BlackLevel = float64(BlackSteps[a.BlackPatch-2]) / 255
+	}
+	// Levels above the first patch told apart from white clip, so white only
+	// needs to reach the last patch that still looked white
+	if a.WhitePatch > 1 {
+		p.WhiteLevel = float64(WhiteSteps[a.WhitePatch-2]) / 255
+	}
+	return p, nil
+}
+
+// Eval returns the output for an input in [0, 1]: the display gamma is
+// corrected to TargetGamma between the fitted black and white levels
+func (p Params) Eval(x float64) float64 {
+	x = math.Max(0, math.Min(1, x))
+	return p.BlackLevel + (p.WhiteLevel-p.BlackLevel)*math.Pow(x, TargetGamma/p.DisplayGamma)
+}
+
+// curveInputs are the control point inputs of the fitted curve, denser in
+// the shadows where the correction bends most
+var curveInputs = []float64{0, 1.0 / 64, 1.0 / 32, 1.0 / 16, 1.0 / 8, 0.25, 0.375, 0.5, 0.625, 0.75, 0.875, 1}
+
+// Curve returns the fitted correction as control points for the curve layer
+func (p Params) Curve() []gamma.CurvePoint {
+	points := make([]gamma.CurvePoint, len(curveInputs))
+	for i, x := range curveInputs {
+		points[i] = gamma.CurvePoint{X: round4(x), Y: round4(p.Eval(x))}
+	}
+	return points
+}
+
+// CurveString returns the control points in the --curve syntax
+func (p Params) CurveString() string {
+	var parts []string
+	for _, pt := range p.Curve() {
+		parts = append(parts, fmt.Sprintf("%g:%g", pt.X, pt.Y))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p Params) String() string {
+	return fmt.Sprintf("black level %.1f%%, white level %.1f%%, display gamma %.2f (corrected to %.1f)",
+		p.BlackLevel*100, p.WhiteLevel*100, p.DisplayGamma, TargetGamma)
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,107 @@
+package calibrate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jipaix/lumos/gamma"
+)
+
+// viewer answers the patterns like a user in front of a display that shows
+// levels up to crush as black, levels from clip up as white, and has the
+// given gamma
+func viewer(crush, clip uint8, g float64) Answers {
+	a := Answers{BlackPatch: len(BlackSteps), WhitePatch: len(WhiteSteps)}
+	for i, level := range BlackSteps {
+		if level > crush {
+			a.BlackPatch = i + 1
+			break
+		}
+	}
+	for i, level := range WhiteSteps {
+		if level < clip {
+			a.WhitePatch = i + 1
+			break
+		}
+	}
+
+	// The center square closest to the stripes' half luminance blends in
+	best := math.Inf(1)
+	for _, check := range GammaChecks {
+		if d := math.Abs(math.Pow(float64(GammaLevel(check))/255, g) - 0.5); d < best {
+			best, a.Gamma = d, check
+		}
+	}
+	return a
+}
+
+func TestFitRecovers(t *testing.T) {
+	tests := []struct {
+		name        string
+		crush, clip uint8
+		gamma       float64
+		want        Params
+	}{
+		{"ideal display", 0, 255, 2.2, Params{BlackLevel: 0, WhiteLevel: 1, DisplayGamma: 2.2}},
+		{"crushed blacks", 8, 255, 2.2, Params{BlackLevel: 8.0 / 255, WhiteLevel: 1, DisplayGamma: 2.2}},
+		{"crush between steps", 9, 255, 2.4, Params{BlackLevel: 8.0 / 255, WhiteLevel: 1, DisplayGamma: 2.4}},
+		{"clipped whites", 0, 245, 1.8, Params{BlackLevel: 0, WhiteLevel: 245.0 / 255, DisplayGamma: 1.8}},
+		{"both", 14, 240, 2.2, Params{BlackLevel: 14.0 / 255, WhiteLevel: 241.0 / 255, DisplayGamma: 2.2}},
+		{"nothing visible", 255, 0, 2.4, Params{BlackLevel: 22.0 / 255, WhiteLevel: 233.0 / 255, DisplayGamma: 2.4}},
+	}
+	for _, tt := range tests {
+		a := viewer(tt.crush, tt.clip, tt.gamma)
+		got, err := Fit(a)
+		if err != nil {
+			t.Errorf("%s: Fit(%+v) = %v", tt.name, a, err)
+			continue
+		}
+		if math.Abs(got.BlackLevel-tt.want.BlackLevel) > 1e-9 || math.Abs(got.WhiteLevel-tt.want.WhiteLevel) > 1e-9 || got.DisplayGamma != tt.want.DisplayGamma {
+			t.Errorf("%s: Fit(%+v) = %+v, want %+v", tt.name, a, got, tt.want)
+		}
+		if tt.crush < 255 && (got.BlackLevel*255 > float64(tt.crush) || got.WhiteLevel*255 < float64(tt.clip)) {
+			t.Errorf("%s: fitted levels %v-%v lift black past %d or keep white above %d", tt.name, got.BlackLevel*255, got.WhiteLevel*255, tt.crush, tt.clip)
+		}
+	}
+}
+
+func TestFitCorrectsGamma(t *testing.T) {
+	for _, g := range GammaChecks {
+		p, err := Fit(viewer(0, 255, g))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Eval(0) != 0 || p.Eval(1) != 1 {
+			t.Errorf("gamma %v: Eval(0) = %v, Eval(1) = %v, want 0 and 1", g, p.Eval(0), p.Eval(1))
+		}
+		// The display's gamma applied to the output gives TargetGamma
+		for _, x := range []float64{0.1, 0.25, 0.5, 0.75} {
+			if got, want := math.Pow(p.Eval(x), g), math.Pow(x, TargetGamma); math.Abs(got-want) > 1e-9 {
+				t.Errorf("gamma %v: luminance at %v = %v, want %v", g, x, got, want)
+			}
+		}
+
+		points, err := gamma.ParseCurvePoints(p.CurveString())
+		if err != nil {
+			t.Errorf("gamma %v: CurveString %q: %v", g, p.CurveString(), err)
+		} else if len(points) != len(curveInputs) {
+			t.Errorf("gamma %v: %d curve points, want %d", g, len(points), len(curveInputs))
+		}
+	}
+}
+
+func TestFitInvalid(t *testing.T) {
+	tests := []Answers{
+		{BlackPatch: 0, WhitePatch: 1, Gamma: 2.2},
+		{BlackPatch: len(BlackSteps) + 1, WhitePatch: 1, Gamma: 2.2},
+		{BlackPatch: 1, WhitePatch: 0, Gamma: 2.2},
+		{BlackPatch: 1, WhitePatch: len(WhiteSteps) + 1, Gamma: 2.2},
+		{BlackPatch: 1, WhitePatch: 1, Gamma: 0.9},
+		{BlackPatch: 1, WhitePatch: 1, Gamma: 3.1},
+	}
+	for _, a := range tests {
+		if _, err := Fit(a); err == nil {
+			t.Errorf("Fit(%+v) = nil error", a)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package calibrate
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Pattern is a test image shown full screen during calibration
+type Pattern struct {
+	Name     string // File name without extension, e.g. "black-level"
+	Question string // What to look for, empty for reference patterns
+	Image    *image.NRGBA
+}
+
+// BlackSteps are the gray levels of the black level patches, numbered from 1
+var BlackSteps = []uint8{2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 24}
+
+// WhiteSteps are the gray levels of the white level patches, numbered from 1
+var WhiteSteps = []uint8{253, 251, 249, 247, 245, 243, 241, 239, 237, 235, 233, 231}
+
+// GammaChecks are the gammas of the gamma check patches. A patch matches
+// its stripes when the display has that gamma.
+var GammaChecks = []float64{1.8, 2.2, 2.4}
+
+// Patterns draws every test pattern at the given size
+func Patterns(width, height int) ([]Pattern, error) {
+	if width < 320 || height < 240 {
+		return nil, fmt.Errorf("pattern size must be at least 320x240, got %dx%d", width, height)
+	}
+	return []Pattern{
+		{Name: "black-level", Question: "What is the lowest numbered patch you can see on the black background?", Image: stepsPattern(width, height, 0, BlackSteps)},
+		{Name: "white-level", Question: "What is the lowest numbered patch you can tell apart from the white background?", Image: stepsPattern(width, height, 255, WhiteSteps)},
+		{Name: "gamma", Question: "Squint or step back: which gamma's center square blends into its stripes (e.g. 2.2, or 2.0 if between 1.8 and 2.2)?", Image: gammaPattern(width, height)},
+		{Name: "grayscale", Image: grayscalePattern(width, height)},
+		{Name: "color-bars", Image: colorBarsPattern(width, height)},
+	}, nil
+}
+
+// stepsPattern draws numbered patches at the given levels on a background
+func stepsPattern(width, height int, background uint8, steps []uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	fill(img, img.Bounds(), gray(background))
+
+	cols := 6
+	rows := (len(steps) + cols - 1) / cols
+	cellW, cellH := width/cols, height/rows
+	scale := max(cellH/40, 2)
+
+	label := gray(128)
+	for i, level := range steps {
+		x, y := (i%cols)*cellW, (i/cols)*cellH
+		patch := image.Rect(x+cellW/6, y+cellH/6, x+cellW*5/6, y+cellH*2/3)
+		fill(img, patch, gray(level))
+		drawText(img, x+cellW/6, y+cellH*2/3+scale*2, scale, fmt.Sprint(i+1), label)
+	}
+	return img
+}
+
+// gammaPattern draws one column per gamma: alternating black and white
+// lines around a solid square whose level is 50% luminance at that gamma
+func gammaPattern(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	fill(img, img.Bounds(), gray(128))
+
+	cellW := width / len(GammaChecks)
+	scale := max(height/120, 2)
+	for i, g := range GammaChecks {
+		x := i * cellW
+		stripes := image.Rect(x+cellW/8, height/8, x+cellW*7/8, height*3/4)
+		for y := stripes.Min.Y; y < stripes.Max.Y; y++ {
+			level := uint8(0)
+			if y%2 == 0 {
+				level = 255
+			}
+			fill(img, image.Rect(stripes.Min.X, y, stripes.Max.X, y+1), gray(level))
+		}
+
+		// Solid center with the same luminance as the stripes at gamma g
+		w, h := stripes.Dx()/3, stripes.Dy()/3
+		center := image.Rect(stripes.Min.X+w, stripes.Min.Y+h, stripes.Max.X-w, stripes.Max.Y-h)
+		fill(img, center, gray(GammaLevel(g)))
+
+		drawText(img, stripes.Min.X, stripes.Max.Y+scale*4, scale, fmt.Sprintf("%.1f", g), gray(0))
+	}
+	return img
+}
+
+// GammaLevel returns the gray level with half the luminance of white on a
+// display with the given gamma
+func GammaLevel(g float64) uint8 {
+	return uint8(math.Round(255 * math.Pow(0.5, 1/g)))
+}
+
+// grayscalePattern draws a smooth ramp above 32 steps, to spot banding and
+// color casts
+func grayscalePattern(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		level := uint8(x * 255 / (width - 1))
+		fill(img, image.Rect(x, 0, x+1, height/2), gray(level))
+
+		step := uint8(x * 32 / width * 255 / 31)
+		fill(img, image.Rect(x, height/2, x+1, height), gray(step))
+	}
+	return img
+}
+
+// colorBarsPattern draws 75% color bars: white, yellow, cyan, green,
+// magenta, red, blue, black
+func colorBarsPattern(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	const l = 191 // 75%
+	bars := []color.NRGBA{
+		{l, l, l, 255}, {l, l, 0, 255}, {0, l, l, 255}, {0, l, 0, 255},
+		{l, 0, l, 255}, {l, 0, 0, 255}, {0, 0, l, 255}, {0, 0, 0, 255},
+	}
+	for i, c := range bars {
+		fill(img, image.Rect(i*width/len(bars), 0, (i+1)*width/len(bars), height), c)
+	}
+	return img
+}
+
+func gray(level uint8) color.NRGBA {
+	return color.NRGBA{level, level, level, 255}
+}
+
+func fill(img *image.NRGBA, r image.Rectangle, c color.NRGBA) {
+	draw.Draw(img, r, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+// digits is a 3x5 bitmap font for the labels, one row per string
+var digits = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+}
+
+// drawText draws digits with each font pixel as a scale x scale square
+func drawText(img *image.NRGBA, x, y, scale int, text string, c color.NRGBA) {
+	for _, r := range text {
+		glyph, ok := digits[r]
+		if !ok {
+			continue
+		}
+		for row, line := range glyph {
+			for col, px := range line {
+				if px == '#' {
+					fill(img, image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale), c)
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
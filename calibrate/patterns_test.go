@@ -0,0 +1,125 @@
+package calibrate
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestGammaLevel(t *testing.T) {
+	tests := []struct {
+		gamma float64
+		want  uint8
+	}{
+		{1, 128},
+		{1.8, 174},
+		{2.2, 186},
+		{2.4, 191},
+	}
+	for _, tt := range tests {
+		got := GammaLevel(tt.gamma)
+		if got != tt.want {
+			t.Errorf("GammaLevel(%v) = %d, want %d", tt.gamma, got, tt.want)
+		}
+		// Half the luminance of white, to within one level
+		lum := math.Pow(float64(got)/255, tt.gamma)
+		if step := math.Pow(float64(got+1)/255, tt.gamma) - lum; math.Abs(lum-0.5) > step {
+			t.Errorf("GammaLevel(%v) has luminance %.4f, want 0.5", tt.gamma, lum)
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	const width, height = 1920, 1080
+	patterns, err := Patterns(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"black-level", "white-level", "gamma", "grayscale", "color-bars"}
+	if len(patterns) != len(names) {
+		t.Fatalf("%d patterns, want %d", len(patterns), len(names))
+	}
+	byName := map[string]Pattern{}
+	for i, p := range patterns {
+		if p.Name != names[i] {
+			t.Errorf("pattern %d is %q, want %q", i, p.Name, names[i])
+		}
+		if b := p.Image.Bounds(); b.Dx() != width || b.Dy() != height || b.Min.X != 0 || b.Min.Y != 0 {
+			t.Errorf("%s: bounds %v, want %dx%d", p.Name, b, width, height)
+		}
+		if (p.Question != "") != (i < 3) {
+			t.Errorf("%s: question %q", p.Name, p.Question)
+		}
+		byName[p.Name] = p
+	}
+
+	// Patch centers in the 6 column grid of the level patterns
+	cellW, cellH := width/6, height/2
+	for _, tt := range []struct {
+		name       string
+		background uint8
+		steps      []uint8
+	}{
+		{"black-level", 0, BlackSteps},
+		{"white-level", 255, WhiteSteps},
+	} {
+		img := byName[tt.name].Image
+		checkGray(t, tt.name+" background", img.NRGBAAt(2, 2), tt.background)
+		for i, level := range tt.steps {
+			x, y := (i%6)*cellW+cellW/2, (i/6)*cellH+cellH*5/12
+			checkGray(t, tt.name+" patch", img.NRGBAAt(x, y), level)
+		}
+	}
+
+	img := byName["gamma"].Image
+	cellW = width / len(GammaChecks)
+	for i, g := range GammaChecks {
+		// Even rows are white
+		x, y := i*cellW+cellW/8, height/8+height/8%2
+		checkGray(t, "gamma stripe", img.NRGBAAt(x, y), 255)
+		checkGray(t, "gamma stripe", img.NRGBAAt(x, y+1), 0)
+		checkGray(t, "gamma center", img.NRGBAAt(i*cellW+cellW/2, height*7/16), GammaLevel(g))
+	}
+
+	img = byName["grayscale"].Image
+	checkGray(t, "smooth ramp start", img.NRGBAAt(0, 0), 0)
+	checkGray(t, "smooth ramp end", img.NRGBAAt(width-1, 0), 255)
+	steps := map[uint8]bool{}
+	for x := range width {
+		c := img.NRGBAAt(x, height-1)
+		if x > 0 && c.R < img.NRGBAAt(x-1, height-1).R {
+			t.Fatalf("stepped ramp decreases at x = %d", x)
+		}
+		steps[c.R] = true
+	}
+	if len(steps) != 32 || !steps[0] || !steps[255] {
+		t.Errorf("stepped ramp has %d levels, want 32 from 0 to 255", len(steps))
+	}
+
+	img = byName["color-bars"].Image
+	bars := []color.NRGBA{
+		{191, 191, 191, 255}, {191, 191, 0, 255}, {0, 191, 191, 255}, {0, 191, 0, 255},
+		{191, 0, 191, 255}, {191, 0, 0, 255}, {0, 0, 191, 255}, {0, 0, 0, 255},
+	}
+	for i, want := range bars {
+		if got := img.NRGBAAt(i*width/8+width/16, height/2); got != want {
+			t.Errorf("color bar %d = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestPatternsTooSmall(t *testing.T) {
+	for _, size := range [][2]int{{319, 240}, {320, 239}, {0, 0}} {
+		if _, err := Patterns(size[0], size[1]); err == nil {
+			t.Errorf("Patterns(%d, %d) = nil error", size[0], size[1])
+		}
+	}
+}
+
+func checkGray(t *testing.T, what string, c color.NRGBA, want uint8) {
+	t.Helper()
+	if c != gray(want) {
+		t.Errorf("%s = %v, want gray %d", what, c, want)
+	}
+}
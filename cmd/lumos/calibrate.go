@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/apply"
+	"github.com/jipaix/lumos/calibrate"
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/gamma"
+	"github.com/jipaix/lumos/mode"
+)
+
+func runCalibrate(args []string) error {
+	// patterns has its own flags
+	if len(args) > 0 && args[0] == "patterns" {
+		return runCalibratePatterns(args[1:])
+	}
+
+	fs := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	var sel display.Selector
+	fs.Var(&sel, "display", "Display to calibrate (default: the only display)")
+	dir := fs.String("dir", filepath.Join(os.TempDir(), "lumos-calibration"), "Directory for the test patterns")
+	save := fs.String("save", "", "Also save the fitted ramp to a .icc, .cal, .cube or .csv file")
+	fs.Usage = printCalibrateHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		printCalibrateHelp()
+		return nil
+	}
+
+	displays, err := sel.Resolve()
+	if err != nil {
+		return err
+	}
+	if len(displays) != 1 {
+		return fmt.Errorf("select one display to calibrate with --display (%d selected)", len(displays))
+	}
+	d := displays[0]
+
+	width, height := 1920, 1080
+	if m, err := mode.Current(d.DeviceName); err == nil {
+		width, height = m.Width, m.Height
+	}
+	patterns, err := writePatterns(*dir, width, height)
+	if err != nil {
+		return err
+	}
+
+	answers, err := measure(d, patterns, *dir)
+	if err != nil {
+		return err
+	}
+	params, err := calibrate.Fit(answers)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", d.Label(), params)
+
+	// The curve was fitted on a linear ramp, so it replaces the calibration
+	step, err := newGammaLayerStep(gamma.LayerCalibration, gamma.CalibrationNone, display.Only(d), "")
+	if err != nil {
+		return err
+	}
+	if err := step.setLayer(gamma.LayerCurve, params.CurveString()); err != nil {
+		return err
+	}
+	plan := apply.NewPlan()
+	plan.Add(step)
+	if err := runPlan(plan); err != nil {
+		return err
+	}
+
+	if *save != "" {
+		points := gamma.Pipeline{Curve: &gamma.CurveLayer{Points: [][]gamma.CurvePoint{params.Curve(), params.Curve(), params.Curve()}}}
+		ramp, err := points.Ramp()
+		if err != nil {
+			return err
+		}
+		if err := gamma.WriteTable(*save, gamma.TableFromRamp(&ramp), "lumos calibration of "+d.Label()); err != nil {
+			return err
+		}
+		fmt.Printf("%s: calibration saved to %s\n", d.Label(), *save)
+	}
+	return nil
+}
+
+// measure shows the patterns on a linear ramp and asks what the user sees.
+// The previous ramp is restored afterwards.
+func measure(d display.Display, patterns []calibrate.Pattern, dir string) (calibrate.Answers, error) {
+	var a calibrate.Answers
+
+	prev, err := gamma.GetRamp(d)
+	if err != nil {
+		return a, err
+	}
+	identity := gamma.IdentityRamp()
+	results, err := gamma.SetRamp(&identity, "linear ramp for calibration", display.Only(d))
+	if err != nil {
+		return a, err
+	}
+	if err := gamma.Failed(results); err != nil {
+		return a, err
+	}
+	defer func() {
+		results, err := gamma.SetRamp(&prev, "previous gamma", display.Only(d))
+		if err == nil {
+			err = gamma.Failed(results)
+		}
+		if err != nil {
+			fmt.Printf("%s: failed to restore the previous gamma (%v)\n", d.Label(), err)
+		}
+	}()
+
+	in := bufio.NewReader(os.Stdin)
+	for _, p := range patterns {
+		if p.Question == "" {
+			continue
+		}
+		fmt.Printf("\nOpen %s full screen on %s.\n", filepath.Join(dir, p.Name+".png"), d.Label())
+
+		var parse func(string) error
+		switch p.Name {
+		case "black-level":
+			parse = func(s string) (err error) { a.BlackPatch, err = strconv.Atoi(s); return err }
+		case "white-level":
+			parse = func(s string) (err error) { a.WhitePatch, err = strconv.Atoi(s); return err }
+		case "gamma":
+			parse = func(s string) (err error) { a.Gamma, err = strconv.ParseFloat(s, 64); return err }
+		}
+		if err := ask(in, p.Question, parse); err != nil {
+			return a, err
+		}
+	}
+	fmt.Println()
+	return a, nil
+}
+
+// ask prints a question and reads answers until one parses
+func ask(in *bufio.Reader, question string, parse func(string) error) error {
+	for {
+		fmt.Printf("%s ", question)
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return errors.New("calibration cancelled")
+		}
+		if err := parse(strings.TrimSpace(line)); err == nil {
+			return nil
+		}
+		fmt.Println("Please answer with a number.")
+	}
+}
+
+func runCalibratePatterns(args []string) error {
+	fs := flag.NewFlagSet("calibrate patterns", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "Directory to write the patterns to")
+	size := fs.String("size", "1920x1080", "Pattern size like 2560x1440")
+	fs.Usage = printCalibrateHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		printCalibrateHelp()
+		return nil
+	}
+
+	width, height, err := mode.ParseResolution(*size)
+	if err != nil {
+		return err
+	}
+	patterns, err := writePatterns(*dir, width, height)
+	if err != nil {
+		return err
+	}
+	for _, p := range patterns {
+		fmt.Println(filepath.Join(*dir, p.Name+".png"))
+	}
+	return nil
+}
+
+// writePatterns draws the test patterns and writes them as PNG files
+func writePatterns(dir string, width, height int) ([]calibrate.Pattern, error) {
+	patterns, err := calibrate.Patterns(width, height)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	for _, p := range patterns {
+		f, err := os.Create(filepath.Join(dir, p.Name+".png"))
+		if err != nil {
+			return nil, err
+		}
+		err = png.Encode(f, p.Image)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return patterns, nil
+}
+
+func printCalibrateHelp() {
+	fmt.Println("Usage: lumos calibrate [--display <display>] [--save <file>] [--dir <dir>]")
+	fmt.Println("       lumos calibrate patterns [--dir <dir>] [--size <w>x<h>]")
+	fmt.Println()
+	fmt.Println("Calibrate a display by eye. The guided flow switches the display to a linear")
+	fmt.Println("ramp, asks which patches of the black level, white level and gamma patterns you")
+	fmt.Println("can see, and fits a tone curve that corrects the display to gamma 2.2. The curve")
+	fmt.Println("is saved as the display's curve layer and replaces its calibration layer.")
+	fmt.Println("'patterns' only writes the test patterns as PNG files.")
+	fmt.Println()
+	fmt.Println("Options:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --display <display>\tDisplay to calibrate (default: the only display)")
+	fmt.Fprintln(w, "  --save <file>\tAlso save the fitted ramp as .icc, .cal, .cube or .csv")
+	fmt.Fprintln(w, "  --dir <dir>\tDirectory for the test patterns")
+	fmt.Fprintln(w, "  --size <w>x<h>\tPattern size for 'patterns' (default: 1920x1080)")
+	w.Flush()
+}
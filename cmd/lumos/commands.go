@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+)
+
+// command is a lumos subcommand such as "lumos vcp get 10"
+type command struct {
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"calibrate": {usage: "calibrate [patterns]", summary: "Calibrate a display by eye with test patterns", run: runCalibrate},
+		"confirm":   {usage: "confirm", summary: "Keep a change made with --confirm", run: runConfirm},
+		"gamma":     {usage: "gamma [show|set|reset|load|save|plot|apply|extended-range]", summary: "Show or change the layers of the gamma ramp", run: runGamma},
+		"hdr":       {usage: "hdr status|acm|sdr-brightness", summary: "Show HDR state, switch ACM or set SDR brightness", run: runHDR},
+		"list":      {usage: "list", summary: "List connected displays and their IDs", run: runList},
+		"mode":      {usage: "mode [--resolution <w>x<h>] [--refresh <hz>]", summary: "List or change display modes", run: runMode},
+		"power":     {usage: "power on|off|standby", summary: "Turn displays on, off or to standby", run: runPower},
+		"preview":   {usage: "preview [--gamma] [--temperature] [--curve] <in> <out>", summary: "Preview gamma settings on an image", run: runPreview},
+		"revert":    {usage: "revert [now|cancel]", summary: "Show, apply or cancel the revert scheduled by --for", run: runRevert},
+		"topology":  {usage: "topology extend|clone|internal|external|save|restore", summary: "Switch or save the display topology", run: runTopology},
+		"vcp":       {usage: "vcp get|set|caps", summary: "Read and write monitor settings over DDC/CI", run: runVCP},
+	}
+}
+
+// runCommand dispatches to a subcommand by name
+func runCommand(name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", name)
+	}
+	if err := cmd.run(args); err != nil && !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
+
+// commandNames returns the subcommand names in alphabetical order
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseArgs parses flags that may appear before, between or after positional
+// arguments and returns the positional ones
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jipaix/lumos/revert"
+)
+
+// confirmPoll is how often the confirmation countdown is updated
+const confirmPoll = 250 * time.Millisecond
+
+func runConfirm(args []string) error {
+	fs := flag.NewFlagSet("confirm", flag.ContinueOnError)
+	fs.Usage = printConfirmHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		printConfirmHelp()
+		return nil
+	}
+
+	path, err := revert.Path()
+	if err != nil {
+		return err
+	}
+
+	snap, err := revert.Load(path)
+	if errors.Is(err, revert.ErrNoPending) || (err == nil && !snap.Confirm) {
+		fmt.Println("No change is waiting for confirmation")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := revert.Take(path, snap.Token); err != nil {
+		if errors.Is(err, revert.ErrNoPending) {
+			fmt.Println("No change is waiting for confirmation")
+			return nil
+		}
+		return err
+	}
+	fmt.Printf("Confirmed %v, the new settings are kept\n", snap.Changes)
+	return nil
+}
+
+// confirmChange starts the revert helper for snap as a safety net, then
+// waits for Enter or "lumos confirm" until the snapshot is due. Without a
+// confirmation the previous settings are restored.
+func confirmChange(snap *revert.Snapshot) error {
+	snap.Confirm = true
+	if err := startRevert(snap); err != nil {
+		return err
+	}
+
+	path, err := revert.Path()
+	if err != nil {
+		return err
+	}
+
+	// Only a line read from the terminal confirms, not EOF from a closed stdin
+	confirmed := make(chan struct{})
+	go func() {
+		if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err == nil {
+			close(confirmed)
+		}
+	}()
+
+	fmt.Println("Keep these settings? Press Enter, or run 'lumos confirm', to keep them.")
+	last := -1
+	tick := func(left time.Duration) {
+		if secs := int(left.Round(time.Second) / time.Second); secs != last {
+			fmt.Printf("\rReverting in %ds ", secs)
+			last = secs
+		}
+	}
+
+	due, err := revert.Confirm(revert.SystemClock, path, snap.Token, confirmed, confirmPoll, tick)
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	if due == nil {
+		fmt.Println("Settings kept")
+		return nil
+	}
+
+	fmt.Println("No confirmation, restoring the previous settings")
+	return restoreSnapshot(due)
+}
+
+func printConfirmHelp() {
+	fmt.Println("Usage: lumos confirm")
+	fmt.Println()
+	fmt.Println("Keep a change made with --confirm, e.g. 'lumos --gamma 10 --confirm 15s' or")
+	fmt.Println("'lumos mode --refresh 144 --confirm 15s'. Without confirmation the previous")
+	fmt.Println("settings are restored when the timeout expires.")
+}
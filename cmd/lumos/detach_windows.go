@@ -0,0 +1,17 @@
+package main
+
+import "syscall"
+
+const (
+	DETACHED_PROCESS         = 0x00000008
+	CREATE_NEW_PROCESS_GROUP = 0x00000200
+)
+
+// detachedProcAttr starts a process without a console so it outlives the
+// terminal that started it
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: DETACHED_PROCESS | CREATE_NEW_PROCESS_GROUP,
+		HideWindow:    true,
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jipaix/lumos/gamma"
+)
+
+// dryRun is the --dry-run flag: "" (off), "summary" or "full"
+type dryRun string
+
+func (d *dryRun) String() string   { return string(*d) }
+func (d *dryRun) IsBoolFlag() bool { return true }
+
+func (d *dryRun) Set(value string) error {
+	switch value {
+	case "true", "summary":
+		*d = "summary"
+	case "full":
+		*d = "full"
+	case "false":
+		*d = ""
+	default:
+		return fmt.Errorf("invalid dry run mode: %s (must be 'summary' or 'full')", value)
+	}
+	return nil
+}
+
+// rampSamples are the ramp entries shown in a ramp summary
+var rampSamples = []int{0, 32, 64, 128, 192, 255}
+
+// rampSummary describes a gamma ramp by a few sample entries
+func rampSummary(r *gamma.GammaRamp) string {
+	channel := func(c *[256]uint16) string {
+		parts := make([]string, len(rampSamples))
+		for i, idx := range rampSamples {
+			parts[i] = fmt.Sprintf("[%d]=%d", idx, c[idx])
+		}
+		return strings.Join(parts, " ")
+	}
+
+	if r.Red == r.Green && r.Green == r.Blue {
+		return "RGB " + channel(&r.Red)
+	}
+	return "R " + channel(&r.Red) + ", G " + channel(&r.Green) + ", B " + channel(&r.Blue)
+}
+
+// printRampTable prints every entry of the new ramp next to the current one
+func printRampTable(current, next *gamma.GammaRamp) {
+	fmt.Println("    idx  current R/G/B          new R/G/B")
+	for i := range 256 {
+		fmt.Printf("    %3d  %5d %5d %5d  ->  %5d %5d %5d\n", i,
+			current.Red[i], current.Green[i], current.Blue[i],
+			next.Red[i], next.Green[i], next.Blue[i])
+	}
+}
+
+// hexDiff prints old and new bytes side by side in 16 byte rows, marking
+// rows that differ with - and +
+func hexDiff(old, new []byte) {
+	row := func(b []byte, off int) string {
+		if off >= len(b) {
+			return ""
+		}
+		end := min(off+16, len(b))
+		return fmt.Sprintf("% X", b[off:end])
+	}
+
+	for off := 0; off < max(len(old), len(new)); off += 16 {
+		o, n := row(old, off), row(new, off)
+		if o == n {
+			fmt.Printf("    %04X    %s\n", off, o)
+			continue
+		}
+		if o != "" {
+			fmt.Printf("    %04X  - %s\n", off, o)
+		}
+		if n != "" {
+			fmt.Printf("    %04X  + %s\n", off, n)
+		}
+	}
+	if bytes.Equal(old, new) {
+		fmt.Println("    (unchanged)")
+	}
+}
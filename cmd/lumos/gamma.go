@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/apply"
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/gamma"
+)
+
+func runGamma(args []string) error {
+	// plot has its own flags
+	if len(args) > 0 && args[0] == "plot" {
+		return runGammaPlot(args[1:])
+	}
+
+	fs := flag.NewFlagSet("gamma", flag.ContinueOnError)
+	var sel display.Selector
+	var mode dryRun
+	fs.Var(&sel, "display", "Displays to use (all, or numbers, IDs or names like 1,3)")
+	fs.Var(&mode, "dry-run", "Show what would change without changing anything (summary/full)")
+	fs.Usage = printGammaHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return handleGammaShow(sel)
+	}
+
+	var step *gammaStep
+	switch positional[0] {
+	case "show":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: lumos gamma show")
+		}
+		return handleGammaShow(sel)
+	case "set":
+		if len(positional) != 3 {
+			return fmt.Errorf("usage: lumos gamma set <layer> <value>")
+		}
+		step, err = newGammaLayerStep(positional[1], positional[2], sel, mode)
+	case "reset":
+		if len(positional) > 2 {
+			return fmt.Errorf("usage: lumos gamma reset [<layer>]")
+		}
+		layer := ""
+		if len(positional) == 2 {
+			layer = positional[1]
+		}
+		step, err = newGammaResetStep(layer, sel, mode)
+	case "load":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: lumos gamma load <file>")
+		}
+		step, err = newGammaLayerStep(gamma.LayerCalibration, positional[1], sel, mode)
+	case "save":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: lumos gamma save <file>")
+		}
+		return handleGammaSave(positional[1], sel)
+	case "extended-range":
+		switch {
+		case len(positional) == 1:
+			return handleGammaRangeStatus()
+		case len(positional) == 2 && (positional[1] == "on" || positional[1] == "off"):
+			return handleGammaRangeSet(positional[1] == "on")
+		default:
+			return fmt.Errorf("usage: lumos gamma extended-range [on|off]")
+		}
+	case "apply":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: lumos gamma apply")
+		}
+		return handleGammaApply(sel)
+	default:
+		return fmt.Errorf("invalid gamma action: %s (must be 'show', 'set', 'reset', 'load', 'save', 'plot', 'extended-range', or 'apply')", positional[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	plan := apply.NewPlan()
+	plan.Add(step)
+	if mode != "" {
+		return plan.Preview()
+	}
+	return runPlan(plan)
+}
+
+// handleGammaShow prints the pipeline layers of the selected displays
+func handleGammaShow(sel display.Selector) error {
+	displays, err := sel.Resolve()
+	if err != nil {
+		return err
+	}
+	pipelines, err := loadPipelines()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, d := range displays {
+		fmt.Fprintf(w, "%s:\n", d.Label())
+		p := pipelines[d.ID]
+		if p == nil {
+			p = &gamma.Pipeline{}
+		}
+		for _, name := range gamma.LayerNames {
+			fmt.Fprintf(w, "  %s\t%s\n", name, layerValue(p, name))
+		}
+	}
+	return w.Flush()
+}
+
+// handleGammaApply applies the stored pipelines again, e.g. after another
+// program or a driver reset replaced the ramps
+func handleGammaApply(sel display.Selector) error {
+	pipelines, err := loadPipelines()
+	if err != nil {
+		return err
+	}
+	results, err := gamma.ApplyPipelines(pipelines, "gamma layers applied", sel)
+	if err != nil {
+		return err
+	}
+	printGammaResults(results)
+	return gamma.Failed(results)
+}
+
+// handleGammaSave writes the current gamma ramp of one display to a file
+func handleGammaSave(path string, sel display.Selector) error {
+	displays, err := sel.Resolve()
+	if err != nil {
+		return err
+	}
+	if len(displays) != 1 {
+		return fmt.Errorf("select one display to save with --display (%d selected)", len(displays))
+	}
+
+	d := displays[0]
+	ramp, err := gamma.GetRamp(d)
+	if err != nil {
+		return err
+	}
+	if err := gamma.WriteTable(path, gamma.TableFromRamp(&ramp), d.Label()); err != nil {
+		return err
+	}
+	fmt.Printf("%s: gamma ramp saved to %s\n", d.Label(), path)
+	return nil
+}
+
+// handleGammaRangeStatus shows the GdiIcmGammaRange Windows checks ramps
+// against
+func handleGammaRangeStatus() error {
+	gammaRange, err := gamma.GammaRange()
+	if err != nil {
+		return err
+	}
+	if gammaRange >= gamma.ExtendedGammaRange {
+		fmt.Printf("Extended gamma range: on (GdiIcmGammaRange %d, any ramp is accepted)\n", gammaRange)
+		return nil
+	}
+	lo, _ := gamma.AcceptedRange(255, gammaRange)
+	fmt.Printf("Extended gamma range: off (GdiIcmGammaRange %d, white can't go below %.0f%%)\n", gammaRange, float64(lo)/65535*100)
+	return nil
+}
+
+// handleGammaRangeSet sets or removes GdiIcmGammaRange
+func handleGammaRangeSet(enable bool) error {
+	if err := gamma.SetExtendedRange(enable); err != nil {
+		return err
+	}
+	fmt.Printf("Extended gamma range %s, restart Windows for it to take effect\n", map[bool]string{true: "enabled", false: "disabled"}[enable])
+	return nil
+}
+
+func loadPipelines() (gamma.Pipelines, error) {
+	path, err := gamma.PipelinesPath()
+	if err != nil {
+		return nil, err
+	}
+	return gamma.LoadPipelines(path)
+}
+
+// layerValue describes a layer of a pipeline, "-" when it isn't set
+func layerValue(p *gamma.Pipeline, name string) string {
+	for _, l := range p.Layers() {
+		if l.Name() == name {
+			return l.String()
+		}
+	}
+	return "-"
+}
+
+// pipelineSummary lists the set layers of a pipeline on one line
+func pipelineSummary(p *gamma.Pipeline) string {
+	var parts []string
+	for _, l := range p.Layers() {
+		parts = append(parts, l.Name()+" "+l.String())
+	}
+	if len(parts) == 0 {
+		return "none (linear ramp)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printGammaHelp() {
+	fmt.Println("Usage: lumos gamma [show | set <layer> <value> | reset [<layer>] | load <file> | save <file> | apply]")
+	fmt.Println("                   [--display <display>[,...]]")
+	fmt.Println("       lumos gamma extended-range [on|off]")
+	fmt.Println("       lumos gamma plot [--out <file.svg>] (see 'lumos gamma plot --help')")
+	fmt.Println()
+	fmt.Println("Build the gamma ramp of each display from layers that are applied in order:")
+	fmt.Println("calibration, curve, temperature, brightness and clamp. Changing one layer keeps")
+	fmt.Println("the others. Without an action, shows the layers of each display.")
+	fmt.Println()
+	fmt.Println("Ramp files can be 1D .cube LUTs, index,red,green,blue .csv rows, Argyll .cal")
+	fmt.Println("files or .icc/.icm profiles with calibration curves (vcgt). 'load' uses a file")
+	fmt.Println("as the calibration layer, 'save' writes the current ramp of one display.")
+	fmt.Println(".csv values are 0-1 when written with a decimal point, 8 or 16 bit integers")
+	fmt.Println("otherwise; a header like index,red16,green16,blue16 states the depth.")
+	fmt.Println()
+	fmt.Println("Windows rejects ramps too far from linear, e.g. white below about 50%. Such")
+	fmt.Println("ramps are clamped into the accepted range with a warning. 'extended-range on'")
+	fmt.Println("sets the GdiIcmGammaRange registry value to lift the limit after a restart;")
+	fmt.Println("it needs administrator privileges.")
+	fmt.Println()
+	fmt.Println("Layers:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  calibration <file>|active|none\tRamp file or ICC profile calibration curves, the display's")
+	fmt.Fprintln(w, "  \tactive profile, or a linear base; defaults to the active profile")
+	fmt.Fprintln(w, "  curve <gamma>|<points>\tPower curve, one gamma or one per channel like 1.0,1.1,0.9, or")
+	fmt.Fprintln(w, "  \tinput:output control points like 0:0,0.25:0.2,0.75:0.85,1:1 joined")
+	fmt.Fprintln(w, "  \tby a monotone spline, per channel like r=0:0,1:0.9;b=0:0,1:1")
+	fmt.Fprintf(w, "  temperature <kelvin> [<algorithm>]\tWhite point from %dK to %dK (%dK is neutral with helland); the algorithm\n", gamma.MinTemperature, gamma.MaxTemperature, gamma.NeutralTemperature)
+	fmt.Fprintf(w, "  \tis helland (default), blackbody or daylight (from %dK); blackbody and daylight\n", gamma.MinDaylightTemperature)
+	fmt.Fprintln(w, "  \tare absolute on a D65 display, so daylight is neutral at 6504K and blackbody never is")
+	fmt.Fprintln(w, "  temperature <x>,<y>|<illuminant>\tWhite point by CIE xy chromaticity, e.g. 0.3457,0.3585, or A,")
+	fmt.Fprintln(w, "  \tD50, D55, D65 or D75; same as --whitepoint")
+	fmt.Fprintln(w, "  brightness <0-100> [<scale>]\tBrightness and contrast, same as --gamma; the scale is linear,")
+	fmt.Fprintln(w, "  \tlightness or power, like --scale")
+	fmt.Fprintln(w, "  clamp <min>,<max>\tLimit the output range, e.g. 0.02,1")
+	w.Flush()
+
+	fmt.Println()
+	fmt.Println("Options:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --display <display>[,...]\tOnly use the given displays by number, ID or name (default: all)")
+	fmt.Fprintln(w, "  --dry-run[=full]\tShow the ramps that would be written without changing anything")
+	w.Flush()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/hdr"
+)
+
+func runHDR(args []string) error {
+	fs := flag.NewFlagSet("hdr", flag.ContinueOnError)
+	var sel display.Selector
+	fs.Var(&sel, "display", "Displays to use (all, or numbers, IDs or names like 1,3)")
+	var scale brightness.Scale
+	fs.Var(&scale, "scale", "Brightness scale for sdr-brightness percentages (linear/lightness/power)")
+	fs.Usage = printHDRHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		printHDRHelp()
+		return nil
+	}
+
+	switch positional[0] {
+	case "sdr-brightness":
+		switch len(positional) {
+		case 1:
+			return handleSDRBrightnessGet(sel)
+		case 2:
+			nits, err := hdr.ParseSDRBrightness(positional[1], scale)
+			if err != nil {
+				return err
+			}
+			return handleSDRBrightnessSet(nits, sel)
+		default:
+			return fmt.Errorf("usage: lumos hdr sdr-brightness [<percent>|<nits>nits]")
+		}
+	case "acm":
+		switch len(positional) {
+		case 1:
+			return handleHDRStatus(sel)
+		case 2:
+			return handleACM(positional[1], sel)
+		default:
+			return fmt.Errorf("usage: lumos hdr acm [on|off]")
+		}
+	case "status":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: lumos hdr status")
+		}
+		return handleHDRStatus(sel)
+	default:
+		return fmt.Errorf("invalid hdr action: %s (must be 'status', 'acm', or 'sdr-brightness')", positional[0])
+	}
+}
+
+func handleHDRStatus(sel display.Selector) error {
+	hdrCtrl := hdr.NewHDR()
+	states, err := hdrCtrl.States(sel)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if hdrCtrl.ACMSupported() {
+		fmt.Fprintln(w, "Display\tHDR\tACM")
+		for _, s := range states {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Display.Label(), s.HDRStatus(), s.ACMStatus())
+		}
+	} else {
+		fmt.Fprintln(w, "Display\tHDR")
+		for _, s := range states {
+			fmt.Fprintf(w, "%s\t%s\n", s.Display.Label(), s.HDRStatus())
+		}
+	}
+	return w.Flush()
+}
+
+func handleACM(state string, sel display.Selector) error {
+	var enable bool
+	switch state {
+	case "on":
+		enable = true
+	case "off":
+		enable = false
+	default:
+		return fmt.Errorf("invalid ACM state: %s (must be 'on' or 'off')", state)
+	}
+
+	results, err := hdr.NewHDR().SetACM(enable, sel)
+	if err != nil {
+		return err
+	}
+	printHDRResults(results)
+	return hdr.Failed(results)
+}
+
+func handleSDRBrightnessGet(sel display.Selector) error {
+	levels, err := hdr.NewHDR().SDRWhiteLevels(sel)
+	if err != nil {
+		return err
+	}
+	printSDRWhiteLevels(levels)
+	return nil
+}
+
+func handleSDRBrightnessSet(nits float64, sel display.Selector) error {
+	results, err := hdr.NewHDR().SetSDRWhiteLevel(nits, sel)
+	if err != nil {
+		return err
+	}
+	printHDRResults(results)
+	return hdr.Failed(results)
+}
+
+// printHDRResults prints which displays were changed and which failed
+func printHDRResults(results []hdr.Result) {
+	for _, r := range results {
+		switch r.Outcome {
+		case hdr.Changed:
+			fmt.Printf("%s: %s\n", r.Display.Label(), r.Action)
+		case hdr.Failure:
+			reason := r.Err.Error()
+			if code := r.Code(); code != 0 {
+				reason = fmt.Sprintf("error %d: %v", uint32(code), code)
+			}
+			if r.Attempts > 1 {
+				reason += fmt.Sprintf(", %d attempts", r.Attempts)
+			}
+			fmt.Printf("%s: %s failed (%s)\n", r.Display.Label(), r.Action, reason)
+		default:
+			fmt.Printf("%s: %s (%s)\n", r.Display.Label(), r.Action, r.Outcome)
+		}
+	}
+}
+
+func printSDRWhiteLevels(levels []hdr.SDRWhiteLevel) {
+	for _, l := range levels {
+		if l.Err != nil {
+			fmt.Printf("%s: %v\n", l.Display.Label(), l.Err)
+			continue
+		}
+		note := ""
+		if !l.HDREnabled {
+			note = " (applies when HDR is on)"
+		}
+		fmt.Printf("%s: SDR brightness %.0f%% (%.0f nits)%s\n", l.Display.Label(), l.Percent(), l.Nits(), note)
+	}
+}
+
+func printHDRHelp() {
+	fmt.Println("Usage: lumos hdr <action> [--display <display>[,...]]")
+	fmt.Println()
+	fmt.Println("Actions:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  status\tShow HDR and Auto Color Management state per display")
+	fmt.Fprintln(w, "  acm [on|off]\tShow or switch Auto Color Management (Windows 11 24H2 and later)")
+	fmt.Fprintln(w, "  sdr-brightness [<percent>|<nits>nits]\tShow or set the brightness of SDR content while HDR is on,")
+	fmt.Fprintln(w, "  \tlike the \"SDR content brightness\" slider; 0-100% maps to 80-480 nits")
+	w.Flush()
+
+	fmt.Println()
+	fmt.Println("Options:")
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --display <display>[,...]\tOnly use the given displays by number, ID or name (default: all)")
+	fmt.Fprintln(w, "  --scale linear|lightness|power\tHow sdr-brightness percentages map to the slider, like")
+	fmt.Fprintln(w, "  \tlumos --scale (default: linear)")
+	w.Flush()
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/edid"
+)
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.Usage = printListHelp
+	verbose := fs.Bool("verbose", false, "Show decoded EDID details")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		printListHelp()
+		return nil
+	}
+
+	displays, err := display.List()
+	if err != nil {
+		return err
+	}
+	if len(displays) == 0 {
+		fmt.Println("No displays found")
+		return nil
+	}
+
+	if *verbose {
+		for i, d := range displays {
+			if i > 0 {
+				fmt.Println()
+			}
+			printDisplayDetails(d)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tName\tID\tDevice")
+	for _, d := range displays {
+		device := d.DeviceName
+		if d.Internal {
+			device += " (internal)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", d.Number, d.Name, d.ID, device)
+	}
+	return w.Flush()
+}
+
+// printDisplayDetails prints the decoded EDID of a display
+func printDisplayDetails(d display.Display) {
+	fmt.Println(d.Label())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "  ID:\t%s\n", d.ID)
+	fmt.Fprintf(w, "  Device:\t%s\n", d.DeviceName)
+
+	e, err := edid.Parse(d.EDID)
+	if err != nil {
+		fmt.Fprintf(w, "  EDID:\tunavailable (%v)\n", err)
+		return
+	}
+
+	fmt.Fprintf(w, "  Manufacturer:\t%s\n", e.Manufacturer)
+	fmt.Fprintf(w, "  Product:\t%04X\n", e.ProductCode)
+	if e.SerialString != "" {
+		fmt.Fprintf(w, "  Serial:\t%s\n", e.SerialString)
+	} else if e.SerialNumber != 0 {
+		fmt.Fprintf(w, "  Serial:\t%08X\n", e.SerialNumber)
+	}
+	if e.Year != 0 {
+		fmt.Fprintf(w, "  Year:\t%d\n", e.Year)
+	}
+	fmt.Fprintf(w, "  EDID version:\t%d.%d\n", e.Version, e.Revision)
+	if e.WidthCm != 0 && e.HeightCm != 0 {
+		fmt.Fprintf(w, "  Size:\t%d x %d cm (%.1f\")\n", e.WidthCm, e.HeightCm, e.DiagonalInches())
+	}
+	if t := e.NativeTiming; t != nil {
+		fmt.Fprintf(w, "  Native mode:\t%dx%d @ %.2f Hz\n", t.Width, t.Height, t.RefreshRate)
+	}
+	if e.Gamma != 0 {
+		fmt.Fprintf(w, "  Gamma:\t%.2f\n", e.Gamma)
+	}
+	p := e.Primaries
+	fmt.Fprintf(w, "  Primaries:\tR %.4f,%.4f  G %.4f,%.4f  B %.4f,%.4f  W %.4f,%.4f\n",
+		p.Red.X, p.Red.Y, p.Green.X, p.Green.Y, p.Blue.X, p.Blue.Y, p.White.X, p.White.Y)
+	if len(e.ColorSpaces) > 0 {
+		fmt.Fprintf(w, "  Color spaces:\t%s\n", strings.Join(e.ColorSpaces, ", "))
+	}
+
+	if !e.SupportsHDR() {
+		fmt.Fprintln(w, "  HDR:\tnot supported")
+	} else {
+		h := e.HDR
+		fmt.Fprintf(w, "  HDR:\t%s (%s)\n", strings.Join(h.EOTFs, ", "), h.Source)
+		if h.MaxLuminance != 0 {
+			fmt.Fprintf(w, "  Max luminance:\t%.0f cd/m²\n", h.MaxLuminance)
+		}
+		if h.MaxFrameAvgLuminance != 0 {
+			fmt.Fprintf(w, "  Max frame-avg:\t%.0f cd/m²\n", h.MaxFrameAvgLuminance)
+		}
+		if h.MinLuminance != 0 {
+			fmt.Fprintf(w, "  Min luminance:\t%.4f cd/m²\n", h.MinLuminance)
+		}
+	}
+	if len(e.Extensions) > 0 {
+		fmt.Fprintf(w, "  Extensions:\t%s\n", strings.Join(e.Extensions, ", "))
+	}
+}
+
+func printListHelp() {
+	fmt.Println("Usage: lumos list [--verbose]")
+	fmt.Println()
+	fmt.Println("List connected displays with their number, name and stable ID. Any of these")
+	fmt.Println("can be given to --display; the ID stays the same across reboots and hotplug.")
+	fmt.Println()
+	fmt.Println("Options:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --verbose\tShow decoded EDID details: size, native mode, color spaces,")
+	fmt.Fprintln(w, "  \tprimaries and HDR capabilities")
+	w.Flush()
+}
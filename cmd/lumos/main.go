@@ -5,22 +5,39 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
 	"text/tabwriter"
-	"time"
 
+	"github.com/jipaix/lumos/apply"
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/display"
 	"github.com/jipaix/lumos/gamma"
-	"github.com/jipaix/lumos/hdr"
-	n "github.com/jipaix/lumos/night"
 )
 
 const version = "1.0"
 
 func main() {
+	// Dispatch subcommands like "lumos vcp get 10"
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Define flags
 	hdrFlag := flag.String("hdr", "", "Set HDR state (on/off/toggle)")
-	gammaFlag := flag.Int("gamma", -1, "Set gamma percentage (0-100)")
+	gammaFlag := flag.Int("gamma", -1, "Set gamma brightness percentage (0-100)")
+	var scaleFlag brightness.Scale
+	flag.Var(&scaleFlag, "scale", "Brightness scale for --gamma (linear/lightness/power)")
+	curveFlag := flag.String("curve", "", "Set the gamma tone curve from control points, e.g. 0:0,0.5:0.45,1:1")
+	whitepointFlag := flag.String("whitepoint", "", "Set the gamma white point as x,y chromaticity or an illuminant, e.g. 0.3457,0.3585 or D50")
 	nightFlag := flag.String("night", "", "Set night light state (on/off/toggle)")
+	forFlag := flag.Duration("for", 0, "Revert the changes after this long, e.g. 45m")
+	confirmFlag := flag.Duration("confirm", 0, "Revert the changes unless confirmed within this long, e.g. 15s")
+	var dryRunFlag dryRun
+	flag.Var(&dryRunFlag, "dry-run", "Show what would change without changing anything (summary/full)")
 	helpFlag := flag.Bool("help", false, "Show help message")
 	versionFlag := flag.Bool("version", false, "Show version")
 
@@ -41,140 +58,110 @@ func main() {
 		return
 	}
 
-	// Execute commands based on flags
-	var hasOperation bool
+	// Build one plan from the flags so a failure rolls back earlier changes
+	plan := apply.NewPlan()
 
-	// Handle HDR
 	if *hdrFlag != "" {
-		hasOperation = true
-		if err := handleHDR(*hdrFlag); err != nil {
-			fmt.Printf("Error setting HDR: %v\n", err)
+		step, err := newHDRStep(*hdrFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
+		plan.Add(step)
 	}
 
-	// Handle Gamma
-	if *gammaFlag != -1 {
-		hasOperation = true
-		if err := handleGamma(*gammaFlag); err != nil {
-			fmt.Printf("Error setting gamma: %v\n", err)
+	if *gammaFlag != -1 || *curveFlag != "" || *whitepointFlag != "" {
+		// One step for all so they change the same saved gamma layers
+		step := newGammaStep(display.All, dryRunFlag)
+		var err error
+		if *gammaFlag != -1 {
+			err = step.setBrightness(*gammaFlag, scaleFlag)
+		}
+		if err == nil && *curveFlag != "" {
+			err = step.setLayer(gamma.LayerCurve, *curveFlag)
+		}
+		if err == nil && *whitepointFlag != "" {
+			err = step.setWhitePoint(*whitepointFlag)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
+		plan.Add(step)
 	}
 
-	// Handle Night Light
 	if *nightFlag != "" {
-		hasOperation = true
-		if err := handleNightLight(*nightFlag); err != nil {
-			fmt.Printf("Error setting night light: %v\n", err)
+		step, err := newNightStep(*nightFlag)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
+		plan.Add(step)
 	}
 
-	// If no valid operations were performed, show help
-	if !hasOperation {
+	// If no valid operations were requested, show help
+	if plan.Len() == 0 {
 		printHelp()
+		return
 	}
-}
 
-func handleHDR(hdrState string) error {
-	hdrCtrl := hdr.NewHDR()
-
-	// Check if HDR is supported
-	if !hdrCtrl.IsHDRSupported() {
-		return fmt.Errorf("HDR is not supported on this system or requires administrator privileges")
+	if *forFlag < 0 || *confirmFlag < 0 {
+		fmt.Println("Error: --for and --confirm must be positive")
+		os.Exit(1)
+	}
+	if *forFlag > 0 && *confirmFlag > 0 {
+		fmt.Println("Error: --for and --confirm can't be combined")
+		os.Exit(1)
 	}
 
-	switch hdrState {
-	case "on":
-		if err := hdrCtrl.Enable(); err != nil {
-			return fmt.Errorf("HDR enable failed: %v", err)
+	if dryRunFlag != "" {
+		if err := plan.Preview(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
-		fmt.Println("HDR enabled on all compatible displays")
-	case "off":
-		if err := hdrCtrl.Disable(); err != nil {
-			return fmt.Errorf("HDR disable failed: %v", err)
+		if *forFlag > 0 {
+			fmt.Printf("The previous settings would be restored after %s\n", *forFlag)
 		}
-		fmt.Println("HDR disabled on all compatible displays")
-	case "toggle":
-		if err := hdrCtrl.Toggle(); err != nil {
-			return fmt.Errorf("HDR toggle failed: %v", err)
+		if *confirmFlag > 0 {
+			fmt.Printf("The previous settings would be restored unless confirmed within %s\n", *confirmFlag)
 		}
-		fmt.Println("HDR toggled on all compatible displays")
-	default:
-		return fmt.Errorf("invalid HDR state: %s (must be 'on', 'off', or 'toggle')", hdrState)
+		return
 	}
-	return nil
-}
 
-func handleGamma(percentage int) error {
-	if percentage < 0 || percentage > 100 {
-		return fmt.Errorf("gamma percentage must be between 0 and 100, got %d", percentage)
+	if *forFlag > 0 || *confirmFlag > 0 {
+		if err := checkNoPendingRevert(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	if err := gamma.SetGamma(percentage); err != nil {
-		return err
+	if err := runPlan(plan); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
-	fmt.Printf("Gamma set to %d%% on all displays\n", percentage)
-	return nil
-}
-
-func handleNightLight(state string) error {
-	nl := n.NewLumos()
 
-	switch state {
-	case "on":
-		if err := nl.Enable(); err != nil {
-			return err
-		}
-		fmt.Println("Night light enabled")
-	case "off":
-		if err := nl.Disable(); err != nil {
-			return err
-		}
-		fmt.Println("Night light disabled")
-	case "toggle":
-		if err := nl.Toggle(); err != nil {
-			return err
-		}
-		fmt.Println("Night light toggled")
-	default:
-		val, err := strconv.ParseFloat(state, 64)
-		if err != nil {
-			return fmt.Errorf("invalid night light state: %s (must be 'on', 'off', 'toggle', or a percentage like '50')", state)
+	if *forFlag > 0 {
+		if err := scheduleRevert(plan, *forFlag); err != nil {
+			fmt.Printf("Error: %v (the changes were kept)\n", err)
+			os.Exit(1)
 		}
+	}
 
-		if err := nl.SetStrength(val); err != nil {
-			return err
+	if *confirmFlag > 0 {
+		snap, err := planSnapshot(plan, *confirmFlag)
+		if err == nil {
+			err = confirmChange(snap)
 		}
-
-		// Check if currently enabled to decide if we need a "hard restart"
-		enabled, err := nl.Enabled()
 		if err != nil {
-			return err
-		}
-
-		// If it's currently on, disable it first to force the refresh
-		if enabled {
-			if err := nl.Disable(); err != nil {
-				return err
-			}
-			time.Sleep(200 * time.Millisecond)
-		}
-
-		// Turn it on (or back on)
-		if err := nl.Enable(); err != nil {
-			return err
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
-
-		fmt.Printf("Night light strength set to %v%%\n", val)
-		return nil
 	}
-	return nil
 }
 
 func printHelp() {
-	fmt.Println("Usage: lumos [--hdr on|off|toggle] [--gamma <0-100>] [--night on|off|toggle|<0-100>]")
+	fmt.Println("Usage: lumos [--hdr on|off|toggle] [--gamma <0-100>] [--scale <scale>] [--curve <points>] [--whitepoint <x,y>] [--night on|off|toggle|<0-100>] [--for <duration>|--confirm <duration>] [--dry-run]")
+	fmt.Println("       lumos <command> [arguments]")
 	fmt.Println()
 	fmt.Println("Options:")
 
@@ -183,10 +170,29 @@ func printHelp() {
 
 	// Use \t to separate the flag from the description
 	fmt.Fprintln(w, "  --hdr on|off|toggle\tControl HDR")
-	fmt.Fprintln(w, "  --gamma <0-100>\tSet gamma level")
+	fmt.Fprintln(w, "  --gamma <0-100>\tSet the brightness layer of the gamma ramp")
+	fmt.Fprintln(w, "  --scale linear|lightness|power\tHow --gamma maps to the ramp: linear (default), CIE L*")
+	fmt.Fprintln(w, "  \tlightness or a 2.2 power law, so that 50% looks half as bright")
+	fmt.Fprintln(w, "  --curve <points>\tSet the tone curve layer from input:output control points,")
+	fmt.Fprintln(w, "  \te.g. 0:0,0.25:0.2,0.75:0.85,1:1, or per channel like r=0:0,1:0.9;b=0:0,1:1")
+	fmt.Fprintln(w, "  --whitepoint <x>,<y>\tSet the temperature layer to a white point by CIE xy chromaticity,")
+	fmt.Fprintln(w, "  \te.g. 0.3457,0.3585, or a standard illuminant like D50")
 	fmt.Fprintln(w, "  --night on|off|toggle|<0-100>\tControl night light")
+	fmt.Fprintln(w, "  --for <duration>\tRestore the previous settings after this long, e.g. 45m")
+	fmt.Fprintln(w, "  --confirm <duration>\tRestore the previous settings unless confirmed within this")
+	fmt.Fprintln(w, "  \tlong, e.g. 15s; confirm with Enter or 'lumos confirm'")
+	fmt.Fprintln(w, "  --dry-run[=full]\tShow what would change without changing anything;")
+	fmt.Fprintln(w, "  \tfull also prints every gamma ramp entry")
 	fmt.Fprintln(w, "  --help\tShow help")
 	fmt.Fprintln(w, "  --version\tShow version")
+	w.Flush()
+
+	fmt.Println()
+	fmt.Println("Commands:")
 
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "  %s\t%s\n", commands[name].usage, commands[name].summary)
+	}
 	w.Flush()
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/mode"
+	"github.com/jipaix/lumos/revert"
+)
+
+func runMode(args []string) error {
+	fs := flag.NewFlagSet("mode", flag.ContinueOnError)
+	var sel display.Selector
+	fs.Var(&sel, "display", "Displays to use (all, or numbers, IDs or names like 1,3)")
+	resolution := fs.String("resolution", "", "Resolution like 2560x1440")
+	refresh := fs.Int("refresh", 0, "Refresh rate in Hz")
+	list := fs.Bool("list", false, "List supported modes")
+	confirm := fs.Duration("confirm", 0, "Revert unless confirmed within this long, e.g. 15s")
+	fs.Usage = printModeHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		printModeHelp()
+		return nil
+	}
+
+	var req mode.Request
+	if *resolution != "" {
+		req.Width, req.Height, err = mode.ParseResolution(*resolution)
+		if err != nil {
+			return err
+		}
+	}
+	if *refresh < 0 {
+		return fmt.Errorf("refresh rate must be positive, got %d", *refresh)
+	}
+	req.RefreshRate = *refresh
+
+	selected, err := sel.Resolve()
+	if err != nil {
+		return err
+	}
+
+	if *list || req.IsZero() {
+		return handleModeList(selected)
+	}
+	if *confirm > 0 {
+		return handleModeSetConfirm(selected, req, *confirm)
+	}
+	return handleModeSet(selected, req)
+}
+
+// handleModeSetConfirm changes modes like handleModeSet and restores the
+// previous modes unless the change is confirmed within timeout
+func handleModeSetConfirm(displays []display.Display, req mode.Request, timeout time.Duration) error {
+	if err := checkNoPendingRevert(); err != nil {
+		return err
+	}
+
+	snap, err := revert.NewSnapshot(time.Now(), timeout)
+	if err != nil {
+		return err
+	}
+	snap.Changes = []string{"mode " + req.String()}
+	for _, d := range displays {
+		current, err := mode.Current(d.DeviceName)
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.Label(), err)
+		}
+		snap.Modes = append(snap.Modes, revert.ModeState{ID: d.ID, Mode: current})
+	}
+
+	if err := handleModeSet(displays, req); err != nil {
+		// Put back the displays that did switch
+		fmt.Println("Restoring the previous modes")
+		return errors.Join(err, restoreSnapshot(snap))
+	}
+
+	// The timeout starts once the new modes are up
+	snap.RevertAt = time.Now().Add(timeout)
+	return confirmChange(snap)
+}
+
+func handleModeList(displays []display.Display) error {
+	for _, d := range displays {
+		modes, err := mode.Modes(d.DeviceName)
+		if err != nil {
+			return err
+		}
+		current, err := mode.Current(d.DeviceName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s, %s: %s\n", d.Label(), d.DeviceName, current)
+
+		mode.Sort(modes)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, res := range mode.Resolutions(modes) {
+			var atRes []mode.Mode
+			for _, m := range modes {
+				if fmt.Sprintf("%dx%d", m.Width, m.Height) == res {
+					atRes = append(atRes, m)
+				}
+			}
+			fmt.Fprintf(w, "  %s\t%s\n", res, strings.Join(mode.RefreshRates(atRes), ", "))
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+func handleModeSet(displays []display.Display, req mode.Request) error {
+	var errs []error
+	for _, d := range displays {
+		if err := setDisplayMode(d, req); err != nil {
+			fmt.Printf("%s: %v\n", d.Label(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", d.Label(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setDisplayMode switches one display to the mode closest to req
+func setDisplayMode(d display.Display, req mode.Request) error {
+	modes, err := mode.Modes(d.DeviceName)
+	if err != nil {
+		return err
+	}
+	current, err := mode.Current(d.DeviceName)
+	if err != nil {
+		return err
+	}
+
+	target, err := mode.Select(modes, current, req)
+	if err != nil {
+		return err
+	}
+
+	if target == current {
+		fmt.Printf("%s: already at %s\n", d.Label(), current)
+		return nil
+	}
+
+	if err := mode.Apply(d.DeviceName, target); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s -> %s\n", d.Label(), current, target)
+	return nil
+}
+
+func printModeHelp() {
+	fmt.Println("Usage: lumos mode [--resolution <w>x<h>] [--refresh <hz>] [--display <display>[,...]] [--list] [--confirm <duration>]")
+	fmt.Println()
+	fmt.Println("Change the resolution and refresh rate of displays. Without a resolution or")
+	fmt.Println("refresh rate, the supported modes are listed. Every mode is validated by the")
+	fmt.Println("driver before it is applied.")
+	fmt.Println()
+	fmt.Println("Options:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --resolution <w>x<h>\tResolution, e.g. 2560x1440 (default: current)")
+	fmt.Fprintln(w, "  --refresh <hz>\tRefresh rate (default: current, or highest available)")
+	fmt.Fprintln(w, "  --display <display>[,...]\tOnly use the given displays by number, ID or name (default: all)")
+	fmt.Fprintln(w, "  --list\tList supported modes")
+	fmt.Fprintln(w, "  --confirm <duration>\tRestore the previous modes unless confirmed within this long,")
+	fmt.Fprintln(w, "  \te.g. 15s; confirm with Enter or 'lumos confirm'")
+	w.Flush()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/gamma"
+)
+
+// runGammaPlot renders the identity, current and target ramps, and
+// optionally each pipeline layer, as an SVG chart
+func runGammaPlot(args []string) error {
+	fs := flag.NewFlagSet("gamma plot", flag.ContinueOnError)
+	var sel display.Selector
+	fs.Var(&sel, "display", "Plot the saved gamma layers and current ramp of this display")
+	out := fs.String("out", "gamma.svg", "SVG file to write")
+	perLayer := fs.Bool("layers", false, "Also plot the ramp after each pipeline layer")
+	var layers layerFlags
+	layers.register(fs)
+	fs.Usage = printGammaPlotHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		printGammaPlotHelp()
+		return nil
+	}
+
+	p, err := previewPipeline(sel, &layers)
+	if err != nil {
+		return err
+	}
+
+	target, err := p.Ramp()
+	if err != nil {
+		return err
+	}
+	gammaRange, err := gamma.GammaRange()
+	if err != nil {
+		return err
+	}
+	target, rangeErr := gamma.Fit(target, gammaRange)
+	if rangeErr != nil {
+		fmt.Printf("Warning: %v\n", rangeErr)
+	}
+
+	title := "Gamma ramp preview"
+	series := []gamma.PlotSeries{{Name: "identity", Ramp: gamma.IdentityRamp(), Style: gamma.PlotDashed}}
+	if !sel.IsAll() {
+		displays, err := sel.Resolve()
+		if err != nil {
+			return err
+		}
+		d := displays[0] // previewPipeline checked there is one
+		title = "Gamma ramp of " + d.Label()
+		if current, err := gamma.GetRamp(d); err != nil {
+			fmt.Printf("%s: current ramp not plotted (%v)\n", d.Label(), err)
+		} else {
+			series = append(series, gamma.PlotSeries{Name: "current", Ramp: current, Style: gamma.PlotDotted})
+		}
+	}
+	if *perLayer {
+		for _, l := range p.LayerRamps() {
+			series = append(series, gamma.PlotSeries{Name: l.Name, Ramp: l.Ramp, Style: gamma.PlotThin})
+		}
+	}
+	series = append(series, gamma.PlotSeries{Name: "target", Ramp: target, Style: gamma.PlotSolid})
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = gamma.WritePlotSVG(f, title, series)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Plot of %s written to %s\n", pipelineSummary(p), *out)
+	return nil
+}
+
+func printGammaPlotHelp() {
+	fmt.Println("Usage: lumos gamma plot [--out <file.svg>] [--display <display>] [--layers]")
+	fmt.Println("                        [--gamma <0-100>] [--scale <scale>] [--temperature <kelvin>|--whitepoint <x,y>] [--curve <curve>]")
+	fmt.Println()
+	fmt.Println("Draw the red, green and blue gamma ramps as an SVG chart: the identity ramp,")
+	fmt.Println("the current ramp of the display and the target ramp lumos would program.")
+	fmt.Println()
+	fmt.Println("Options:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --out <file.svg>\tFile to write (default: gamma.svg)")
+	fmt.Fprintln(w, "  --display <display>\tStart from the saved gamma layers of this display and plot")
+	fmt.Fprintln(w, "  \tits current ramp (default: none)")
+	fmt.Fprintln(w, "  --layers\tAlso plot the ramp after each pipeline layer")
+	fmt.Fprintln(w, "  --gamma <0-100>\tBrightness layer, same as lumos --gamma")
+	fmt.Fprintln(w, "  --scale <scale>\tBrightness scale for --gamma: linear, lightness or power")
+	fmt.Fprintln(w, "  --temperature <kelvin>\tTemperature layer, e.g. 2700 or 5000K daylight")
+	fmt.Fprintln(w, "  --whitepoint <x>,<y>\tTemperature layer as a white point, e.g. D50")
+	fmt.Fprintln(w, "  --curve <curve>\tCurve layer, same as lumos --curve")
+	w.Flush()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/power"
+)
+
+func runPower(args []string) error {
+	fs := flag.NewFlagSet("power", flag.ContinueOnError)
+	var sel display.Selector
+	fs.Var(&sel, "display", "Displays to use (all, or numbers, IDs or names like 1,3)")
+	method := fs.String("method", "auto", "Power control method (auto/ddc/broadcast)")
+	fs.Usage = printPowerHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		printPowerHelp()
+		return nil
+	}
+
+	state, err := power.ParseState(positional[0])
+	if err != nil {
+		return err
+	}
+
+	switch *method {
+	case "auto":
+		// Broadcasts can't target a single display, so only use them for all
+		if sel.IsAll() {
+			return handlePowerBroadcast(state)
+		}
+		return handlePowerDDC(state, sel)
+	case "ddc":
+		return handlePowerDDC(state, sel)
+	case "broadcast":
+		if !sel.IsAll() {
+			return fmt.Errorf("--method broadcast always affects all displays and can't be combined with --display")
+		}
+		return handlePowerBroadcast(state)
+	default:
+		return fmt.Errorf("invalid power method: %s (must be 'auto', 'ddc', or 'broadcast')", *method)
+	}
+}
+
+func handlePowerDDC(state power.State, sel display.Selector) error {
+	results, err := power.SetDDC(state, sel)
+	if err != nil {
+		return err
+	}
+	printPowerResults(state, results)
+	return power.Failed(results)
+}
+
+func handlePowerBroadcast(state power.State) error {
+	// Give the key release of the Enter key that started lumos time to pass,
+	// otherwise it wakes the displays right back up
+	if state != power.On {
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	results := []power.Result{power.SetAll(state)}
+	printPowerResults(state, results)
+	return power.Failed(results)
+}
+
+func printPowerResults(state power.State, results []power.Result) {
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("%s: no response via %s (%v)\n", r.Display, r.Method, r.Err)
+		} else {
+			fmt.Printf("%s: %s via %s\n", r.Display, state, r.Method)
+		}
+	}
+}
+
+func printPowerHelp() {
+	fmt.Println("Usage: lumos power on|off|standby [--display <display>[,...]] [--method auto|ddc|broadcast]")
+	fmt.Println()
+	fmt.Println("Change the power state of displays. Broadcast uses SC_MONITORPOWER on Windows")
+	fmt.Println("and DPMS on X11 and always affects all displays; DDC/CI (VCP 0xD6) targets")
+	fmt.Println("individual monitors. Auto uses broadcast for all displays and DDC/CI otherwise.")
+	fmt.Println()
+	fmt.Println("Options:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --display <display>[,...]\tOnly use the given displays by number, ID or name (default: all)")
+	fmt.Fprintln(w, "  --method auto|ddc|broadcast\tPower control method (default: auto)")
+	w.Flush()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/gamma"
+)
+
+func runPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ContinueOnError)
+	var sel display.Selector
+	fs.Var(&sel, "display", "Start from the saved gamma layers of this display")
+	var layers layerFlags
+	layers.register(fs)
+	compare := fs.Bool("compare", false, "Put the original and the preview side by side")
+	fs.Usage = printPreviewHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		printPreviewHelp()
+		return nil
+	}
+	in, out := positional[0], positional[1]
+
+	p, err := previewPipeline(sel, &layers)
+	if err != nil {
+		return err
+	}
+
+	// Same ramp generation and range check as the live path
+	ramp, err := p.Ramp()
+	if err != nil {
+		return err
+	}
+	gammaRange, err := gamma.GammaRange()
+	if err != nil {
+		return err
+	}
+	ramp, rangeErr := gamma.Fit(ramp, gammaRange)
+	if rangeErr != nil {
+		fmt.Printf("Warning: %v\n", rangeErr)
+	}
+
+	img, err := readImage(in)
+	if err != nil {
+		return err
+	}
+	var result image.Image = gamma.ApplyToImage(img, &ramp)
+	if *compare {
+		result = sideBySide(img, result)
+	}
+	if err := writeImage(out, result); err != nil {
+		return err
+	}
+
+	fmt.Printf("Preview with %s written to %s\n", pipelineSummary(p), out)
+	return nil
+}
+
+// layerFlags are the --gamma, --scale, --temperature, --whitepoint and
+// --curve flags that change a pipeline without applying it
+type layerFlags struct {
+	brightness  *int
+	scale       brightness.Scale
+	temperature *string
+	whitepoint  *string
+	curve       *string
+}
+
+func (f *layerFlags) register(fs *flag.FlagSet) {
+	f.brightness = fs.Int("gamma", -1, "Gamma brightness percentage (0-100)")
+	fs.Var(&f.scale, "scale", "Brightness scale for --gamma (linear/lightness/power)")
+	f.temperature = fs.String("temperature", "", "Color temperature in kelvin, e.g. 2700")
+	f.whitepoint = fs.String("whitepoint", "", "White point as x,y chromaticity or an illuminant, e.g. D50")
+	f.curve = fs.String("curve", "", "Tone curve, a gamma or control points like 0:0,0.5:0.45,1:1")
+}
+
+// apply sets the layers given on the command line
+func (f *layerFlags) apply(p *gamma.Pipeline) error {
+	if *f.temperature != "" && *f.whitepoint != "" {
+		return errors.New("--temperature and --whitepoint both set the temperature layer, use one")
+	}
+	if *f.brightness != -1 {
+		if err := p.SetLayer(gamma.LayerBrightness, brightnessValue(*f.brightness, f.scale)); err != nil {
+			return err
+		}
+	}
+	if *f.temperature != "" {
+		if err := p.SetLayer(gamma.LayerTemperature, *f.temperature); err != nil {
+			return err
+		}
+	}
+	if *f.whitepoint != "" {
+		if _, err := gamma.ParseWhitePoint(*f.whitepoint); err != nil {
+			return err
+		}
+		if err := p.SetLayer(gamma.LayerTemperature, *f.whitepoint); err != nil {
+			return err
+		}
+	}
+	if *f.curve != "" {
+		if err := p.SetLayer(gamma.LayerCurve, *f.curve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// previewPipeline returns the pipeline lumos would program for the selected
+// display with the layers of the command line, from a copy of its saved
+// pipeline, or from an empty pipeline without --display
+func previewPipeline(sel display.Selector, layers *layerFlags) (*gamma.Pipeline, error) {
+	if sel.IsAll() {
+		p := &gamma.Pipeline{}
+		return p, layers.apply(p)
+	}
+
+	displays, err := sel.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if len(displays) != 1 {
+		return nil, fmt.Errorf("select one display to preview with --display (%d selected)", len(displays))
+	}
+	pipelines, err := loadPipelines()
+	if err != nil {
+		return nil, err
+	}
+	return displayPipeline(pipelines.Clone(), displays[0], layers.apply)
+}
+
+func readImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return img, nil
+}
+
+// writeImage encodes an image as PNG or JPEG by the file extension
+func writeImage(path string, img image.Image) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+		return fmt.Errorf("unsupported output type: %s (use .png, .jpg or .jpeg)", filepath.Base(path))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if ext == ".png" {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: 95})
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// sideBySide places two images of the same size next to each other
+func sideBySide(left, right image.Image) image.Image {
+	b := left.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx()*2, b.Dy()))
+	draw.Draw(out, image.Rect(0, 0, b.Dx(), b.Dy()), left, b.Min, draw.Src)
+	draw.Draw(out, image.Rect(b.Dx(), 0, b.Dx()*2, b.Dy()), right, right.Bounds().Min, draw.Src)
+	return out
+}
+
+func printPreviewHelp() {
+	fmt.Println("Usage: lumos preview [--gamma <0-100>] [--scale <scale>] [--temperature <kelvin>|--whitepoint <x,y>] [--curve <curve>] <in> <out>")
+	fmt.Println()
+	fmt.Println("Run an image through the gamma ramp lumos would program, to compare settings")
+	fmt.Println("before applying them. Reads PNG, JPEG and GIF, writes PNG or JPEG.")
+	fmt.Println()
+	fmt.Println("Options:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --gamma <0-100>\tBrightness layer, same as lumos --gamma")
+	fmt.Fprintln(w, "  --scale <scale>\tBrightness scale for --gamma: linear, lightness or power")
+	fmt.Fprintln(w, "  --temperature <kelvin>\tTemperature layer, e.g. 2700 or 5000K daylight")
+	fmt.Fprintln(w, "  --whitepoint <x>,<y>\tTemperature layer as a white point, e.g. D50")
+	fmt.Fprintln(w, "  --curve <curve>\tCurve layer, same as lumos --curve")
+	fmt.Fprintln(w, "  --display <display>\tStart from the saved gamma layers and calibration of this display (default: none)")
+	fmt.Fprintln(w, "  --compare\tPut the original and the preview side by side")
+	w.Flush()
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jipaix/lumos/apply"
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/gamma"
+	"github.com/jipaix/lumos/hdr"
+	"github.com/jipaix/lumos/mode"
+	n "github.com/jipaix/lumos/night"
+	"github.com/jipaix/lumos/revert"
+)
+
+// snapshotter is a step that can record its captured state for a revert
+type snapshotter interface {
+	snapshot(s *revert.Snapshot)
+}
+
+func runRevert(args []string) error {
+	fs := flag.NewFlagSet("revert", flag.ContinueOnError)
+	fs.Usage = printRevertHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	path, err := revert.Path()
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		return handleRevertStatus(path)
+	}
+
+	switch positional[0] {
+	case "now":
+		snap, err := revert.Take(path, "")
+		if errors.Is(err, revert.ErrNoPending) {
+			fmt.Println("No pending revert")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return restoreSnapshot(snap)
+	case "cancel":
+		_, err := revert.Take(path, "")
+		if errors.Is(err, revert.ErrNoPending) {
+			fmt.Println("No pending revert")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("Pending revert canceled, the current settings are kept")
+		return nil
+	case "wait":
+		// Run by the detached helper started for --for
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: lumos revert wait <token>")
+		}
+		snap, err := revert.Wait(revert.SystemClock, path, positional[1], revert.PollInterval)
+		if errors.Is(err, revert.ErrNoPending) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: reverting %v\n", time.Now().Format(time.RFC3339), snap.Changes)
+		return restoreSnapshot(snap)
+	default:
+		return fmt.Errorf("invalid revert action: %s (must be 'now' or 'cancel')", positional[0])
+	}
+}
+
+func handleRevertStatus(path string) error {
+	snap, err := revert.Load(path)
+	if errors.Is(err, revert.ErrNoPending) {
+		fmt.Println("No pending revert")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Reverting %v at %s (in %s)\n", snap.Changes, snap.RevertAt.Format("15:04:05"),
+		snap.Remaining(time.Now()).Round(time.Second))
+	return nil
+}
+
+// scheduleRevert saves the state captured by the plan's steps and starts a
+// detached helper that restores it after d
+func scheduleRevert(plan *apply.Plan, d time.Duration) error {
+	snap, err := planSnapshot(plan, d)
+	if err != nil {
+		return err
+	}
+	if err := startRevert(snap); err != nil {
+		return err
+	}
+
+	fmt.Printf("Reverting at %s (in %s); use 'lumos revert now' or 'lumos revert cancel'\n",
+		snap.RevertAt.Format("15:04:05"), d)
+	return nil
+}
+
+// planSnapshot records the state captured by the plan's steps, due after d
+func planSnapshot(plan *apply.Plan, d time.Duration) (*revert.Snapshot, error) {
+	snap, err := revert.NewSnapshot(time.Now(), d)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range plan.Steps() {
+		snap.Changes = append(snap.Changes, step.Name())
+		if s, ok := step.(snapshotter); ok {
+			s.snapshot(snap)
+		}
+	}
+	return snap, nil
+}
+
+// startRevert saves a snapshot and starts the detached helper that
+// restores it when it is due
+func startRevert(snap *revert.Snapshot) error {
+	path, err := revert.Path()
+	if err != nil {
+		return err
+	}
+
+	if err := snap.Save(path); err != nil {
+		return fmt.Errorf("failed to save revert state: %v", err)
+	}
+	if err := startRevertHelper(path, snap.Token); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to start revert helper: %v", err)
+	}
+	return nil
+}
+
+// checkNoPendingRevert fails when a revert is already scheduled, so a
+// second --for doesn't lose the state the first one would restore
+func checkNoPendingRevert() error {
+	path, err := revert.Path()
+	if err != nil {
+		return err
+	}
+	snap, err := revert.Load(path)
+	if errors.Is(err, revert.ErrNoPending) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("a revert of %v is already pending; run 'lumos revert now' or 'lumos revert cancel' first", snap.Changes)
+}
+
+// startRevertHelper starts "lumos revert wait <token>" detached from the
+// terminal, logging next to the revert file
+func startRevertHelper(path, token string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	log, err := os.Create(filepath.Join(filepath.Dir(path), "revert.log"))
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	cmd := exec.Command(exe, "revert", "wait", token)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// restoreSnapshot restores display modes, HDR, gamma and night light, in the
+// order they are applied. Displays that are no longer connected are skipped.
+func restoreSnapshot(snap *revert.Snapshot) error {
+	displays, err := display.List()
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]display.Display, len(displays))
+	for _, d := range displays {
+		byID[d.ID] = d
+	}
+
+	var errs []error
+	for _, st := range snap.Modes {
+		d, ok := byID[st.ID]
+		if !ok {
+			fmt.Printf("%s: no longer connected, mode not restored\n", st.ID)
+			continue
+		}
+		if err := mode.Apply(d.DeviceName, st.Mode); err != nil {
+			fmt.Printf("%s: failed to restore %s (%v)\n", d.Label(), st.Mode, err)
+			errs = append(errs, fmt.Errorf("%s: %w", d.Label(), err))
+		} else {
+			fmt.Printf("%s: restored %s\n", d.Label(), st.Mode)
+		}
+	}
+
+	if len(snap.HDR) > 0 {
+		step := &hdrStep{state: "restore", ctrl: hdr.NewHDR()}
+		for _, st := range snap.HDR {
+			if d, ok := byID[st.ID]; ok {
+				step.prev = append(step.prev, hdr.State{Display: d, HDRSupported: true, HDREnabled: st.Enabled})
+			} else {
+				fmt.Printf("%s: no longer connected, HDR not restored\n", st.ID)
+			}
+		}
+		errs = append(errs, step.Rollback())
+	}
+
+	if len(snap.Gamma) > 0 {
+		errs = append(errs, restoreGamma(snap.Gamma, byID))
+	}
+
+	if snap.Night != nil {
+		step := &nightStep{nl: n.NewLumos(), wasEnabled: snap.Night.Enabled, prevStrength: snap.Night.Strength}
+		err := step.Rollback()
+		if err == nil {
+			fmt.Println("Night light restored")
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// restoreGamma restores the gamma ramps and pipeline layers of a snapshot
+func restoreGamma(states []revert.GammaState, byID map[string]display.Display) error {
+	path, err := gamma.PipelinesPath()
+	if err != nil {
+		return err
+	}
+	pipelines, err := gamma.LoadPipelines(path)
+	if err != nil {
+		return err
+	}
+
+	step := &gammaStep{path: path, prevPipelines: pipelines, prev: make(map[string]gamma.GammaRamp)}
+	for _, st := range states {
+		if st.Pipeline != nil {
+			pipelines[st.ID] = st.Pipeline
+		} else {
+			delete(pipelines, st.ID)
+		}
+		if d, ok := byID[st.ID]; ok {
+			step.displays = append(step.displays, d)
+			step.prev[st.ID] = st.Ramp
+		} else {
+			fmt.Printf("%s: no longer connected, gamma not restored\n", st.ID)
+		}
+	}
+	return step.Rollback()
+}
+
+func printRevertHelp() {
+	fmt.Println("Usage: lumos revert [now|cancel]")
+	fmt.Println()
+	fmt.Println("Manage the revert scheduled by --for, e.g. 'lumos --gamma 100 --for 45m'. The")
+	fmt.Println("previous settings are restored by a background helper even if the terminal is")
+	fmt.Println("closed. Without an action, shows the pending revert.")
+	fmt.Println()
+	fmt.Println("Actions:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  now\tRestore the previous settings immediately")
+	fmt.Fprintln(w, "  cancel\tKeep the current settings and drop the pending revert")
+	w.Flush()
+}
@@ -0,0 +1,587 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jipaix/lumos/apply"
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/gamma"
+	"github.com/jipaix/lumos/hdr"
+	n "github.com/jipaix/lumos/night"
+	"github.com/jipaix/lumos/revert"
+)
+
+// runPlan applies a plan and reports each rolled back step
+func runPlan(plan *apply.Plan) error {
+	plan.OnRollback = func(step apply.Step, err error) {
+		if err != nil {
+			fmt.Printf("Failed to roll back %s: %v\n", step.Name(), err)
+		} else {
+			fmt.Printf("Rolled back %s\n", step.Name())
+		}
+	}
+	return plan.Run()
+}
+
+// hdrStep switches HDR on all displays
+type hdrStep struct {
+	state string
+	ctrl  *hdr.HDR
+	prev  []hdr.State
+}
+
+func newHDRStep(state string) (*hdrStep, error) {
+	switch state {
+	case "on", "off", "toggle":
+	default:
+		return nil, fmt.Errorf("invalid HDR state: %s (must be 'on', 'off', or 'toggle')", state)
+	}
+	return &hdrStep{state: state, ctrl: hdr.NewHDR()}, nil
+}
+
+func (s *hdrStep) Name() string       { return "HDR " + s.state }
+func (s *hdrStep) Stage() apply.Stage { return apply.StageHDR }
+
+func (s *hdrStep) Capture() error {
+	// Check if HDR is supported
+	if !s.ctrl.IsHDRSupported() {
+		return fmt.Errorf("HDR is not supported on this system or requires administrator privileges")
+	}
+
+	prev, err := s.ctrl.States(display.All)
+	if err != nil {
+		return err
+	}
+	s.prev = prev
+	return nil
+}
+
+func (s *hdrStep) Apply() error {
+	var results []hdr.Result
+	var err error
+	switch s.state {
+	case "on":
+		results, err = s.ctrl.Enable(display.All)
+	case "off":
+		results, err = s.ctrl.Disable(display.All)
+	case "toggle":
+		results, err = s.ctrl.Toggle(display.All)
+	}
+	if err != nil {
+		return err
+	}
+
+	printHDRResults(results)
+	return hdr.Failed(results)
+}
+
+func (s *hdrStep) Preview() error {
+	var calls []hdr.Call
+	var err error
+	switch s.state {
+	case "on", "off":
+		calls, err = s.ctrl.PlanSetHDR(s.state == "on", display.All)
+	case "toggle":
+		calls, err = s.ctrl.PlanToggle(display.All)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, c := range calls {
+		switch c.Outcome {
+		case hdr.Changed:
+			fmt.Printf("%s: %s would call %s\n", c.Display.Label(), c.Action, c)
+		case hdr.Failure:
+			fmt.Printf("%s: %s unknown (%v)\n", c.Display.Label(), c.Action, c.Err)
+		default:
+			fmt.Printf("%s: %s (%s, no call)\n", c.Display.Label(), c.Action, c.Outcome)
+		}
+	}
+	return nil
+}
+
+func (s *hdrStep) snapshot(snap *revert.Snapshot) {
+	for _, st := range s.prev {
+		if st.Err == nil && st.HDRSupported {
+			snap.HDR = append(snap.HDR, revert.HDRState{ID: st.Display.ID, Enabled: st.HDREnabled})
+		}
+	}
+}
+
+func (s *hdrStep) Rollback() error {
+	// Group the displays by their previous state, one call per state
+	var on, off []display.Display
+	for _, st := range s.prev {
+		switch {
+		case st.Err != nil || !st.HDRSupported:
+		case st.HDREnabled:
+			on = append(on, st.Display)
+		default:
+			off = append(off, st.Display)
+		}
+	}
+
+	var errs []error
+	for _, group := range []struct {
+		enable   bool
+		displays []display.Display
+	}{{true, on}, {false, off}} {
+		if len(group.displays) == 0 {
+			continue
+		}
+		results, err := s.ctrl.SetHDR(group.enable, display.Only(group.displays...))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		printHDRResults(results)
+		errs = append(errs, hdr.Failed(results))
+	}
+	return errors.Join(errs...)
+}
+
+// gammaStep sets or resets layers of the gamma pipeline of the selected
+// displays and applies the recomputed ramps
+type gammaStep struct {
+	changes []gammaChange
+	sel     display.Selector
+	dryRun  dryRun
+
+	path          string
+	next          gamma.Pipelines
+	prevPipelines gamma.Pipelines
+	prev          map[string]gamma.GammaRamp // By stable display ID
+	displays      []display.Display
+}
+
+// gammaChange sets or resets one pipeline layer
+type gammaChange struct {
+	layer string // Empty with reset for all layers
+	value string
+	reset bool
+}
+
+// newGammaStep creates a step without changes, add them with setLayer
+func newGammaStep(sel display.Selector, mode dryRun) *gammaStep {
+	return &gammaStep{sel: sel, dryRun: mode}
+}
+
+// newGammaLayerStep sets one pipeline layer, checking the value up front
+func newGammaLayerStep(layer, value string, sel display.Selector, mode dryRun) (*gammaStep, error) {
+	s := newGammaStep(sel, mode)
+	return s, s.setLayer(layer, value)
+}
+
+// newGammaResetStep removes one pipeline layer, or all of them when layer
+// is empty
+func newGammaResetStep(layer string, sel display.Selector, mode dryRun) (*gammaStep, error) {
+	if layer != "" {
+		if err := (&gamma.Pipeline{}).ResetLayer(layer); err != nil {
+			return nil, err
+		}
+	}
+	return &gammaStep{changes: []gammaChange{{layer: layer, reset: true}}, sel: sel, dryRun: mode}, nil
+}
+
+// setLayer adds a layer change to the step, checking the value up front
+func (s *gammaStep) setLayer(layer, value string) error {
+	for _, c := range s.changes {
+		if c.layer == layer && !c.reset {
+			return fmt.Errorf("the %s layer is set twice (%s and %s), use one", layer, c.value, value)
+		}
+	}
+	if layer != gamma.LayerCalibration || value != gamma.CalibrationActive {
+		if err := (&gamma.Pipeline{}).SetLayer(layer, value); err != nil {
+			return err
+		}
+	}
+	s.changes = append(s.changes, gammaChange{layer: layer, value: value})
+	return nil
+}
+
+// setBrightness adds a change of the brightness layer to the step
+func (s *gammaStep) setBrightness(percentage int, scale brightness.Scale) error {
+	if percentage < 0 || percentage > 100 {
+		return fmt.Errorf("gamma percentage must be between 0 and 100, got %d", percentage)
+	}
+	return s.setLayer(gamma.LayerBrightness, brightnessValue(percentage, scale))
+}
+
+// setWhitePoint adds a change of the temperature layer to a white point
+func (s *gammaStep) setWhitePoint(value string) error {
+	if _, err := gamma.ParseWhitePoint(value); err != nil {
+		return err
+	}
+	return s.setLayer(gamma.LayerTemperature, value)
+}
+
+// brightnessValue is the brightness layer value of a percentage on a scale
+func brightnessValue(percentage int, scale brightness.Scale) string {
+	if scale.IsLinear() {
+		return strconv.Itoa(percentage)
+	}
+	return strconv.Itoa(percentage) + " " + scale.String()
+}
+
+func (s *gammaStep) Name() string {
+	names := make([]string, len(s.changes))
+	for i, c := range s.changes {
+		names[i] = c.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c gammaChange) String() string {
+	switch {
+	case c.reset && c.layer == "":
+		return "gamma reset"
+	case c.reset:
+		return "gamma " + c.layer + " reset"
+	case c.layer == gamma.LayerBrightness:
+		if l, err := gamma.ParseBrightness(c.value); err == nil {
+			return "gamma " + l.String()
+		}
+		return "gamma " + c.value
+	default:
+		return "gamma " + c.layer + " " + c.value
+	}
+}
+
+func (s *gammaStep) Stage() apply.Stage { return apply.StageGamma }
+
+func (s *gammaStep) Capture() error {
+	displays, err := s.sel.Resolve()
+	if err != nil {
+		return err
+	}
+	if len(displays) == 0 {
+		return errors.New("no displays found")
+	}
+
+	path, err := gamma.PipelinesPath()
+	if err != nil {
+		return err
+	}
+	pipelines, err := gamma.LoadPipelines(path)
+	if err != nil {
+		return err
+	}
+
+	s.path = path
+	s.displays = displays
+	s.prevPipelines = pipelines.Clone()
+	s.next = pipelines
+	s.prev = make(map[string]gamma.GammaRamp, len(displays))
+	for _, d := range displays {
+		ramp, err := gamma.GetRamp(d)
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.Label(), err)
+		}
+		s.prev[d.ID] = ramp
+
+		// Compute the new ramp now so a bad layer combination changes nothing
+		p, err := displayPipeline(s.next, d, func(p *gamma.Pipeline) error { return s.update(p, d) })
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.Label(), err)
+		}
+		if _, err := p.Ramp(); err != nil {
+			return fmt.Errorf("%s: %v", d.Label(), err)
+		}
+	}
+	return nil
+}
+
+// displayPipeline returns the pipeline lumos programs for a display: its
+// pipeline in pipelines after change, with the calibration of the active
+// profile as the base when it has no calibration layer
+func displayPipeline(pipelines gamma.Pipelines, d display.Display, change func(p *gamma.Pipeline) error) (*gamma.Pipeline, error) {
+	p := pipelines.For(d.ID)
+	if err := change(p); err != nil {
+		return nil, err
+	}
+	if p.Calibration == nil {
+		if l, err := gamma.ActiveCalibration(d); err == nil {
+			p.Calibration = l
+		}
+	}
+	return p, nil
+}
+
+// update applies the changes of this step to the pipeline of a display
+func (s *gammaStep) update(p *gamma.Pipeline, d display.Display) error {
+	for _, c := range s.changes {
+		if err := c.apply(p, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply sets or resets the layer of a display's pipeline
+func (c gammaChange) apply(p *gamma.Pipeline, d display.Display) error {
+	switch {
+	case c.reset && c.layer == "":
+		*p = gamma.Pipeline{}
+		return nil
+	case c.reset:
+		return p.ResetLayer(c.layer)
+	case c.layer == gamma.LayerCalibration && c.value == gamma.CalibrationActive:
+		l, err := gamma.ActiveCalibration(d)
+		if err != nil {
+			return err
+		}
+		if l == nil {
+			l = gamma.LinearCalibration()
+			l.Source = "active profile (no calibration curves)"
+		}
+		p.Calibration = l
+		return nil
+	default:
+		return p.SetLayer(c.layer, c.value)
+	}
+}
+
+func (s *gammaStep) Apply() error {
+	results, err := gamma.ApplyPipelines(s.next, s.Name(), display.Only(s.displays...))
+	if err != nil {
+		return err
+	}
+	printGammaResults(results)
+
+	// Keep the layers even on failure, the rollback saves the previous ones
+	return errors.Join(gamma.Failed(results), s.next.Save(s.path))
+}
+
+func (s *gammaStep) Preview() error {
+	gammaRange, err := gamma.GammaRange()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range s.displays {
+		current := s.prev[d.ID]
+		ramp, err := s.next.For(d.ID).Ramp()
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.Label(), err)
+		}
+		ramp, rangeErr := gamma.Fit(ramp, gammaRange)
+		if current == ramp {
+			fmt.Printf("%s: %s (already set, no write)\n", d.Label(), s.Name())
+			continue
+		}
+
+		fmt.Printf("%s: %s would call SetDeviceGammaRamp on %s\n", d.Label(), s.Name(), d.DeviceName)
+		fmt.Printf("    layers:  %s\n", pipelineSummary(s.next.For(d.ID)))
+		fmt.Printf("    current: %s\n", rampSummary(&current))
+		fmt.Printf("    new:     %s\n", rampSummary(&ramp))
+		if rangeErr != nil {
+			fmt.Printf("    warning: %v\n", rangeErr)
+		}
+		if s.dryRun == "full" {
+			printRampTable(&current, &ramp)
+		}
+	}
+	return nil
+}
+
+func (s *gammaStep) snapshot(snap *revert.Snapshot) {
+	for _, d := range s.displays {
+		snap.Gamma = append(snap.Gamma, revert.GammaState{ID: d.ID, Ramp: s.prev[d.ID], Pipeline: s.prevPipelines[d.ID]})
+	}
+}
+
+func (s *gammaStep) Rollback() error {
+	var errs []error
+	for _, d := range s.displays {
+		ramp, ok := s.prev[d.ID]
+		if !ok {
+			continue
+		}
+		results, err := gamma.SetRamp(&ramp, "previous gamma", display.Only(d))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		printGammaResults(results)
+		errs = append(errs, gamma.Failed(results))
+	}
+	if s.prevPipelines != nil {
+		errs = append(errs, s.prevPipelines.Save(s.path))
+	}
+	return errors.Join(errs...)
+}
+
+func printGammaResults(results []gamma.Result) {
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("%s: %s failed (%v)\n", r.Display.Label(), r.Action, r.Err)
+		} else {
+			fmt.Printf("%s: %s\n", r.Display.Label(), r.Action)
+		}
+		if r.Warning != nil {
+			fmt.Printf("%s: warning: %v\n", r.Display.Label(), r.Warning)
+		}
+	}
+}
+
+// nightStep switches night light or sets its strength
+type nightStep struct {
+	state    string
+	strength float64 // Requested strength when state is a percentage
+	nl       *n.Lumos
+
+	wasEnabled   bool
+	prevStrength float64
+}
+
+func newNightStep(state string) (*nightStep, error) {
+	s := &nightStep{state: state, nl: n.NewLumos()}
+	switch state {
+	case "on", "off", "toggle":
+	default:
+		val, err := strconv.ParseFloat(state, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid night light state: %s (must be 'on', 'off', 'toggle', or a percentage like '50')", state)
+		}
+		s.strength = val
+	}
+	return s, nil
+}
+
+func (s *nightStep) Name() string       { return "night light " + s.state }
+func (s *nightStep) Stage() apply.Stage { return apply.StageNight }
+
+func (s *nightStep) Capture() error {
+	enabled, err := s.nl.Enabled()
+	if err != nil {
+		return err
+	}
+	strength, err := s.nl.GetStrength()
+	if err != nil {
+		return err
+	}
+	s.wasEnabled, s.prevStrength = enabled, strength
+	return nil
+}
+
+func (s *nightStep) Apply() error {
+	switch s.state {
+	case "on":
+		if err := s.nl.Enable(); err != nil {
+			return err
+		}
+		fmt.Println("Night light enabled")
+	case "off":
+		if err := s.nl.Disable(); err != nil {
+			return err
+		}
+		fmt.Println("Night light disabled")
+	case "toggle":
+		if err := s.nl.Toggle(); err != nil {
+			return err
+		}
+		fmt.Println("Night light toggled")
+	default:
+		if err := setNightStrength(s.nl, s.strength); err != nil {
+			return err
+		}
+		fmt.Printf("Night light strength set to %v%%\n", s.strength)
+	}
+	return nil
+}
+
+func (s *nightStep) Preview() error {
+	var changes []*n.Change
+	switch s.state {
+	case "on", "off", "toggle":
+		var c *n.Change
+		var err error
+		switch s.state {
+		case "on":
+			c, err = s.nl.PlanEnable()
+		case "off":
+			c, err = s.nl.PlanDisable()
+		default:
+			c, err = s.nl.PlanToggle()
+		}
+		if err != nil {
+			return err
+		}
+		changes = append(changes, c)
+	default:
+		c, err := s.nl.PlanStrength(s.strength)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, c)
+
+		if s.wasEnabled {
+			fmt.Println("Night light: would be turned off and on again to apply the new strength")
+		} else {
+			c, err := s.nl.PlanEnable()
+			if err != nil {
+				return err
+			}
+			changes = append(changes, c)
+		}
+	}
+
+	for _, c := range changes {
+		if c == nil {
+			fmt.Printf("Night light: %s (already set, no write)\n", s.Name())
+			continue
+		}
+		fmt.Printf("Night light: would write HKCU\\%s\\Data (%d -> %d bytes)\n", c.Key, len(c.Old), len(c.New))
+		hexDiff(c.Old, c.New)
+	}
+	return nil
+}
+
+func (s *nightStep) snapshot(snap *revert.Snapshot) {
+	snap.Night = &revert.NightState{Enabled: s.wasEnabled, Strength: s.prevStrength}
+}
+
+func (s *nightStep) Rollback() error {
+	if err := s.nl.SetStrength(s.prevStrength); err != nil {
+		return err
+	}
+	if s.wasEnabled {
+		// Restart night light so the restored strength takes effect
+		if err := s.nl.Disable(); err != nil {
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+		return s.nl.Enable()
+	}
+	return s.nl.Disable()
+}
+
+// setNightStrength sets the night light strength and turns it on
+func setNightStrength(nl *n.Lumos, val float64) error {
+	if err := nl.SetStrength(val); err != nil {
+		return err
+	}
+
+	// Check if currently enabled to decide if we need a "hard restart"
+	enabled, err := nl.Enabled()
+	if err != nil {
+		return err
+	}
+
+	// If it's currently on, disable it first to force the refresh
+	if enabled {
+		if err := nl.Disable(); err != nil {
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	// Turn it on (or back on)
+	return nl.Enable()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/topology"
+)
+
+func runTopology(args []string) error {
+	fs := flag.NewFlagSet("topology", flag.ContinueOnError)
+	fs.Usage = printTopologyHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		current, err := topology.Current()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Current topology: %s\n", current)
+		return nil
+	}
+
+	switch positional[0] {
+	case "save":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: lumos topology save <file>")
+		}
+		return handleTopologySave(positional[1])
+	case "restore":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: lumos topology restore <file>")
+		}
+		return handleTopologyRestore(positional[1])
+	}
+
+	if len(positional) != 1 {
+		printTopologyHelp()
+		return nil
+	}
+
+	t, err := topology.Parse(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := topology.Apply(t); err != nil {
+		return err
+	}
+	fmt.Printf("Topology set to %s\n", t)
+	return nil
+}
+
+func handleTopologySave(path string) error {
+	config, err := topology.Capture()
+	if err != nil {
+		return err
+	}
+	if err := config.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved %d display paths to %s\n", len(config.Paths), path)
+	for _, p := range config.Paths {
+		fmt.Printf("  %s\n", p.Monitor)
+	}
+	return nil
+}
+
+func handleTopologyRestore(path string) error {
+	config, err := topology.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := topology.Restore(config); err != nil {
+		return err
+	}
+	fmt.Printf("Restored display configuration from %s\n", path)
+	return nil
+}
+
+func printTopologyHelp() {
+	fmt.Println("Usage: lumos topology [extend|clone|internal|external]")
+	fmt.Println("       lumos topology save|restore <file>")
+	fmt.Println()
+	fmt.Println("Switch between display topologies like Win+P does, or save the exact layout,")
+	fmt.Println("resolution and refresh rate of every display to a file and restore it later.")
+	fmt.Println("Monitors are matched by identity, so a layout survives docking and reboots.")
+	fmt.Println()
+	fmt.Println("Topologies:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  extend\tExtend the desktop across all displays")
+	fmt.Fprintln(w, "  clone\tShow the same desktop on all displays")
+	fmt.Fprintln(w, "  internal\tOnly use the built-in display")
+	fmt.Fprintln(w, "  external\tOnly use external displays")
+	w.Flush()
+}
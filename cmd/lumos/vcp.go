@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/ddc"
+	"github.com/jipaix/lumos/display"
+)
+
+func runVCP(args []string) error {
+	fs := flag.NewFlagSet("vcp", flag.ContinueOnError)
+	var sel display.Selector
+	fs.Var(&sel, "display", "Displays to use (all, or numbers, IDs or names like 1,3)")
+	var scale brightness.Scale
+	fs.Var(&scale, "scale", "Scale for percentage values (linear/lightness/power)")
+	fs.Usage = printVCPHelp
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		printVCPHelp()
+		return nil
+	}
+
+	monitors, err := ddc.Monitors()
+	if err != nil {
+		return err
+	}
+	defer ddc.CloseAll(monitors)
+
+	selected, err := selectMonitors(monitors, sel)
+	if err != nil {
+		return err
+	}
+
+	switch positional[0] {
+	case "get":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: lumos vcp get <code>")
+		}
+		code, err := ddc.ParseCode(positional[1])
+		if err != nil {
+			return err
+		}
+		return handleVCPGet(selected, code)
+	case "set":
+		if len(positional) != 3 {
+			return fmt.Errorf("usage: lumos vcp set <code> <value>")
+		}
+		code, err := ddc.ParseCode(positional[1])
+		if err != nil {
+			return err
+		}
+		value, err := parseVCPValue(positional[2])
+		if err != nil {
+			return err
+		}
+		return handleVCPSet(selected, code, value, scale)
+	case "caps":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: lumos vcp caps")
+		}
+		return handleVCPCaps(selected)
+	default:
+		return fmt.Errorf("invalid vcp action: %s (must be 'get', 'set', or 'caps')", positional[0])
+	}
+}
+
+// ddcDisplay is a DDC/CI monitor and the display it belongs to
+type ddcDisplay struct {
+	monitor *ddc.Monitor
+	label   string
+}
+
+// selectMonitors returns the DDC/CI monitors of the selected displays
+func selectMonitors(monitors []*ddc.Monitor, sel display.Selector) ([]ddcDisplay, error) {
+	displays, err := sel.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []ddcDisplay
+	for _, m := range monitors {
+		if d, ok := display.FindByDeviceName(displays, m.DeviceName); ok {
+			selected = append(selected, ddcDisplay{monitor: m, label: d.Label()})
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("none of the selected displays support DDC/CI (%d DDC/CI monitors available)", len(monitors))
+	}
+	return selected, nil
+}
+
+func handleVCPGet(monitors []ddcDisplay, code byte) error {
+	var errs []error
+	for _, m := range monitors {
+		current, max, err := m.monitor.GetVCP(code)
+		if err != nil {
+			fmt.Printf("%s: %v\n", m.label, err)
+			errs = append(errs, fmt.Errorf("%s: %w", m.label, err))
+			continue
+		}
+
+		value := fmt.Sprintf("%d", current)
+		if name := ddc.ValueName(code, byte(current)); name != "" {
+			value += " (" + name + ")"
+		}
+		fmt.Printf("%s: 0x%02X %s = %s (max %d)\n", m.label, code, ddc.CodeName(code), value, max)
+	}
+	return errors.Join(errs...)
+}
+
+// vcpValue is a raw VCP value or a percentage of the feature's maximum
+type vcpValue struct {
+	raw      uint32
+	percent  float64
+	relative bool
+}
+
+// parseVCPValue parses a raw value like "30" or "0x1E", or a percentage
+// like "50%"
+func parseVCPValue(s string) (vcpValue, error) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasSuffix(trimmed, "%") {
+		raw, err := ddc.ParseValue(s)
+		return vcpValue{raw: raw}, err
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(trimmed, "%")), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return vcpValue{}, fmt.Errorf("invalid VCP percentage: %q (must be 0-100%%)", s)
+	}
+	return vcpValue{percent: percent, relative: true}, nil
+}
+
+// resolve returns the raw value for a monitor. Percentages are mapped
+// through scale onto the maximum the monitor reports for the code.
+func (v vcpValue) resolve(m *ddc.Monitor, code byte, scale brightness.Scale) (uint32, error) {
+	if !v.relative {
+		return v.raw, nil
+	}
+	_, max, err := m.GetVCP(code)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(math.Round(scale.Level(v.percent) * float64(max))), nil
+}
+
+func handleVCPSet(monitors []ddcDisplay, code byte, v vcpValue, scale brightness.Scale) error {
+	var errs []error
+	for _, m := range monitors {
+		value, err := v.resolve(m.monitor, code, scale)
+		if err == nil {
+			err = m.monitor.SetVCP(code, value)
+		}
+		if err != nil {
+			fmt.Printf("%s: %v\n", m.label, err)
+			errs = append(errs, fmt.Errorf("%s: %w", m.label, err))
+			continue
+		}
+		fmt.Printf("%s: 0x%02X %s set to %d\n", m.label, code, ddc.CodeName(code), value)
+	}
+	return errors.Join(errs...)
+}
+
+func handleVCPCaps(monitors []ddcDisplay) error {
+	var errs []error
+	for _, m := range monitors {
+		caps, err := m.monitor.Capabilities()
+		if err != nil {
+			fmt.Printf("%s: %v\n", m.label, err)
+			errs = append(errs, fmt.Errorf("%s: %w", m.label, err))
+			continue
+		}
+
+		model := caps.Model
+		if model == "" {
+			model = m.monitor.Description
+		}
+		fmt.Printf("%s: %s (MCCS %s)\n", m.label, model, caps.MCCSVersion)
+
+		for _, vcp := range caps.VCP {
+			line := fmt.Sprintf("  0x%02X  %s", vcp.Code, ddc.CodeName(vcp.Code))
+			if len(vcp.Values) > 0 {
+				values := make([]string, len(vcp.Values))
+				for i, v := range vcp.Values {
+					values[i] = fmt.Sprintf("0x%02X", v)
+					if name := ddc.ValueName(vcp.Code, v); name != "" {
+						values[i] += " (" + name + ")"
+					}
+				}
+				line += ": " + strings.Join(values, ", ")
+			}
+			fmt.Println(line)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func printVCPHelp() {
+	fmt.Println("Usage: lumos vcp get <code> | set <code> <value> | caps [--display <display>[,...]]")
+	fmt.Println()
+	fmt.Println("Read and write monitor settings over DDC/CI. Codes are hex (e.g. 0x60 for")
+	fmt.Println("input source), values are decimal or 0x-prefixed hex, or a percentage of the")
+	fmt.Println("maximum the monitor reports, e.g. 'lumos vcp set 0x10 50% --scale lightness'.")
+	fmt.Println()
+	fmt.Println("Options:")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  --display <display>[,...]\tOnly use the given displays by number, ID or name (default: all)")
+	fmt.Fprintln(w, "  --scale linear|lightness|power\tHow percentage values map to the monitor's range, like")
+	fmt.Fprintln(w, "  \tlumos --scale (default: linear)")
+	w.Flush()
+}
@@ -0,0 +1,311 @@
+package ddc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capabilities holds a parsed MCCS capabilities string
+type Capabilities struct {
+	Raw         string
+	Protocol    string
+	Type        string
+	Model       string
+	MCCSVersion string
+	Commands    []byte
+	VCP         []VCPCapability
+	Fields      map[string]string // Every top-level field, unparsed
+}
+
+// VCPCapability describes a supported VCP code and, for non-continuous
+// codes, the values the monitor advertises
+type VCPCapability struct {
+	Code   byte
+	Values []byte // nil when the monitor does not restrict the value
+}
+
+var errUnbalanced = errors.New("unbalanced parentheses in capabilities string")
+
+// ParseCapabilities parses an MCCS capabilities string such as
+// "(prot(monitor)type(lcd)model(X)cmds(01 02 03)vcp(10 12 60(0F 11))mccs_ver(2.1))"
+func ParseCapabilities(s string) (*Capabilities, error) {
+	caps := &Capabilities{
+		Raw:    s,
+		Fields: make(map[string]string),
+	}
+
+	body := strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	if body == "" {
+		return nil, errors.New("empty capabilities string")
+	}
+
+	// Strip the outer parentheses. Some monitors omit the final one or
+	// append garbage after it, so both are tolerated here.
+	if body[0] == '(' {
+		end, err := matchParen(body, 0)
+		if err != nil {
+			body = body[1:]
+		} else {
+			body = body[1:end]
+		}
+	}
+
+	fields, err := splitFields(body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		caps.Fields[f.name] = f.value
+
+		switch f.name {
+		case "prot":
+			caps.Protocol = strings.TrimSpace(f.value)
+		case "type":
+			caps.Type = strings.TrimSpace(f.value)
+		case "model":
+			caps.Model = strings.TrimSpace(f.value)
+		case "mccs_ver":
+			caps.MCCSVersion = strings.TrimSpace(f.value)
+		case "cmds":
+			cmds, err := parseHexList(f.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cmds field: %v", err)
+			}
+			caps.Commands = cmds
+		case "vcp":
+			vcp, err := parseVCPList(f.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid vcp field: %v", err)
+			}
+			caps.VCP = vcp
+		}
+	}
+
+	return caps, nil
+}
+
+// Supports reports whether the monitor advertises the given VCP code
+func (c *Capabilities) Supports(code byte) bool {
+	_, ok := c.Lookup(code)
+	return ok
+}
+
+// Lookup returns the capability entry for a VCP code
+func (c *Capabilities) Lookup(code byte) (VCPCapability, bool) {
+	for _, v := range c.VCP {
+		if v.Code == code {
+			return v, true
+		}
+	}
+	return VCPCapability{}, false
+}
+
+// Allows reports whether value is acceptable for code according to the
+// advertised capabilities. Codes without a value list accept anything.
+func (c *Capabilities) Allows(code byte, value uint32) bool {
+	v, ok := c.Lookup(code)
+	if !ok {
+		return false
+	}
+	if v.Values == nil {
+		return true
+	}
+	for _, allowed := range v.Values {
+		if uint32(allowed) == value {
+			return true
+		}
+	}
+	return false
+}
+
+type capsField struct {
+	name  string
+	value string
+}
+
+// splitFields splits "a(x)b(y(z))" into name/value pairs, keeping nested
+// parentheses in the value
+func splitFields(s string) ([]capsField, error) {
+	var fields []capsField
+
+	i := 0
+	for i < len(s) {
+		if isSpace(s[i]) {
+			i++
+			continue
+		}
+		if s[i] == ')' {
+			return nil, errUnbalanced
+		}
+
+		start := i
+		for i < len(s) && s[i] != '(' && s[i] != ')' && !isSpace(s[i]) {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '(' {
+			// Bare word without a value, ignored
+			continue
+		}
+
+		end, err := matchParen(s, i)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, capsField{name: name, value: s[i+1 : end]})
+		i = end + 1
+	}
+
+	return fields, nil
+}
+
+// parseVCPList parses the content of the vcp field, e.g. "10 12 60(0F 11)"
+func parseVCPList(s string) ([]VCPCapability, error) {
+	var list []VCPCapability
+	seen := make(map[byte]int)
+	last := -1
+
+	i := 0
+	for i < len(s) {
+		if isSpace(s[i]) {
+			i++
+			continue
+		}
+
+		if s[i] == '(' {
+			// Value list for the last code
+			if last < 0 {
+				return nil, errors.New("value list without a VCP code")
+			}
+			end, err := matchParen(s, i)
+			if err != nil {
+				return nil, err
+			}
+			values, err := parseHexList(s[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			list[last].Values = append(list[last].Values, values...)
+			i = end + 1
+			continue
+		}
+
+		if s[i] == ')' {
+			return nil, errUnbalanced
+		}
+
+		start := i
+		for i < len(s) && isHex(s[i]) {
+			i++
+		}
+		if start == i {
+			return nil, fmt.Errorf("unexpected character %q", s[i])
+		}
+		codes, err := hexBytes(s[start:i])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, code := range codes {
+			// Duplicate entries are merged into the first one
+			idx, ok := seen[code]
+			if !ok {
+				idx = len(list)
+				seen[code] = idx
+				list = append(list, VCPCapability{Code: code})
+			}
+			last = idx
+		}
+	}
+
+	sort.SliceStable(list, func(a, b int) bool { return list[a].Code < list[b].Code })
+	return list, nil
+}
+
+// parseHexList parses a whitespace separated list of hex bytes. Values
+// packed without separators ("0F1011") are also accepted.
+func parseHexList(s string) ([]byte, error) {
+	list := []byte{}
+
+	i := 0
+	for i < len(s) {
+		if isSpace(s[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && isHex(s[i]) {
+			i++
+		}
+		if start == i {
+			return nil, fmt.Errorf("unexpected character %q", s[i])
+		}
+		values, err := hexBytes(s[start:i])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, values...)
+	}
+
+	return list, nil
+}
+
+// hexBytes decodes a run of hex digits two at a time. A lone digit is
+// accepted as a single value.
+func hexBytes(tok string) ([]byte, error) {
+	if len(tok) == 1 {
+		return []byte{hexDigit(tok[0])}, nil
+	}
+	if len(tok)%2 != 0 {
+		return nil, fmt.Errorf("odd-length hex token %q", tok)
+	}
+
+	out := make([]byte, 0, len(tok)/2)
+	for j := 0; j < len(tok); j += 2 {
+		out = append(out, hexDigit(tok[j])<<4|hexDigit(tok[j+1]))
+	}
+	return out, nil
+}
+
+// matchParen returns the index of the parenthesis closing the one at open
+func matchParen(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, errUnbalanced
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == 0
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigit(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,153 @@
+package ddc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Capabilities strings reported by real monitors
+var capsFixtures = []struct {
+	name     string
+	caps     string
+	model    string
+	mccs     string
+	vcpCount int
+	inputs   []byte // Values of VCP 0x60
+}{
+	{
+		name:     "Dell U2415",
+		caps:     "(prot(monitor)type(LCD)model(U2415)cmds(01 02 03 07 0C E3 F3)vcp(02 04 05 08 10 12 14(05 08 0B 0C) 16 18 1A 52 60(01 0F 11) AA(01 02) AC AE B2 B6 C6 C8 C9 D6(01 04 05) DC(00 02 03 05) DF E0 E1 E2(00 01 02 04 0E 12 14 19) F0(00 08) F1(01 02) F2 FD)mswhql(1)asset_eep(40)mccs_ver(2.1))",
+		model:    "U2415",
+		mccs:     "2.1",
+		vcpCount: 30,
+		inputs:   []byte{0x01, 0x0F, 0x11},
+	},
+	{
+		name:     "LG 24MP59G",
+		caps:     "(prot(monitor)type(LCD)model(LG FULLHD)cmds(01 02 03 0C E3 F3)vcp(02 04 05 08 10 12 14(05 06 08 0B) 16 18 1A 52 60(01 03 04) 6C 6E 70 87 AC AE B6 C0 C6 C8 C9 D6(01 04) DF 62 8D F4 F5(00 01 02) F6(00 01 02) 4D 4E 4F 15(01 06 11 13 14 28 29 32 48) F7(00 01 02 03) F8(00 01) F9 E4 E5 E6 E7 E8 E9 EA EB EF FD(00 01) FE(00 01 02) FF)mccs_ver(2.1)mswhql(1))",
+		model:    "LG FULLHD",
+		mccs:     "2.1",
+		vcpCount: 49,
+		inputs:   []byte{0x01, 0x03, 0x04},
+	},
+	{
+		// Values packed without separators and the final parenthesis missing
+		name:     "Samsung S24D300",
+		caps:     "(prot(monitor)type(LCD)model(S24D300)cmds(01 02 03 07 0C E3 F3)vcp(02 04 05 08 10 12 14(05 08 0B 0C)16 18 1A 60(0102030F10)62 AC AE B2 B6 C6 C8 C9 D6(01 04) DC(00 01 02 03 04 05) DF FD)mccs_ver(2.0)",
+		model:    "S24D300",
+		mccs:     "2.0",
+		vcpCount: 23,
+		inputs:   []byte{0x01, 0x02, 0x03, 0x0F, 0x10},
+	},
+	{
+		// Trailing NUL bytes as returned in the DDC/CI buffer
+		name:     "HP Z27n",
+		caps:     "(prot(monitor)type(lcd)model(HP Z27n)cmds(01 02 03 07 0C E3 F3)vcp(02 04 05 08 0B 0C 10 12 14(01 02 04 05 06 08 0B 0C) 16 18 1A 52 60(0F 10 11 12) 62 6C 6E 70 86(01 02 05) 87 AC AE B6 C0 C6 C8 C9 CA(01 02) CC(01 02 03 04 06 07 08 09 0A 0C 0D 0E 14 16 1E) D6(01 04 05) DC(00 01 02 03 04 05 06) DF E4 E5 FF)mswhql(1)asset_eep(40)mccs_ver(2.2))\x00\x00",
+		model:    "HP Z27n",
+		mccs:     "2.2",
+		vcpCount: 35,
+		inputs:   []byte{0x0F, 0x10, 0x11, 0x12},
+	},
+}
+
+func TestParseCapabilities(t *testing.T) {
+	for _, tt := range capsFixtures {
+		t.Run(tt.name, func(t *testing.T) {
+			caps, err := ParseCapabilities(tt.caps)
+			if err != nil {
+				t.Fatalf("ParseCapabilities: %v", err)
+			}
+			if caps.Protocol != "monitor" {
+				t.Errorf("Protocol = %q, want monitor", caps.Protocol)
+			}
+			if caps.Model != tt.model {
+				t.Errorf("Model = %q, want %q", caps.Model, tt.model)
+			}
+			if caps.MCCSVersion != tt.mccs {
+				t.Errorf("MCCSVersion = %q, want %q", caps.MCCSVersion, tt.mccs)
+			}
+			if len(caps.VCP) != tt.vcpCount {
+				t.Errorf("%d VCP codes, want %d", len(caps.VCP), tt.vcpCount)
+			}
+			if !caps.Supports(VCP_BRIGHTNESS) {
+				t.Error("brightness not supported")
+			}
+
+			inputs, ok := caps.Lookup(VCP_INPUT_SOURCE)
+			if !ok {
+				t.Fatal("input source not supported")
+			}
+			if !bytes.Equal(inputs.Values, tt.inputs) {
+				t.Errorf("input values = % X, want % X", inputs.Values, tt.inputs)
+			}
+			if caps.Allows(VCP_INPUT_SOURCE, 0xFF) {
+				t.Error("unadvertised input allowed")
+			}
+		})
+	}
+}
+
+func TestParseCapabilitiesTolerated(t *testing.T) {
+	// Garbage after the outer parenthesis and lone hex digits are accepted
+	for s, codes := range map[string]int{
+		"(prot(monitor)vcp(10 12)))": 2,
+		"(vcp(10 12))garbage":        2,
+		"(vcp(10 12 60(0F 1)))":      3,
+	} {
+		caps, err := ParseCapabilities(s)
+		if err != nil {
+			t.Errorf("ParseCapabilities(%q): %v", s, err)
+			continue
+		}
+		if len(caps.VCP) != codes {
+			t.Errorf("ParseCapabilities(%q) has %d VCP codes, want %d", s, len(caps.VCP), codes)
+		}
+	}
+}
+
+func TestParseCapabilitiesInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"\x00\x00",
+		"(prot(monitor)vcp(10 12 60(0F 11)model(X))",
+		"prot(monitor)vcp(10 12",
+		"(vcp((0F)))",
+		"(vcp(10 12 xyz))",
+		"(vcp(123))",
+	} {
+		if caps, err := ParseCapabilities(s); err == nil {
+			t.Errorf("ParseCapabilities(%q) = %+v, want an error", s, caps)
+		}
+	}
+
+	if _, err := ParseCapabilities("prot(monitor))vcp(10)"); !errors.Is(err, errUnbalanced) {
+		t.Errorf("extra closing parenthesis: got %v, want %v", err, errUnbalanced)
+	}
+}
+
+func FuzzParseCapabilities(f *testing.F) {
+	for _, tt := range capsFixtures {
+		f.Add(tt.caps)
+	}
+	f.Add("(vcp(10 12 60(0F 11)")
+	f.Add("(vcp(60((0F))))")
+	f.Add(")(")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		caps, err := ParseCapabilities(s)
+		if err != nil {
+			return
+		}
+		for i := 1; i < len(caps.VCP); i++ {
+			if caps.VCP[i-1].Code >= caps.VCP[i].Code {
+				t.Fatalf("VCP codes not sorted and unique: %02X before %02X", caps.VCP[i-1].Code, caps.VCP[i].Code)
+			}
+		}
+		for _, v := range caps.VCP {
+			if !caps.Supports(v.Code) {
+				t.Fatalf("listed code %02X not supported", v.Code)
+			}
+		}
+	})
+}
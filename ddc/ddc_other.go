@@ -0,0 +1,32 @@
+//go:build !windows
+
+package ddc
+
+import "errors"
+
+var errUnsupported = errors.New("DDC/CI is only supported on Windows")
+
+// Monitors returns every physical monitor attached to the desktop
+func Monitors() ([]*Monitor, error) {
+	return nil, errUnsupported
+}
+
+// Close releases the physical monitor handle
+func (m *Monitor) Close() error {
+	return nil
+}
+
+// GetVCP reads the current and maximum value of a VCP feature
+func (m *Monitor) GetVCP(code byte) (current, max uint32, err error) {
+	return 0, 0, errUnsupported
+}
+
+// SetVCP writes a VCP feature value
+func (m *Monitor) SetVCP(code byte, value uint32) error {
+	return errUnsupported
+}
+
+// CapabilitiesString retrieves the raw MCCS capabilities string
+func (m *Monitor) CapabilitiesString() (string, error) {
+	return "", errUnsupported
+}
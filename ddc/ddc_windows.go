@@ -0,0 +1,175 @@
+package ddc
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+	dxva2  = syscall.NewLazyDLL("dxva2.dll")
+
+	procEnumDisplayMonitors                     = user32.NewProc("EnumDisplayMonitors")
+	procGetMonitorInfo                          = user32.NewProc("GetMonitorInfoW")
+	procGetNumberOfPhysicalMonitorsFromHMONITOR = dxva2.NewProc("GetNumberOfPhysicalMonitorsFromHMONITOR")
+	procGetPhysicalMonitorsFromHMONITOR         = dxva2.NewProc("GetPhysicalMonitorsFromHMONITOR")
+	procDestroyPhysicalMonitor                  = dxva2.NewProc("DestroyPhysicalMonitor")
+	procGetVCPFeatureAndVCPFeatureReply         = dxva2.NewProc("GetVCPFeatureAndVCPFeatureReply")
+	procSetVCPFeature                           = dxva2.NewProc("SetVCPFeature")
+	procGetCapabilitiesStringLength             = dxva2.NewProc("GetCapabilitiesStringLength")
+	procCapabilitiesRequestAndCapabilitiesReply = dxva2.NewProc("CapabilitiesRequestAndCapabilitiesReply")
+)
+
+// PHYSICAL_MONITOR structure
+type physicalMonitor struct {
+	hPhysicalMonitor syscall.Handle
+	description      [128]uint16
+}
+
+// RECT structure
+type rect struct {
+	Left, Top, Right, Bottom int32
+}
+
+// MONITORINFOEXW structure
+type monitorInfoEx struct {
+	cbSize    uint32
+	rcMonitor rect
+	rcWork    rect
+	dwFlags   uint32
+	szDevice  [32]uint16
+}
+
+var (
+	enumMu       sync.Mutex
+	enumHandles  []uintptr
+	enumCallback = syscall.NewCallback(func(hmonitor, hdc, rc, lparam uintptr) uintptr {
+		enumHandles = append(enumHandles, hmonitor)
+		return 1 // Continue enumeration
+	})
+)
+
+// Monitors returns every physical monitor attached to the desktop. The
+// caller must release them with CloseAll.
+func Monitors() ([]*Monitor, error) {
+	enumMu.Lock()
+	enumHandles = nil
+	ret, _, err := procEnumDisplayMonitors.Call(0, 0, enumCallback, 0)
+	hmonitors := enumHandles
+	enumMu.Unlock()
+
+	if ret == 0 {
+		return nil, errors.New("failed to enumerate display monitors: " + err.Error())
+	}
+
+	var monitors []*Monitor
+	for _, hmonitor := range hmonitors {
+		var count uint32
+		ret, _, _ := procGetNumberOfPhysicalMonitorsFromHMONITOR.Call(hmonitor, uintptr(unsafe.Pointer(&count)))
+		if ret == 0 || count == 0 {
+			continue
+		}
+
+		physical := make([]physicalMonitor, count)
+		ret, _, _ = procGetPhysicalMonitorsFromHMONITOR.Call(
+			hmonitor,
+			uintptr(count),
+			uintptr(unsafe.Pointer(&physical[0])),
+		)
+		if ret == 0 {
+			continue
+		}
+
+		deviceName := monitorDeviceName(hmonitor)
+		for _, pm := range physical {
+			monitors = append(monitors, &Monitor{
+				Index:       len(monitors) + 1,
+				DeviceName:  deviceName,
+				Description: syscall.UTF16ToString(pm.description[:]),
+				handle:      uintptr(pm.hPhysicalMonitor),
+			})
+		}
+	}
+
+	if len(monitors) == 0 {
+		return nil, errors.New("no DDC/CI capable monitors found")
+	}
+
+	return monitors, nil
+}
+
+// monitorDeviceName returns the GDI device name of a display monitor
+func monitorDeviceName(hmonitor uintptr) string {
+	var mi monitorInfoEx
+	mi.cbSize = uint32(unsafe.Sizeof(mi))
+
+	ret, _, _ := procGetMonitorInfo.Call(hmonitor, uintptr(unsafe.Pointer(&mi)))
+	if ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(mi.szDevice[:])
+}
+
+// Close releases the physical monitor handle
+func (m *Monitor) Close() error {
+	if m.handle == 0 {
+		return nil
+	}
+	ret, _, err := procDestroyPhysicalMonitor.Call(m.handle)
+	m.handle = 0
+	if ret == 0 {
+		return errors.New("failed to destroy physical monitor: " + err.Error())
+	}
+	return nil
+}
+
+// GetVCP reads the current and maximum value of a VCP feature
+func (m *Monitor) GetVCP(code byte) (current, max uint32, err error) {
+	var codeType uint32
+	ret, _, callErr := procGetVCPFeatureAndVCPFeatureReply.Call(
+		m.handle,
+		uintptr(code),
+		uintptr(unsafe.Pointer(&codeType)),
+		uintptr(unsafe.Pointer(&current)),
+		uintptr(unsafe.Pointer(&max)),
+	)
+	if ret == 0 {
+		return 0, 0, errors.New("failed to get VCP feature: " + callErr.Error())
+	}
+	return current, max, nil
+}
+
+// SetVCP writes a VCP feature value
+func (m *Monitor) SetVCP(code byte, value uint32) error {
+	ret, _, err := procSetVCPFeature.Call(m.handle, uintptr(code), uintptr(value))
+	if ret == 0 {
+		return errors.New("failed to set VCP feature: " + err.Error())
+	}
+	return nil
+}
+
+// CapabilitiesString retrieves the raw MCCS capabilities string
+func (m *Monitor) CapabilitiesString() (string, error) {
+	var length uint32
+	ret, _, err := procGetCapabilitiesStringLength.Call(m.handle, uintptr(unsafe.Pointer(&length)))
+	if ret == 0 {
+		return "", errors.New("failed to get capabilities string length: " + err.Error())
+	}
+	if length == 0 {
+		return "", errors.New("monitor returned an empty capabilities string")
+	}
+
+	buf := make([]byte, length)
+	ret, _, err = procCapabilitiesRequestAndCapabilitiesReply.Call(
+		m.handle,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(length),
+	)
+	if ret == 0 {
+		return "", errors.New("failed to get capabilities string: " + err.Error())
+	}
+
+	return string(buf), nil
+}
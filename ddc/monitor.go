@@ -0,0 +1,25 @@
+package ddc
+
+// Monitor is a physical monitor reachable over DDC/CI
+type Monitor struct {
+	Index       int    // 1-based position in enumeration order
+	DeviceName  string // GDI device name, e.g. \\.\DISPLAY1
+	Description string
+	handle      uintptr
+}
+
+// CloseAll releases every monitor returned by Monitors
+func CloseAll(monitors []*Monitor) {
+	for _, m := range monitors {
+		m.Close()
+	}
+}
+
+// Capabilities retrieves and parses the MCCS capabilities string
+func (m *Monitor) Capabilities() (*Capabilities, error) {
+	raw, err := m.CapabilitiesString()
+	if err != nil {
+		return nil, err
+	}
+	return ParseCapabilities(raw)
+}
@@ -0,0 +1,163 @@
+package ddc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Common MCCS VCP feature codes
+const (
+	VCP_RESTORE_FACTORY_DEFAULTS = 0x04
+	VCP_BRIGHTNESS               = 0x10
+	VCP_CONTRAST                 = 0x12
+	VCP_SELECT_COLOR_PRESET      = 0x14
+	VCP_RED_GAIN                 = 0x16
+	VCP_GREEN_GAIN               = 0x18
+	VCP_BLUE_GAIN                = 0x1A
+	VCP_INPUT_SOURCE             = 0x60
+	VCP_AUDIO_SPEAKER_VOLUME     = 0x62
+	VCP_AUDIO_MUTE               = 0x8D
+	VCP_DISPLAY_MODE             = 0xDC
+	VCP_POWER_MODE               = 0xD6
+	VCP_VERSION                  = 0xDF
+)
+
+// vcpNames maps well-known VCP codes to their MCCS names
+var vcpNames = map[byte]string{
+	0x02:                         "New Control Value",
+	VCP_RESTORE_FACTORY_DEFAULTS: "Restore Factory Defaults",
+	0x05:                         "Restore Factory Brightness/Contrast Defaults",
+	0x08:                         "Restore Color Defaults",
+	0x0B:                         "Color Temperature Increment",
+	0x0C:                         "Color Temperature Request",
+	VCP_BRIGHTNESS:               "Brightness",
+	VCP_CONTRAST:                 "Contrast",
+	VCP_SELECT_COLOR_PRESET:      "Select Color Preset",
+	VCP_RED_GAIN:                 "Video Gain: Red",
+	VCP_GREEN_GAIN:               "Video Gain: Green",
+	VCP_BLUE_GAIN:                "Video Gain: Blue",
+	0x52:                         "Active Control",
+	VCP_INPUT_SOURCE:             "Input Source",
+	VCP_AUDIO_SPEAKER_VOLUME:     "Audio: Speaker Volume",
+	0x6C:                         "Video Black Level: Red",
+	0x6E:                         "Video Black Level: Green",
+	0x70:                         "Video Black Level: Blue",
+	0x87:                         "Sharpness",
+	VCP_AUDIO_MUTE:               "Audio Mute",
+	0xAC:                         "Horizontal Frequency",
+	0xAE:                         "Vertical Frequency",
+	0xB2:                         "Flat Panel Sub-Pixel Layout",
+	0xB6:                         "Display Technology Type",
+	0xC0:                         "Display Usage Time",
+	0xC6:                         "Application Enable Key",
+	0xC8:                         "Display Controller Type",
+	0xC9:                         "Display Firmware Level",
+	0xCA:                         "OSD",
+	0xCC:                         "OSD Language",
+	VCP_POWER_MODE:               "Power Mode",
+	VCP_DISPLAY_MODE:             "Display Mode",
+	VCP_VERSION:                  "VCP Version",
+}
+
+// inputSourceNames maps VCP 0x60 values to MCCS input names
+var inputSourceNames = map[byte]string{
+	0x01: "VGA-1",
+	0x02: "VGA-2",
+	0x03: "DVI-1",
+	0x04: "DVI-2",
+	0x05: "Composite-1",
+	0x06: "Composite-2",
+	0x07: "S-Video-1",
+	0x08: "S-Video-2",
+	0x09: "Tuner-1",
+	0x0A: "Tuner-2",
+	0x0B: "Tuner-3",
+	0x0C: "Component-1",
+	0x0D: "Component-2",
+	0x0E: "Component-3",
+	0x0F: "DisplayPort-1",
+	0x10: "DisplayPort-2",
+	0x11: "HDMI-1",
+	0x12: "HDMI-2",
+	0x1B: "USB-C",
+}
+
+// colorPresetNames maps VCP 0x14 values to MCCS color preset names
+var colorPresetNames = map[byte]string{
+	0x01: "sRGB",
+	0x02: "Display Native",
+	0x03: "4000 K",
+	0x04: "5000 K",
+	0x05: "6500 K",
+	0x06: "7500 K",
+	0x07: "8200 K",
+	0x08: "9300 K",
+	0x09: "10000 K",
+	0x0A: "11500 K",
+	0x0B: "User 1",
+	0x0C: "User 2",
+	0x0D: "User 3",
+}
+
+// powerModeNames maps VCP 0xD6 values to MCCS power states
+var powerModeNames = map[byte]string{
+	0x01: "On",
+	0x02: "Standby",
+	0x03: "Suspend",
+	0x04: "Off",
+	0x05: "Off (power button)",
+}
+
+// CodeName returns the MCCS name of a VCP code, or "Unknown" if it is not known
+func CodeName(code byte) string {
+	if name, ok := vcpNames[code]; ok {
+		return name
+	}
+	if code >= 0xE0 {
+		return "Manufacturer Specific"
+	}
+	return "Unknown"
+}
+
+// ValueName returns the MCCS name of a value for a non-continuous VCP code,
+// or an empty string if there is none
+func ValueName(code, value byte) string {
+	switch code {
+	case VCP_INPUT_SOURCE:
+		return inputSourceNames[value]
+	case VCP_SELECT_COLOR_PRESET:
+		return colorPresetNames[value]
+	case VCP_POWER_MODE:
+		return powerModeNames[value]
+	}
+	return ""
+}
+
+// ParseCode parses a VCP code given in hexadecimal ("60", "0x60", "60h")
+func ParseCode(s string) (byte, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	t = strings.TrimPrefix(t, "0x")
+	t = strings.TrimSuffix(t, "h")
+
+	code, err := strconv.ParseUint(t, 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid VCP code: %q (must be a hex byte like 0x60)", s)
+	}
+	return byte(code), nil
+}
+
+// ParseValue parses a VCP value given in decimal or with a 0x prefix in hexadecimal
+func ParseValue(s string) (uint32, error) {
+	t := strings.TrimSpace(s)
+	base := 10
+	if len(t) > 2 && (t[:2] == "0x" || t[:2] == "0X") {
+		t, base = t[2:], 16
+	}
+
+	value, err := strconv.ParseUint(t, base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid VCP value: %q (must be 0-65535)", s)
+	}
+	return uint32(value), nil
+}
@@ -0,0 +1,47 @@
+package ddc
+
+import "testing"
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"17", 17, true},
+		{"010", 10, true},
+		{"08", 8, true},
+		{" 65535 ", 65535, true},
+		{"0x11", 0x11, true},
+		{"0X1f", 0x1F, true},
+		{"0xFFFF", 0xFFFF, true},
+		{"65536", 0, false},
+		{"0x10000", 0, false},
+		{"0b1", 0, false},
+		{"0o17", 0, false},
+		{"0x", 0, false},
+		{"1_000", 0, false},
+		{"-1", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseValue(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseValue(%q) = %d, %v; want %d, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	for in, want := range map[string]byte{"60": 0x60, "0x60": 0x60, "60h": 0x60, "D6": 0xD6, "0x10": 0x10} {
+		if got, err := ParseCode(in); err != nil || got != want {
+			t.Errorf("ParseCode(%q) = %02X, %v; want %02X", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "100", "zz", "0x"} {
+		if _, err := ParseCode(in); err == nil {
+			t.Errorf("ParseCode(%q) succeeded, want an error", in)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jipaix/lumos/edid"
+)
+
+// Display is a connected display and its identity
+type Display struct {
+	Number     int    // 1-based number in listing order, as used by --display
+	ID         string // Stable ID from the EDID, e.g. "DEL-40B4-1A2B3C4D"
+	Name       string // Friendly name, e.g. "DELL U2720Q"
+	DeviceName string // GDI device name on Windows (\\.\DISPLAY1), DRM connector on Linux (DP-1)
+	DevicePath string // Monitor device interface path on Windows, sysfs path on Linux
+	EDID       []byte // Raw EDID, nil when it couldn't be read
+	Internal   bool   // Built-in panel
+}
+
+// List returns the connected displays with stable IDs, numbered in the
+// order of their device names
+func List() ([]Display, error) {
+	displays, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	number(displays)
+	return displays, nil
+}
+
+// number orders displays by device name and numbers them. Identical
+// monitors without serial numbers get the same ID, so the duplicates are
+// suffixed with "#2", "#3"... in the order of their device paths, which
+// follow the port a monitor is plugged into rather than the order Windows
+// happened to assign \\.\DISPLAYn names in.
+func number(displays []Display) {
+	sort.SliceStable(displays, func(i, j int) bool {
+		return lessDeviceName(displays[i].DeviceName, displays[j].DeviceName)
+	})
+
+	groups := make(map[string][]int)
+	for i := range displays {
+		displays[i].Number = i + 1
+		groups[displays[i].ID] = append(groups[displays[i].ID], i)
+	}
+
+	for id, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(a, b int) bool {
+			return strings.ToLower(displays[group[a]].DevicePath) < strings.ToLower(displays[group[b]].DevicePath)
+		})
+		for n, i := range group[1:] {
+			displays[i].ID = id + "#" + strconv.Itoa(n+2)
+		}
+	}
+}
+
+// StableID derives a display ID from the manufacturer, product code and
+// serial number in the EDID. The ID survives reboots, hotplug and changes
+// of port, unlike \\.\DISPLAYn names.
+func StableID(e *edid.EDID) string {
+	id := fmt.Sprintf("%s-%04X", e.Manufacturer, e.ProductCode)
+
+	serial := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
+			return r
+		}
+		return -1
+	}, e.SerialString)
+	if serial == "" && e.SerialNumber != 0 {
+		serial = fmt.Sprintf("%08X", e.SerialNumber)
+	}
+	if serial != "" {
+		id += "-" + serial
+	}
+
+	return id
+}
+
+// IDForDevicePath returns the stable ID of the monitor at a device path as
+// found in Display.DevicePath, or "" when its EDID can't be read. Unlike
+// List it also works for monitors that are connected but not active.
+func IDForDevicePath(devicePath string) string {
+	e, err := edid.Parse(readEDID(devicePath))
+	if err != nil {
+		return ""
+	}
+	return StableID(e)
+}
+
+// identify fills ID and Name from the EDID, falling back to the given
+// manufacturer and product code when the EDID is missing or invalid
+func (d *Display) identify(manufacturer string, productCode uint16) {
+	if e, err := edid.Parse(d.EDID); err == nil {
+		d.ID = StableID(e)
+		if d.Name == "" {
+			d.Name = e.Name
+		}
+		return
+	}
+	if manufacturer == "" {
+		d.ID = d.DeviceName
+		return
+	}
+	d.ID = fmt.Sprintf("%s-%04X", manufacturer, productCode)
+}
+
+// FindByDeviceName returns the display with the given device name
+func FindByDeviceName(displays []Display, deviceName string) (Display, bool) {
+	for _, d := range displays {
+		if strings.EqualFold(d.DeviceName, deviceName) {
+			return d, true
+		}
+	}
+	return Display{}, false
+}
+
+// Label returns "Display 1 (DELL U2720Q)" for messages
+func (d Display) Label() string {
+	if d.Name == "" {
+		return fmt.Sprintf("Display %d", d.Number)
+	}
+	return fmt.Sprintf("Display %d (%s)", d.Number, d.Name)
+}
+
+// lessDeviceName orders names like DISPLAY2 before DISPLAY10
+func lessDeviceName(a, b string) bool {
+	pa, na := splitTrailingNumber(a)
+	pb, nb := splitTrailingNumber(b)
+	if pa != pb {
+		return pa < pb
+	}
+	return na < nb
+}
+
+func splitTrailingNumber(s string) (string, int) {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	n, _ := strconv.Atoi(s[i:])
+	return s[:i], n
+}
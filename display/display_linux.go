@@ -0,0 +1,58 @@
+package display
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// list returns the connected DRM connectors that report an EDID
+func list() ([]Display, error) {
+	connectors, err := filepath.Glob("/sys/class/drm/card*-*")
+	if err != nil {
+		return nil, err
+	}
+
+	var displays []Display
+	for _, dir := range connectors {
+		status, err := os.ReadFile(filepath.Join(dir, "status"))
+		if err != nil || strings.TrimSpace(string(status)) != "connected" {
+			continue
+		}
+
+		data := readEDID(dir)
+		if len(data) == 0 {
+			continue
+		}
+
+		// card0-eDP-1 -> eDP-1
+		connector := filepath.Base(dir)
+		if i := strings.Index(connector, "-"); i >= 0 {
+			connector = connector[i+1:]
+		}
+
+		d := Display{
+			DeviceName: connector,
+			DevicePath: dir,
+			EDID:       data,
+			Internal:   strings.HasPrefix(connector, "eDP") || strings.HasPrefix(connector, "LVDS") || strings.HasPrefix(connector, "DSI"),
+		}
+		d.identify("", 0)
+		if d.Name == "" {
+			d.Name = connector
+		}
+
+		displays = append(displays, d)
+	}
+
+	return displays, nil
+}
+
+// readEDID reads the EDID of a DRM connector from its sysfs directory
+func readEDID(devicePath string) []byte {
+	data, err := os.ReadFile(filepath.Join(devicePath, "edid"))
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,15 @@
+//go:build !windows && !linux
+
+package display
+
+import "errors"
+
+// list is not available on this platform
+func list() ([]Display, error) {
+	return nil, errors.New("display identification is not supported on this platform")
+}
+
+// readEDID is not available on this platform
+func readEDID(devicePath string) []byte {
+	return nil
+}
@@ -0,0 +1,49 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/jipaix/lumos/edid"
+)
+
+func TestStableID(t *testing.T) {
+	tests := []struct {
+		e    edid.EDID
+		want string
+	}{
+		{edid.EDID{Manufacturer: "DEL", ProductCode: 0x40B4}, "DEL-40B4"},
+		{edid.EDID{Manufacturer: "DEL", ProductCode: 0x40B4, SerialNumber: 0x1A2B3C4D}, "DEL-40B4-1A2B3C4D"},
+		{edid.EDID{Manufacturer: "GSM", ProductCode: 0x5B7F, SerialString: "104NTAB 3F2.1", SerialNumber: 1}, "GSM-5B7F-104NTAB3F21"},
+	}
+	for _, tt := range tests {
+		if got := StableID(&tt.e); got != tt.want {
+			t.Errorf("StableID(%+v) = %q, want %q", tt.e, got, tt.want)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	// Two identical monitors without serial numbers, listed with their
+	// device names swapped: the duplicate suffix follows the device path
+	displays := []Display{
+		{ID: "DEL-40B4", DeviceName: `\\.\DISPLAY10`, DevicePath: `\\?\DISPLAY#DEL40B4#5&1a2b3c&0&UID4353`},
+		{ID: "GSM-5B7F", DeviceName: `\\.\DISPLAY1`, DevicePath: `\\?\DISPLAY#GSM5B7F#5&2b3c4d&0&UID4352`},
+		{ID: "DEL-40B4", DeviceName: `\\.\DISPLAY2`, DevicePath: `\\?\DISPLAY#DEL40B4#5&1a2b3c&0&UID4354`},
+	}
+	number(displays)
+
+	want := []struct {
+		name string
+		id   string
+	}{
+		{`\\.\DISPLAY1`, "GSM-5B7F"},
+		{`\\.\DISPLAY2`, "DEL-40B4#2"},
+		{`\\.\DISPLAY10`, "DEL-40B4"},
+	}
+	for i, w := range want {
+		d := displays[i]
+		if d.Number != i+1 || d.DeviceName != w.name || d.ID != w.id {
+			t.Errorf("display %d = %d %s %s, want %d %s %s", i, d.Number, d.DeviceName, d.ID, i+1, w.name, w.id)
+		}
+	}
+}
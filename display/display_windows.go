@@ -0,0 +1,69 @@
+package display
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+
+	dc "github.com/jipaix/lumos/displayconfig"
+	"github.com/jipaix/lumos/edid"
+)
+
+// list returns the displays on active paths, identified through
+// DISPLAYCONFIG_DEVICE_INFO_GET_TARGET_NAME and the EDID in the registry
+func list() ([]Display, error) {
+	paths, _, _, err := dc.Query(dc.QDC_ONLY_ACTIVE_PATHS)
+	if err != nil {
+		return nil, err
+	}
+
+	var displays []Display
+	for _, p := range paths {
+		deviceName, err := dc.SourceName(p.SourceInfo.AdapterId, p.SourceInfo.Id)
+		if err != nil {
+			return nil, err
+		}
+		target, err := dc.TargetName(p.TargetInfo.AdapterId, p.TargetInfo.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		d := Display{
+			Name:       target.FriendlyName(),
+			DeviceName: deviceName,
+			DevicePath: target.DevicePath(),
+			Internal:   p.TargetInfo.OutputTechnology.IsInternal(),
+		}
+		d.EDID = readEDID(d.DevicePath)
+
+		// The ID from the target name is the EDID word read little-endian
+		id := target.EdidManufactureId
+		d.identify(edid.DecodeManufacturer(id>>8|id<<8), target.EdidProductCodeId)
+
+		displays = append(displays, d)
+	}
+
+	return displays, nil
+}
+
+// readEDID reads the EDID Windows caches for a monitor device path such as
+// \\?\DISPLAY#DEL40B4#5&2d2a3a5e&0&UID4353#{e6f07b5f-ee97-4a90-b076-33f57bf4eaa7}
+func readEDID(devicePath string) []byte {
+	parts := strings.Split(strings.TrimPrefix(devicePath, `\\?\`), "#")
+	if len(parts) < 3 {
+		return nil
+	}
+
+	keyPath := `SYSTEM\CurrentControlSet\Enum\` + parts[0] + `\` + parts[1] + `\` + parts[2] + `\Device Parameters`
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return nil
+	}
+	defer key.Close()
+
+	data, _, err := key.GetBinaryValue("EDID")
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,124 @@
+package display
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector picks displays by number, stable ID, friendly name or device
+// name, as given to --display
+type Selector struct {
+	terms []string // Empty means all displays
+}
+
+// All selects every display
+var All = Selector{}
+
+// ParseSelector parses a display selector: "all" (or empty), or a comma
+// separated list of display numbers ("1,3"), stable IDs ("DEL-40B4-1A2B3C4D"),
+// friendly names ("DELL U2720Q") or device names (\\.\DISPLAY1)
+func ParseSelector(s string) (Selector, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "all") {
+		return All, nil
+	}
+
+	var sel Selector
+	for _, part := range strings.Split(s, ",") {
+		term := strings.TrimSpace(part)
+		if n, err := strconv.Atoi(term); term == "" || (err == nil && n < 1) {
+			return Selector{}, fmt.Errorf("invalid display: %q (must be 'all', a display number, ID or name)", part)
+		}
+		sel.terms = append(sel.terms, term)
+	}
+	return sel, nil
+}
+
+// Only selects exactly the given displays by their stable ID. It must be
+// given at least one display, an empty selector selects all displays.
+func Only(displays ...Display) Selector {
+	sel := Selector{terms: make([]string, 0, len(displays))}
+	for _, d := range displays {
+		sel.terms = append(sel.terms, d.ID)
+	}
+	return sel
+}
+
+// IsAll reports whether the selector matches every display
+func (s Selector) IsAll() bool {
+	return len(s.terms) == 0
+}
+
+// Matches reports whether the display is selected
+func (s Selector) Matches(d Display) bool {
+	if s.IsAll() {
+		return true
+	}
+	for _, term := range s.terms {
+		if matchTerm(term, d) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTerm(term string, d Display) bool {
+	if n, err := strconv.Atoi(term); err == nil {
+		return n == d.Number
+	}
+	return strings.EqualFold(term, d.ID) ||
+		strings.EqualFold(term, d.Name) ||
+		strings.EqualFold(term, d.DeviceName)
+}
+
+// Select returns the selected displays. Every term must match a display.
+func (s Selector) Select(displays []Display) ([]Display, error) {
+	for _, term := range s.terms {
+		found := false
+		for _, d := range displays {
+			if matchTerm(term, d) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("display %q not found (%d displays connected, see 'lumos list')", term, len(displays))
+		}
+	}
+
+	var selected []Display
+	for _, d := range displays {
+		if s.Matches(d) {
+			selected = append(selected, d)
+		}
+	}
+	return selected, nil
+}
+
+// Resolve lists the connected displays and returns the selected ones
+func (s Selector) Resolve() ([]Display, error) {
+	displays, err := List()
+	if err != nil {
+		return nil, err
+	}
+	return s.Select(displays)
+}
+
+// String returns the selector in the form accepted by ParseSelector
+func (s Selector) String() string {
+	if s.IsAll() {
+		return "all"
+	}
+	return strings.Join(s.terms, ",")
+}
+
+// Set implements flag.Value so a Selector can be bound with flag.Var
+func (s *Selector) Set(value string) error {
+	sel, err := ParseSelector(value)
+	if err != nil {
+		return err
+	}
+	*s = sel
+	return nil
+}
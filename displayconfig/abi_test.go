@@ -0,0 +1,64 @@
+package displayconfig
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// TestABI checks the structure sizes and member offsets against the Windows
+// SDK. The SDK layouts are identical on amd64, arm64 and 386 since every
+// member is at most 4-byte aligned, except UINT64 pixelRate whose offsets
+// are already multiples of 8, so run it on each:
+//
+//	GOARCH=386 go test ./displayconfig
+func TestABI(t *testing.T) {
+	tests := []struct {
+		name string // Structure for its size, or structure.member for an offset
+		got  uintptr
+		want uintptr
+	}{
+		{"LUID", unsafe.Sizeof(LUID{}), 8},
+		{"DISPLAYCONFIG_RATIONAL", unsafe.Sizeof(DISPLAYCONFIG_RATIONAL{}), 8},
+		{"DISPLAYCONFIG_2DREGION", unsafe.Sizeof(DISPLAYCONFIG_2DREGION{}), 8},
+		{"DISPLAYCONFIG_PATH_SOURCE_INFO", unsafe.Sizeof(DISPLAYCONFIG_PATH_SOURCE_INFO{}), 20},
+		{"DISPLAYCONFIG_PATH_TARGET_INFO", unsafe.Sizeof(DISPLAYCONFIG_PATH_TARGET_INFO{}), 48},
+		{"DISPLAYCONFIG_PATH_TARGET_INFO.RefreshRate", unsafe.Offsetof(DISPLAYCONFIG_PATH_TARGET_INFO{}.RefreshRate), 28},
+		{"DISPLAYCONFIG_PATH_TARGET_INFO.TargetAvailable", unsafe.Offsetof(DISPLAYCONFIG_PATH_TARGET_INFO{}.TargetAvailable), 40},
+		{"DISPLAYCONFIG_PATH_INFO", unsafe.Sizeof(DISPLAYCONFIG_PATH_INFO{}), 72},
+		{"DISPLAYCONFIG_PATH_INFO.TargetInfo", unsafe.Offsetof(DISPLAYCONFIG_PATH_INFO{}.TargetInfo), 20},
+		{"DISPLAYCONFIG_PATH_INFO.Flags", unsafe.Offsetof(DISPLAYCONFIG_PATH_INFO{}.Flags), 68},
+		{"DISPLAYCONFIG_VIDEO_SIGNAL_INFO", unsafe.Sizeof(DISPLAYCONFIG_VIDEO_SIGNAL_INFO{}), 48},
+		{"DISPLAYCONFIG_VIDEO_SIGNAL_INFO.VideoStandard", unsafe.Offsetof(DISPLAYCONFIG_VIDEO_SIGNAL_INFO{}.VideoStandard), 40},
+		{"DISPLAYCONFIG_VIDEO_SIGNAL_INFO.ScanLineOrdering", unsafe.Offsetof(DISPLAYCONFIG_VIDEO_SIGNAL_INFO{}.ScanLineOrdering), 44},
+		{"DISPLAYCONFIG_TARGET_MODE", unsafe.Sizeof(DISPLAYCONFIG_TARGET_MODE{}), 48},
+		{"DISPLAYCONFIG_SOURCE_MODE", unsafe.Sizeof(DISPLAYCONFIG_SOURCE_MODE{}), 20},
+		{"DISPLAYCONFIG_DESKTOP_IMAGE_INFO", unsafe.Sizeof(DISPLAYCONFIG_DESKTOP_IMAGE_INFO{}), 40},
+		{"DISPLAYCONFIG_MODE_INFO", unsafe.Sizeof(DISPLAYCONFIG_MODE_INFO{}), 64},
+		{"DISPLAYCONFIG_MODE_INFO.union", unsafe.Offsetof(DISPLAYCONFIG_MODE_INFO{}.union), 16},
+		{"DISPLAYCONFIG_DEVICE_INFO_HEADER", unsafe.Sizeof(DISPLAYCONFIG_DEVICE_INFO_HEADER{}), 20},
+		{"DISPLAYCONFIG_SOURCE_DEVICE_NAME", unsafe.Sizeof(DISPLAYCONFIG_SOURCE_DEVICE_NAME{}), 84},
+		{"DISPLAYCONFIG_TARGET_DEVICE_NAME", unsafe.Sizeof(DISPLAYCONFIG_TARGET_DEVICE_NAME{}), 420},
+		{"DISPLAYCONFIG_TARGET_DEVICE_NAME.MonitorFriendlyDeviceName", unsafe.Offsetof(DISPLAYCONFIG_TARGET_DEVICE_NAME{}.MonitorFriendlyDeviceName), 36},
+		{"DISPLAYCONFIG_TARGET_DEVICE_NAME.MonitorDevicePath", unsafe.Offsetof(DISPLAYCONFIG_TARGET_DEVICE_NAME{}.MonitorDevicePath), 164},
+		{"DISPLAYCONFIG_TARGET_PREFERRED_MODE", unsafe.Sizeof(DISPLAYCONFIG_TARGET_PREFERRED_MODE{}), 80},
+		{"DISPLAYCONFIG_TARGET_PREFERRED_MODE.TargetMode", unsafe.Offsetof(DISPLAYCONFIG_TARGET_PREFERRED_MODE{}.TargetMode), 32},
+		{"DISPLAYCONFIG_ADAPTER_NAME", unsafe.Sizeof(DISPLAYCONFIG_ADAPTER_NAME{}), 276},
+		{"DISPLAYCONFIG_TARGET_BASE_TYPE", unsafe.Sizeof(DISPLAYCONFIG_TARGET_BASE_TYPE{}), 24},
+		{"DISPLAYCONFIG_SET_TARGET_PERSISTENCE", unsafe.Sizeof(DISPLAYCONFIG_SET_TARGET_PERSISTENCE{}), 24},
+		{"DISPLAYCONFIG_SUPPORT_VIRTUAL_RESOLUTION", unsafe.Sizeof(DISPLAYCONFIG_SUPPORT_VIRTUAL_RESOLUTION{}), 24},
+		{"DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO", unsafe.Sizeof(DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO{}), 32},
+		{"DISPLAYCONFIG_SET_ADVANCED_COLOR_STATE", unsafe.Sizeof(DISPLAYCONFIG_SET_ADVANCED_COLOR_STATE{}), 24},
+		{"DISPLAYCONFIG_SDR_WHITE_LEVEL", unsafe.Sizeof(DISPLAYCONFIG_SDR_WHITE_LEVEL{}), 24},
+		{"DISPLAYCONFIG_SET_SDR_WHITE_LEVEL", unsafe.Sizeof(DISPLAYCONFIG_SET_SDR_WHITE_LEVEL{}), 28},
+		{"DISPLAYCONFIG_GET_MONITOR_SPECIALIZATION", unsafe.Sizeof(DISPLAYCONFIG_GET_MONITOR_SPECIALIZATION{}), 24},
+		{"DISPLAYCONFIG_SET_MONITOR_SPECIALIZATION", unsafe.Sizeof(DISPLAYCONFIG_SET_MONITOR_SPECIALIZATION{}), 312},
+		{"DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2", unsafe.Sizeof(DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2{}), 36},
+		{"DISPLAYCONFIG_SET_HDR_STATE", unsafe.Sizeof(DISPLAYCONFIG_SET_HDR_STATE{}), 24},
+		{"DISPLAYCONFIG_SET_WCG_STATE", unsafe.Sizeof(DISPLAYCONFIG_SET_WCG_STATE{}), 24},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
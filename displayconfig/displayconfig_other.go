@@ -0,0 +1,37 @@
+//go:build !windows
+
+package displayconfig
+
+import "errors"
+
+var errUnsupported = errors.New("the display configuration API is only available on Windows")
+
+// Query returns the display paths and modes selected by flags
+func Query(flags uint32) ([]DISPLAYCONFIG_PATH_INFO, []DISPLAYCONFIG_MODE_INFO, uint32, error) {
+	return nil, nil, 0, errUnsupported
+}
+
+// Set calls SetDisplayConfig with the supplied paths and modes
+func Set(paths []DISPLAYCONFIG_PATH_INFO, modes []DISPLAYCONFIG_MODE_INFO, flags uint32) error {
+	return errUnsupported
+}
+
+// GetDeviceInfo calls DisplayConfigGetDeviceInfo
+func GetDeviceInfo(header *DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	return errUnsupported
+}
+
+// SetDeviceInfo calls DisplayConfigSetDeviceInfo
+func SetDeviceInfo(header *DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	return errUnsupported
+}
+
+// SourceName returns the GDI device name (\\.\DISPLAYn) of a path source
+func SourceName(adapterId LUID, id uint32) (string, error) {
+	return "", errUnsupported
+}
+
+// TargetName returns the monitor name and identity of a path target
+func TargetName(adapterId LUID, id uint32) (*DISPLAYCONFIG_TARGET_DEVICE_NAME, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,134 @@
+package displayconfig
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const ERROR_INSUFFICIENT_BUFFER = 122
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+
+	procGetDisplayConfigBufferSizes = user32.NewProc("GetDisplayConfigBufferSizes")
+	procQueryDisplayConfig          = user32.NewProc("QueryDisplayConfig")
+	procSetDisplayConfig            = user32.NewProc("SetDisplayConfig")
+	procDisplayConfigGetDeviceInfo  = user32.NewProc("DisplayConfigGetDeviceInfo")
+	procDisplayConfigSetDeviceInfo  = user32.NewProc("DisplayConfigSetDeviceInfo")
+)
+
+// Query returns the display paths and modes selected by flags. The
+// topology ID is only filled in for QDC_DATABASE_CURRENT.
+func Query(flags uint32) ([]DISPLAYCONFIG_PATH_INFO, []DISPLAYCONFIG_MODE_INFO, uint32, error) {
+	for {
+		var pathCount, modeCount uint32
+		ret, _, _ := procGetDisplayConfigBufferSizes.Call(
+			uintptr(flags),
+			uintptr(unsafe.Pointer(&pathCount)),
+			uintptr(unsafe.Pointer(&modeCount)),
+		)
+		if ret != 0 {
+			return nil, nil, 0, fmt.Errorf("failed to get display config buffer sizes: %v", syscall.Errno(ret))
+		}
+
+		// Keep at least one element so the buffer pointers are valid
+		paths := make([]DISPLAYCONFIG_PATH_INFO, max(pathCount, 1))
+		modes := make([]DISPLAYCONFIG_MODE_INFO, max(modeCount, 1))
+
+		var topology uint32
+		var topologyPtr uintptr
+		if flags&QDC_DATABASE_CURRENT != 0 {
+			topologyPtr = uintptr(unsafe.Pointer(&topology))
+		}
+
+		ret, _, _ = procQueryDisplayConfig.Call(
+			uintptr(flags),
+			uintptr(unsafe.Pointer(&pathCount)),
+			uintptr(unsafe.Pointer(&paths[0])),
+			uintptr(unsafe.Pointer(&modeCount)),
+			uintptr(unsafe.Pointer(&modes[0])),
+			topologyPtr,
+		)
+		if ret == ERROR_INSUFFICIENT_BUFFER {
+			// The configuration changed between the two calls
+			continue
+		}
+		if ret != 0 {
+			return nil, nil, 0, fmt.Errorf("failed to query display config: %v", syscall.Errno(ret))
+		}
+
+		return paths[:pathCount], modes[:modeCount], topology, nil
+	}
+}
+
+// Set calls SetDisplayConfig with the supplied paths and modes, which may
+// both be empty for topology-only flags
+func Set(paths []DISPLAYCONFIG_PATH_INFO, modes []DISPLAYCONFIG_MODE_INFO, flags uint32) error {
+	var pathPtr, modePtr uintptr
+	if len(paths) > 0 {
+		pathPtr = uintptr(unsafe.Pointer(&paths[0]))
+	}
+	if len(modes) > 0 {
+		modePtr = uintptr(unsafe.Pointer(&modes[0]))
+	}
+
+	ret, _, _ := procSetDisplayConfig.Call(
+		uintptr(len(paths)),
+		pathPtr,
+		uintptr(len(modes)),
+		modePtr,
+		uintptr(flags),
+	)
+	if ret != 0 {
+		return fmt.Errorf("failed to set display config: %w", syscall.Errno(ret))
+	}
+	return nil
+}
+
+// GetDeviceInfo calls DisplayConfigGetDeviceInfo. The header must be the
+// first field of a request structure with Type, Size, AdapterId and Id set.
+func GetDeviceInfo(header *DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	ret, _, _ := procDisplayConfigGetDeviceInfo.Call(uintptr(unsafe.Pointer(header)))
+	if ret != 0 {
+		return fmt.Errorf("failed to get display device info (type %d): %w", header.Type, syscall.Errno(ret))
+	}
+	return nil
+}
+
+// SetDeviceInfo calls DisplayConfigSetDeviceInfo with a request like GetDeviceInfo
+func SetDeviceInfo(header *DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	ret, _, _ := procDisplayConfigSetDeviceInfo.Call(uintptr(unsafe.Pointer(header)))
+	if ret != 0 {
+		return fmt.Errorf("failed to set display device info (type %d): %w", header.Type, syscall.Errno(ret))
+	}
+	return nil
+}
+
+// SourceName returns the GDI device name (\\.\DISPLAYn) of a path source
+func SourceName(adapterId LUID, id uint32) (string, error) {
+	var name DISPLAYCONFIG_SOURCE_DEVICE_NAME
+	name.Header.Type = DISPLAYCONFIG_DEVICE_INFO_GET_SOURCE_NAME
+	name.Header.Size = uint32(unsafe.Sizeof(name))
+	name.Header.AdapterId = adapterId
+	name.Header.Id = id
+
+	if err := GetDeviceInfo(&name.Header); err != nil {
+		return "", err
+	}
+	return name.GdiDeviceName(), nil
+}
+
+// TargetName returns the monitor name and identity of a path target
+func TargetName(adapterId LUID, id uint32) (*DISPLAYCONFIG_TARGET_DEVICE_NAME, error) {
+	name := &DISPLAYCONFIG_TARGET_DEVICE_NAME{}
+	name.Header.Type = DISPLAYCONFIG_DEVICE_INFO_GET_TARGET_NAME
+	name.Header.Size = uint32(unsafe.Sizeof(*name))
+	name.Header.AdapterId = adapterId
+	name.Header.Id = id
+
+	if err := GetDeviceInfo(&name.Header); err != nil {
+		return nil, err
+	}
+	return name, nil
+}
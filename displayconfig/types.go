@@ -0,0 +1,425 @@
+// Package displayconfig binds the Windows Display Configuration API
+// (QueryDisplayConfig, SetDisplayConfig and DisplayConfigGetDeviceInfo).
+// The structure layouts match the Windows SDK and are plain Go, so
+// configurations can be inspected and serialized on any platform.
+package displayconfig
+
+import (
+	"unicode/utf16"
+	"unsafe"
+)
+
+// QueryDisplayConfig flags
+const (
+	QDC_ALL_PATHS         = 0x00000001
+	QDC_ONLY_ACTIVE_PATHS = 0x00000002
+	QDC_DATABASE_CURRENT  = 0x00000004
+)
+
+// SetDisplayConfig flags
+const (
+	SDC_TOPOLOGY_INTERNAL           = 0x00000001
+	SDC_TOPOLOGY_CLONE              = 0x00000002
+	SDC_TOPOLOGY_EXTEND             = 0x00000004
+	SDC_TOPOLOGY_EXTERNAL           = 0x00000008
+	SDC_TOPOLOGY_SUPPLIED           = 0x00000010
+	SDC_USE_SUPPLIED_DISPLAY_CONFIG = 0x00000020
+	SDC_VALIDATE                    = 0x00000040
+	SDC_APPLY                       = 0x00000080
+	SDC_NO_OPTIMIZATION             = 0x00000100
+	SDC_SAVE_TO_DATABASE            = 0x00000200
+	SDC_ALLOW_CHANGES               = 0x00000400
+)
+
+// DISPLAYCONFIG_TOPOLOGY_ID values
+const (
+	DISPLAYCONFIG_TOPOLOGY_INTERNAL = 0x00000001
+	DISPLAYCONFIG_TOPOLOGY_CLONE    = 0x00000002
+	DISPLAYCONFIG_TOPOLOGY_EXTEND   = 0x00000004
+	DISPLAYCONFIG_TOPOLOGY_EXTERNAL = 0x00000008
+)
+
+// Win32 errors returned for device info requests a build or driver doesn't handle
+const (
+	ERROR_NOT_SUPPORTED     = 50
+	ERROR_INVALID_PARAMETER = 87
+)
+
+// DISPLAYCONFIG_DEVICE_INFO_TYPE values
+const (
+	DISPLAYCONFIG_DEVICE_INFO_GET_SOURCE_NAME                = 1
+	DISPLAYCONFIG_DEVICE_INFO_GET_TARGET_NAME                = 2
+	DISPLAYCONFIG_DEVICE_INFO_GET_TARGET_PREFERRED_MODE      = 3
+	DISPLAYCONFIG_DEVICE_INFO_GET_ADAPTER_NAME               = 4
+	DISPLAYCONFIG_DEVICE_INFO_SET_TARGET_PERSISTENCE         = 5
+	DISPLAYCONFIG_DEVICE_INFO_GET_TARGET_BASE_TYPE           = 6
+	DISPLAYCONFIG_DEVICE_INFO_GET_SUPPORT_VIRTUAL_RESOLUTION = 7
+	DISPLAYCONFIG_DEVICE_INFO_SET_SUPPORT_VIRTUAL_RESOLUTION = 8
+	DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO        = 9
+	DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE       = 10
+	DISPLAYCONFIG_DEVICE_INFO_GET_SDR_WHITE_LEVEL            = 11
+	DISPLAYCONFIG_DEVICE_INFO_GET_MONITOR_SPECIALIZATION     = 12
+	DISPLAYCONFIG_DEVICE_INFO_SET_MONITOR_SPECIALIZATION     = 13
+	DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO_2      = 15 // Windows 11 24H2
+	DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE                  = 16 // Windows 11 24H2
+	DISPLAYCONFIG_DEVICE_INFO_SET_WCG_STATE                  = 17 // Windows 11 24H2
+
+	// Undocumented, used by the Settings app for the SDR content brightness slider
+	DISPLAYCONFIG_DEVICE_INFO_SET_SDR_WHITE_LEVEL = 0xFFFFFFEE
+)
+
+// DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY values
+const (
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_OTHER                  DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 0xFFFFFFFF
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_HD15                   DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 0
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_SVIDEO                 DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 1
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_COMPOSITE_VIDEO        DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 2
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_COMPONENT_VIDEO        DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 3
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_DVI                    DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 4
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_HDMI                   DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 5
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_LVDS                   DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 6
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_D_JPN                  DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 8
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_SDI                    DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 9
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_DISPLAYPORT_EXTERNAL   DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 10
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_DISPLAYPORT_EMBEDDED   DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 11
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_UDI_EXTERNAL           DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 12
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_UDI_EMBEDDED           DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 13
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_SDTVDONGLE             DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 14
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_MIRACAST               DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 15
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_INDIRECT_WIRED         DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 16
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_INDIRECT_VIRTUAL       DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 17
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_DISPLAYPORT_USB_TUNNEL DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 18
+	DISPLAYCONFIG_OUTPUT_TECHNOLOGY_INTERNAL               DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY = 0x80000000
+)
+
+// DISPLAYCONFIG_ADVANCED_COLOR_MODE values
+const (
+	DISPLAYCONFIG_ADVANCED_COLOR_MODE_SDR = 0
+	DISPLAYCONFIG_ADVANCED_COLOR_MODE_WCG = 1
+	DISPLAYCONFIG_ADVANCED_COLOR_MODE_HDR = 2
+)
+
+// DISPLAYCONFIG_MODE_INFO_TYPE values
+const (
+	DISPLAYCONFIG_MODE_INFO_TYPE_SOURCE        = 1
+	DISPLAYCONFIG_MODE_INFO_TYPE_TARGET        = 2
+	DISPLAYCONFIG_MODE_INFO_TYPE_DESKTOP_IMAGE = 3
+)
+
+const (
+	DISPLAYCONFIG_PATH_ACTIVE           = 0x00000001
+	DISPLAYCONFIG_PATH_MODE_IDX_INVALID = 0xFFFFFFFF
+)
+
+type DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY uint32
+
+// IsInternal reports whether the output is a built-in panel
+func (t DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY) IsInternal() bool {
+	switch t {
+	case DISPLAYCONFIG_OUTPUT_TECHNOLOGY_INTERNAL,
+		DISPLAYCONFIG_OUTPUT_TECHNOLOGY_LVDS,
+		DISPLAYCONFIG_OUTPUT_TECHNOLOGY_DISPLAYPORT_EMBEDDED,
+		DISPLAYCONFIG_OUTPUT_TECHNOLOGY_UDI_EMBEDDED:
+		return true
+	}
+	return false
+}
+
+type LUID struct {
+	LowPart  uint32
+	HighPart int32
+}
+
+type POINTL struct {
+	X int32
+	Y int32
+}
+
+type RECTL struct {
+	Left   int32
+	Top    int32
+	Right  int32
+	Bottom int32
+}
+
+type DISPLAYCONFIG_RATIONAL struct {
+	Numerator   uint32
+	Denominator uint32
+}
+
+type DISPLAYCONFIG_2DREGION struct {
+	Cx uint32
+	Cy uint32
+}
+
+type DISPLAYCONFIG_PATH_SOURCE_INFO struct {
+	AdapterId   LUID
+	Id          uint32
+	ModeInfoIdx uint32
+	StatusFlags uint32
+}
+
+type DISPLAYCONFIG_PATH_TARGET_INFO struct {
+	AdapterId        LUID
+	Id               uint32
+	ModeInfoIdx      uint32
+	OutputTechnology DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY
+	Rotation         uint32
+	Scaling          uint32
+	RefreshRate      DISPLAYCONFIG_RATIONAL
+	ScanLineOrdering uint32
+	TargetAvailable  uint32 // BOOL in Windows is 4 bytes
+	StatusFlags      uint32
+}
+
+type DISPLAYCONFIG_PATH_INFO struct {
+	SourceInfo DISPLAYCONFIG_PATH_SOURCE_INFO
+	TargetInfo DISPLAYCONFIG_PATH_TARGET_INFO
+	Flags      uint32
+}
+
+type DISPLAYCONFIG_VIDEO_SIGNAL_INFO struct {
+	PixelRate        uint64
+	HSyncFreq        DISPLAYCONFIG_RATIONAL
+	VSyncFreq        DISPLAYCONFIG_RATIONAL
+	ActiveSize       DISPLAYCONFIG_2DREGION
+	TotalSize        DISPLAYCONFIG_2DREGION
+	VideoStandard    uint32 // Union with the AdditionalSignalInfo bitfield
+	ScanLineOrdering uint32
+}
+
+type DISPLAYCONFIG_TARGET_MODE struct {
+	TargetVideoSignalInfo DISPLAYCONFIG_VIDEO_SIGNAL_INFO
+}
+
+type DISPLAYCONFIG_SOURCE_MODE struct {
+	Width       uint32
+	Height      uint32
+	PixelFormat uint32
+	Position    POINTL
+}
+
+type DISPLAYCONFIG_DESKTOP_IMAGE_INFO struct {
+	PathSourceSize     POINTL
+	DesktopImageRegion RECTL
+	DesktopImageClip   RECTL
+}
+
+// DISPLAYCONFIG_MODE_INFO holds a source, target or desktop image mode
+// depending on InfoType. The modes share storage like the C union, use
+// the accessor methods to read them.
+type DISPLAYCONFIG_MODE_INFO struct {
+	InfoType  uint32
+	Id        uint32
+	AdapterId LUID
+	union     [6]uint64 // Sized and aligned like DISPLAYCONFIG_TARGET_MODE, the largest member
+}
+
+// TargetMode returns the mode as a target mode
+func (m *DISPLAYCONFIG_MODE_INFO) TargetMode() *DISPLAYCONFIG_TARGET_MODE {
+	return (*DISPLAYCONFIG_TARGET_MODE)(unsafe.Pointer(&m.union))
+}
+
+// SourceMode returns the mode as a source mode
+func (m *DISPLAYCONFIG_MODE_INFO) SourceMode() *DISPLAYCONFIG_SOURCE_MODE {
+	return (*DISPLAYCONFIG_SOURCE_MODE)(unsafe.Pointer(&m.union))
+}
+
+// DesktopImageInfo returns the mode as desktop image information
+func (m *DISPLAYCONFIG_MODE_INFO) DesktopImageInfo() *DISPLAYCONFIG_DESKTOP_IMAGE_INFO {
+	return (*DISPLAYCONFIG_DESKTOP_IMAGE_INFO)(unsafe.Pointer(&m.union))
+}
+
+type DISPLAYCONFIG_DEVICE_INFO_HEADER struct {
+	Type      uint32
+	Size      uint32
+	AdapterId LUID
+	Id        uint32
+}
+
+type DISPLAYCONFIG_SOURCE_DEVICE_NAME struct {
+	Header            DISPLAYCONFIG_DEVICE_INFO_HEADER
+	ViewGdiDeviceName [32]uint16
+}
+
+type DISPLAYCONFIG_TARGET_DEVICE_NAME struct {
+	Header                    DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Flags                     uint32
+	OutputTechnology          DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY
+	EdidManufactureId         uint16
+	EdidProductCodeId         uint16
+	ConnectorInstance         uint32
+	MonitorFriendlyDeviceName [64]uint16
+	MonitorDevicePath         [128]uint16
+}
+
+type DISPLAYCONFIG_TARGET_PREFERRED_MODE struct {
+	Header     DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Width      uint32
+	Height     uint32
+	_          uint32 // DISPLAYCONFIG_TARGET_MODE is 8-byte aligned, also on 386
+	TargetMode DISPLAYCONFIG_TARGET_MODE
+}
+
+type DISPLAYCONFIG_ADAPTER_NAME struct {
+	Header            DISPLAYCONFIG_DEVICE_INFO_HEADER
+	AdapterDevicePath [128]uint16
+}
+
+type DISPLAYCONFIG_TARGET_BASE_TYPE struct {
+	Header               DISPLAYCONFIG_DEVICE_INFO_HEADER
+	BaseOutputTechnology DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY
+}
+
+type DISPLAYCONFIG_SET_TARGET_PERSISTENCE struct {
+	Header DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value  uint32 // Bit 0: bootPersistenceOn
+}
+
+type DISPLAYCONFIG_SUPPORT_VIRTUAL_RESOLUTION struct {
+	Header DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value  uint32 // Bit 0: disableMonitorVirtualResolution
+}
+
+// DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO is the pre-24H2 advanced color query
+type DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO struct {
+	Header              DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value               uint32 // Bitfield, see the accessor methods
+	ColorEncoding       uint32
+	BitsPerColorChannel uint32
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO) AdvancedColorSupported() bool {
+	return i.Value&(1<<0) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO) AdvancedColorEnabled() bool {
+	return i.Value&(1<<1) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO) WideColorEnforced() bool {
+	return i.Value&(1<<2) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO) AdvancedColorForceDisabled() bool {
+	return i.Value&(1<<3) != 0
+}
+
+// DISPLAYCONFIG_SET_ADVANCED_COLOR_STATE is the pre-24H2 HDR switch
+type DISPLAYCONFIG_SET_ADVANCED_COLOR_STATE struct {
+	Header DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value  uint32 // Bit 0: enableAdvancedColor
+}
+
+// DISPLAYCONFIG_SDR_WHITE_LEVEL reports the SDR white level in units of
+// 1/1000 of 80 nits (1000 = 80 nits)
+type DISPLAYCONFIG_SDR_WHITE_LEVEL struct {
+	Header        DISPLAYCONFIG_DEVICE_INFO_HEADER
+	SDRWhiteLevel uint32
+}
+
+// DISPLAYCONFIG_SET_SDR_WHITE_LEVEL sets the SDR white level, in the same
+// units as DISPLAYCONFIG_SDR_WHITE_LEVEL
+type DISPLAYCONFIG_SET_SDR_WHITE_LEVEL struct {
+	Header        DISPLAYCONFIG_DEVICE_INFO_HEADER
+	SDRWhiteLevel uint32
+	FinalValue    uint8 // Non-zero to persist the value, zero while dragging a slider
+	_             [3]byte
+}
+
+type DISPLAYCONFIG_GET_MONITOR_SPECIALIZATION struct {
+	Header DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value  uint32 // Bits: isSpecializationEnabled, ...AvailableForMonitor, ...AvailableForSystem
+}
+
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+type DISPLAYCONFIG_SET_MONITOR_SPECIALIZATION struct {
+	Header                        DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value                         uint32 // Bit 0: isSpecializationEnabled
+	SpecializationType            GUID
+	SpecializationSubType         GUID
+	SpecializationApplicationName [128]uint16
+}
+
+// DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2 is the Windows 11 24H2 query
+// that reports HDR and wide color gamut separately
+type DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2 struct {
+	Header              DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value               uint32 // Bitfield, see the accessor methods
+	ColorEncoding       uint32
+	BitsPerColorChannel uint32
+	ActiveColorMode     uint32 // DISPLAYCONFIG_ADVANCED_COLOR_MODE_*
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2) AdvancedColorSupported() bool {
+	return i.Value&(1<<0) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2) AdvancedColorActive() bool {
+	return i.Value&(1<<1) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2) AdvancedColorLimitedByPolicy() bool {
+	return i.Value&(1<<3) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2) HighDynamicRangeSupported() bool {
+	return i.Value&(1<<4) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2) HighDynamicRangeUserEnabled() bool {
+	return i.Value&(1<<5) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2) WideColorSupported() bool {
+	return i.Value&(1<<6) != 0
+}
+
+func (i *DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2) WideColorUserEnabled() bool {
+	return i.Value&(1<<7) != 0
+}
+
+// DISPLAYCONFIG_SET_HDR_STATE is the Windows 11 24H2 HDR switch
+type DISPLAYCONFIG_SET_HDR_STATE struct {
+	Header DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value  uint32 // Bit 0: enableHdr
+}
+
+// DISPLAYCONFIG_SET_WCG_STATE is the Windows 11 24H2 wide color switch,
+// which drives Auto Color Management
+type DISPLAYCONFIG_SET_WCG_STATE struct {
+	Header DISPLAYCONFIG_DEVICE_INFO_HEADER
+	Value  uint32 // Bit 0: enableWcg
+}
+
+// FriendlyName returns the monitor name, e.g. "DELL U2720Q"
+func (n *DISPLAYCONFIG_TARGET_DEVICE_NAME) FriendlyName() string {
+	return utf16ToString(n.MonitorFriendlyDeviceName[:])
+}
+
+// DevicePath returns the monitor device interface path
+func (n *DISPLAYCONFIG_TARGET_DEVICE_NAME) DevicePath() string {
+	return utf16ToString(n.MonitorDevicePath[:])
+}
+
+// GdiDeviceName returns the GDI device name, e.g. \\.\DISPLAY1
+func (n *DISPLAYCONFIG_SOURCE_DEVICE_NAME) GdiDeviceName() string {
+	return utf16ToString(n.ViewGdiDeviceName[:])
+}
+
+// utf16ToString converts a NUL terminated UTF-16 buffer
+func utf16ToString(s []uint16) string {
+	for i, c := range s {
+		if c == 0 {
+			s = s[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(s))
+}
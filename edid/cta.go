@@ -0,0 +1,116 @@
+package edid
+
+import (
+	"fmt"
+	"math"
+)
+
+// CTA-861 data block tags
+const (
+	ctaTagExtended = 7
+
+	ctaExtColorimetry = 5
+	ctaExtHDRStatic   = 6
+)
+
+// colorimetryNames maps the bits of the first Colorimetry Data Block byte
+var colorimetryNames = []string{
+	"xvYCC601",
+	"xvYCC709",
+	"sYCC601",
+	"opYCC601",
+	"opRGB",
+	"BT.2020 cYCC",
+	"BT.2020 YCC",
+	"BT.2020 RGB",
+}
+
+// parseCTA decodes a CTA-861 extension block
+func (e *EDID) parseCTA(block []byte) {
+	e.Extensions = append(e.Extensions, fmt.Sprintf("CTA-861 rev %d", block[1]))
+
+	dtdStart := int(block[2])
+	if dtdStart == 0 {
+		return // No data blocks and no timings
+	}
+	if dtdStart < 4 || dtdStart > 127 {
+		return
+	}
+
+	// Data block collection from byte 4 up to the first detailed timing
+	if block[1] >= 3 {
+		for i := 4; i < dtdStart; {
+			tag := block[i] >> 5
+			length := int(block[i] & 0x1F)
+			end := i + 1 + length
+			if end > dtdStart {
+				break
+			}
+			payload := block[i+1 : end]
+
+			if tag == ctaTagExtended && len(payload) > 0 {
+				switch payload[0] {
+				case ctaExtColorimetry:
+					e.parseColorimetry(payload[1:])
+				case ctaExtHDRStatic:
+					e.parseHDRStatic(payload[1:])
+				}
+			}
+			i = end
+		}
+	}
+
+	// Detailed timings fill the rest of the block
+	for i := dtdStart; i+18 <= 127; i += 18 {
+		d := block[i : i+18]
+		if d[0] == 0 && d[1] == 0 {
+			break
+		}
+		if t, ok := parseDetailedTiming(d); ok {
+			e.Timings = append(e.Timings, t)
+		}
+	}
+}
+
+// parseColorimetry decodes a Colorimetry Data Block
+func (e *EDID) parseColorimetry(b []byte) {
+	if len(b) < 1 {
+		return
+	}
+	for bit, name := range colorimetryNames {
+		if b[0]&(1<<bit) != 0 {
+			e.addColorSpace(name)
+		}
+	}
+	if len(b) >= 2 && b[1]&0x80 != 0 {
+		e.addColorSpace("DCI-P3")
+	}
+}
+
+// parseHDRStatic decodes an HDR Static Metadata Data Block
+func (e *EDID) parseHDRStatic(b []byte) {
+	if len(b) < 2 {
+		return
+	}
+
+	h := &HDRMetadata{Source: "CTA-861"}
+	for bit, name := range []string{"SDR", "HDR", "PQ", "HLG"} {
+		if b[0]&(1<<bit) != 0 {
+			h.addEOTF(name)
+		}
+	}
+
+	// Luminance code values per CTA-861.3
+	if len(b) >= 3 && b[2] != 0 {
+		h.MaxLuminance = 50 * math.Pow(2, float64(b[2])/32)
+	}
+	if len(b) >= 4 && b[3] != 0 {
+		h.MaxFrameAvgLuminance = 50 * math.Pow(2, float64(b[3])/32)
+	}
+	if len(b) >= 5 && h.MaxLuminance != 0 {
+		cv := float64(b[4]) / 255
+		h.MinLuminance = h.MaxLuminance * cv * cv / 100
+	}
+
+	e.HDR = h
+}
@@ -0,0 +1,144 @@
+package edid
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// DisplayID 2.x data block tags
+const (
+	displayIDDisplayParameters = 0x21
+	displayIDInterfaceFeatures = 0x26
+)
+
+// interfaceColorSpaces maps the bits of the DisplayID 2.x Display Interface
+// Features color space and EOTF byte
+var interfaceColorSpaces = []string{
+	"sRGB",
+	"BT.601",
+	"BT.709",
+	"Adobe RGB",
+	"DCI-P3",
+	"BT.2020 RGB",
+	"BT.2020 RGB", // BT.2020 with SMPTE ST 2084
+}
+
+// parseDisplayID decodes a DisplayID section embedded in an EDID extension
+func (e *EDID) parseDisplayID(section []byte) {
+	if len(section) < 5 {
+		return
+	}
+
+	version := section[0]
+	size := int(section[1])
+	if 4+size+1 > len(section) || checksum(section[:4+size+1]) != 0 {
+		return
+	}
+	e.Extensions = append(e.Extensions, fmt.Sprintf("DisplayID %d.%d", version>>4, version&0x0F))
+
+	if version < 0x20 {
+		return // Only DisplayID 2.x carries color and luminance data
+	}
+
+	blocks := section[4 : 4+size]
+	for i := 0; i+3 <= len(blocks); {
+		tag := blocks[i]
+		length := int(blocks[i+2])
+		end := i + 3 + length
+		if tag == 0 || end > len(blocks) {
+			break
+		}
+		payload := blocks[i+3 : end]
+
+		switch tag {
+		case displayIDDisplayParameters:
+			e.parseDisplayParameters(payload)
+		case displayIDInterfaceFeatures:
+			e.parseInterfaceFeatures(payload)
+		}
+		i = end
+	}
+}
+
+// parseDisplayParameters decodes a DisplayID 2.x Display Parameters block
+func (e *EDID) parseDisplayParameters(b []byte) {
+	if len(b) < 29 {
+		return
+	}
+
+	point := func(p []byte) Point {
+		x := uint16(p[0]) | uint16(p[1]&0x0F)<<8
+		y := uint16(p[1]>>4) | uint16(p[2])<<4
+		return Point{float64(x) / 4096, float64(y) / 4096}
+	}
+	e.Primaries = Chromaticity{
+		Red:   point(b[9:12]),
+		Green: point(b[12:15]),
+		Blue:  point(b[15:18]),
+		White: point(b[18:21]),
+	}
+
+	h := e.HDR
+	if h == nil {
+		h = &HDRMetadata{Source: "DisplayID"}
+	}
+	// Full-screen luminance is the sustained frame average; the 10% window
+	// is the peak
+	if h.MaxLuminance == 0 {
+		h.MaxLuminance = halfFloat(binary.LittleEndian.Uint16(b[23:25]))
+	}
+	if h.MaxFrameAvgLuminance == 0 {
+		h.MaxFrameAvgLuminance = halfFloat(binary.LittleEndian.Uint16(b[21:23]))
+	}
+	if h.MinLuminance == 0 {
+		h.MinLuminance = halfFloat(binary.LittleEndian.Uint16(b[25:27]))
+	}
+	if h.MaxLuminance > 0 || len(h.EOTFs) > 0 {
+		e.HDR = h
+	}
+
+	if b[28] != 0xFF && e.Gamma == 0 {
+		e.Gamma = (float64(b[28]) + 100) / 100
+	}
+}
+
+// parseInterfaceFeatures decodes the color space and EOTF support of a
+// DisplayID 2.x Display Interface Features block
+func (e *EDID) parseInterfaceFeatures(b []byte) {
+	if len(b) < 7 {
+		return
+	}
+
+	for bit, name := range interfaceColorSpaces {
+		if b[6]&(1<<bit) != 0 {
+			e.addColorSpace(name)
+		}
+	}
+
+	// BT.2020 with SMPTE ST 2084 means the display takes PQ signals
+	if b[6]&(1<<6) != 0 {
+		if e.HDR == nil {
+			e.HDR = &HDRMetadata{Source: "DisplayID"}
+		}
+		e.HDR.addEOTF("PQ")
+	}
+}
+
+// halfFloat converts an IEEE 754 half precision value
+func halfFloat(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1F
+	frac := float64(h & 0x3FF)
+
+	switch exp {
+	case 0:
+		return sign * frac / 1024 * math.Pow(2, -14)
+	case 0x1F:
+		return 0 // Infinity and NaN carry no luminance information
+	}
+	return sign * (1 + frac/1024) * math.Pow(2, float64(exp-15))
+}
@@ -0,0 +1,265 @@
+package edid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// BlockSize is the size of the EDID base block and of each extension block
+const BlockSize = 128
+
+var header = []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00}
+
+// EDID holds the decoded fields of an Extended Display Identification Data blob
+type EDID struct {
+	Manufacturer string // Three letter PNP ID, e.g. "DEL"
+	ProductCode  uint16
+	SerialNumber uint32 // Zero when the monitor only reports a serial string
+	SerialString string // From the 0xFF display descriptor
+	Name         string // From the 0xFC display descriptor
+	Week         int    // Week of manufacture, 0 if unknown
+	Year         int    // Year of manufacture, or model year when Week is 0xFF
+	Version      int
+	Revision     int
+
+	Digital      bool
+	WidthCm      int     // Physical size, 0 if unknown
+	HeightCm     int     // Physical size, 0 if unknown
+	Gamma        float64 // Display transfer characteristic, 0 if not given
+	Primaries    Chromaticity
+	NativeTiming *Timing  // Preferred timing, nil if there is no detailed timing
+	Timings      []Timing // Every detailed timing from all blocks
+	ColorSpaces  []string // Supported color spaces, e.g. "sRGB", "BT.2020 RGB", "DCI-P3"
+	HDR          *HDRMetadata
+	Extensions   []string // Decoded extension blocks, e.g. "CTA-861 rev 3"
+}
+
+// Point is a CIE 1931 xy chromaticity coordinate
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Chromaticity holds the color primaries and white point of the panel
+type Chromaticity struct {
+	Red   Point
+	Green Point
+	Blue  Point
+	White Point
+}
+
+// Timing is a detailed timing descriptor
+type Timing struct {
+	Width         int
+	Height        int
+	RefreshRate   float64
+	PixelClockKHz int
+	Interlaced    bool
+	WidthMm       int // Image size, 0 if unknown
+	HeightMm      int
+}
+
+// HDRMetadata holds the HDR static metadata advertised by the display
+type HDRMetadata struct {
+	EOTFs                []string // "SDR", "HDR", "PQ" (SMPTE ST 2084), "HLG"
+	MaxLuminance         float64  // Desired content max luminance in cd/m², 0 if not given
+	MaxFrameAvgLuminance float64  // Desired content max frame-average luminance in cd/m², 0 if not given
+	MinLuminance         float64  // Desired content min luminance in cd/m², 0 if not given
+	Source               string   // "CTA-861" or "DisplayID"
+}
+
+// SupportsHDR reports whether the display accepts an HDR transfer function
+func (e *EDID) SupportsHDR() bool {
+	if e.HDR == nil {
+		return false
+	}
+	for _, eotf := range e.HDR.EOTFs {
+		if eotf == "PQ" || eotf == "HLG" {
+			return true
+		}
+	}
+	return false
+}
+
+// DiagonalInches returns the screen diagonal from the physical size, or 0 if unknown
+func (e *EDID) DiagonalInches() float64 {
+	if e.WidthCm == 0 || e.HeightCm == 0 {
+		return 0
+	}
+	return math.Hypot(float64(e.WidthCm), float64(e.HeightCm)) / 2.54
+}
+
+// Parse decodes an EDID. Only the base block is required; a bad checksum
+// is reported as an error since it means the data can't be trusted.
+func Parse(data []byte) (*EDID, error) {
+	if len(data) < BlockSize {
+		return nil, fmt.Errorf("EDID too short: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[:8], header) {
+		return nil, errors.New("invalid EDID header")
+	}
+	if checksum(data[:BlockSize]) != 0 {
+		return nil, errors.New("invalid EDID base block checksum")
+	}
+
+	e := &EDID{
+		Manufacturer: DecodeManufacturer(binary.BigEndian.Uint16(data[8:10])),
+		ProductCode:  binary.LittleEndian.Uint16(data[10:12]),
+		SerialNumber: binary.LittleEndian.Uint32(data[12:16]),
+		Week:         int(data[16]),
+		Year:         int(data[17]) + 1990,
+		Version:      int(data[18]),
+		Revision:     int(data[19]),
+	}
+
+	e.Digital = data[20]&0x80 != 0
+	e.WidthCm = int(data[21])
+	e.HeightCm = int(data[22])
+	if data[23] != 0xFF {
+		e.Gamma = (float64(data[23]) + 100) / 100
+	}
+	if data[24]&0x04 != 0 {
+		e.ColorSpaces = append(e.ColorSpaces, "sRGB")
+	}
+	e.Primaries = parseChromaticity(data[25:35])
+
+	// Four 18-byte descriptors follow the standard timings
+	for i := 0; i < 4; i++ {
+		d := data[54+i*18 : 54+(i+1)*18]
+		if d[0] != 0 || d[1] != 0 {
+			if t, ok := parseDetailedTiming(d); ok {
+				e.Timings = append(e.Timings, t)
+			}
+			continue
+		}
+		switch d[3] {
+		case 0xFF:
+			e.SerialString = descriptorText(d[5:])
+		case 0xFC:
+			e.Name = descriptorText(d[5:])
+		}
+	}
+
+	// Extension blocks with a bad checksum are skipped rather than failing
+	// the whole EDID, since the base block is still usable
+	count := int(data[126])
+	for i := 1; i <= count && (i+1)*BlockSize <= len(data); i++ {
+		block := data[i*BlockSize : (i+1)*BlockSize]
+		if checksum(block) != 0 {
+			continue
+		}
+		switch block[0] {
+		case 0x02:
+			e.parseCTA(block)
+		case 0x70:
+			e.parseDisplayID(block[1:127])
+		}
+	}
+
+	if len(e.Timings) > 0 {
+		native := e.Timings[0]
+		e.NativeTiming = &native
+	}
+
+	return e, nil
+}
+
+// parseChromaticity decodes the 10-bit color characteristics of bytes 25-34
+func parseChromaticity(b []byte) Chromaticity {
+	coord := func(hi byte, lo byte, shift uint) float64 {
+		return float64(uint16(hi)<<2|uint16(lo>>shift&0x03)) / 1024
+	}
+	return Chromaticity{
+		Red:   Point{coord(b[2], b[0], 6), coord(b[3], b[0], 4)},
+		Green: Point{coord(b[4], b[0], 2), coord(b[5], b[0], 0)},
+		Blue:  Point{coord(b[6], b[1], 6), coord(b[7], b[1], 4)},
+		White: Point{coord(b[8], b[1], 2), coord(b[9], b[1], 0)},
+	}
+}
+
+// parseDetailedTiming decodes an 18-byte detailed timing descriptor
+func parseDetailedTiming(d []byte) (Timing, bool) {
+	clock := int(binary.LittleEndian.Uint16(d[0:2])) * 10 // kHz
+	hActive := int(d[2]) | int(d[4]&0xF0)<<4
+	hBlank := int(d[3]) | int(d[4]&0x0F)<<8
+	vActive := int(d[5]) | int(d[7]&0xF0)<<4
+	vBlank := int(d[6]) | int(d[7]&0x0F)<<8
+	if clock == 0 || hActive == 0 || vActive == 0 {
+		return Timing{}, false
+	}
+
+	t := Timing{
+		Width:         hActive,
+		Height:        vActive,
+		PixelClockKHz: clock,
+		Interlaced:    d[17]&0x80 != 0,
+		WidthMm:       int(d[12]) | int(d[14]&0xF0)<<4,
+		HeightMm:      int(d[13]) | int(d[14]&0x0F)<<8,
+	}
+	if t.Interlaced {
+		t.Height *= 2
+	}
+	t.RefreshRate = float64(clock) * 1000 / float64((hActive+hBlank)*(vActive+vBlank))
+	return t, true
+}
+
+// addColorSpace records a color space once
+func (e *EDID) addColorSpace(name string) {
+	for _, cs := range e.ColorSpaces {
+		if cs == name {
+			return
+		}
+	}
+	e.ColorSpaces = append(e.ColorSpaces, name)
+}
+
+// addEOTF records a transfer function once
+func (h *HDRMetadata) addEOTF(name string) {
+	for _, eotf := range h.EOTFs {
+		if eotf == name {
+			return
+		}
+	}
+	h.EOTFs = append(h.EOTFs, name)
+}
+
+// DecodeManufacturer converts the packed big-endian PNP ID from EDID bytes
+// 8-9 into its three letters
+func DecodeManufacturer(id uint16) string {
+	letters := []byte{
+		byte(id>>10&0x1F) + 'A' - 1,
+		byte(id>>5&0x1F) + 'A' - 1,
+		byte(id&0x1F) + 'A' - 1,
+	}
+	for _, c := range letters {
+		if c < 'A' || c > 'Z' {
+			return fmt.Sprintf("%04X", id)
+		}
+	}
+	return string(letters)
+}
+
+// descriptorText decodes the 13-byte text field of a display descriptor
+func descriptorText(b []byte) string {
+	if i := bytes.IndexByte(b, 0x0A); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7E {
+			return -1
+		}
+		return r
+	}, string(b)))
+}
+
+func checksum(block []byte) byte {
+	var sum byte
+	for _, b := range block {
+		sum += b
+	}
+	return sum
+}
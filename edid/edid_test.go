@@ -0,0 +1,258 @@
+package edid
+
+import (
+	"math"
+	"testing"
+)
+
+// u2720q is the base block of a DELL U2720Q with the gamma byte and
+// extension count left for each test to fill in
+var u2720q = []byte{
+	0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, // header
+	0x10, 0xAC, 0xB4, 0x40, 0x4C, 0x32, 0x41, 0x4C, // DEL, 0x40B4, serial
+	0x01, 0x1E, 0x01, 0x04, // week 1 of 2020, EDID 1.4
+	0xB5, 0x3C, 0x22, 0x78, 0x3E, // digital, 60x34 cm, gamma 2.2, sRGB
+	0xEE, 0x95, 0xA3, 0x54, 0x4C, 0x99, 0x26, 0x0F, 0x50, 0x54, // chromaticity
+	0xA5, 0x4B, 0x00, // established timings
+	0x71, 0x4F, 0x81, 0x80, 0xA9, 0xC0, 0xD1, 0xC0, // standard timings
+	0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+	// 3840x2160 at 60 Hz
+	0x4D, 0xD0, 0x00, 0xA0, 0xF0, 0x70, 0x3E, 0x80, 0x30, 0x20, 0x35, 0x00, 0x54, 0x4F, 0x21, 0x00, 0x00, 0x1A,
+	// Serial string
+	0x00, 0x00, 0x00, 0xFF, 0x00, 0x42, 0x37, 0x34, 0x54, 0x53, 0x4D, 0x32, 0x0A, 0x20, 0x20, 0x20, 0x20, 0x20,
+	// Name
+	0x00, 0x00, 0x00, 0xFC, 0x00, 0x44, 0x45, 0x4C, 0x4C, 0x20, 0x55, 0x32, 0x37, 0x32, 0x30, 0x51, 0x0A, 0x20,
+	// Range limits
+	0x00, 0x00, 0x00, 0xFD, 0x00, 0x18, 0x4B, 0x1E, 0x8C, 0x3C, 0x00, 0x0A, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x00, 0x00, // extension count, checksum
+}
+
+// cta is a CTA-861 rev 3 extension with BT.2020 colorimetry and HDR
+// static metadata for SDR, PQ and HLG
+var cta = []byte{
+	0x02, 0x03, 0x12, 0x00,
+	0xE3, 0x05, 0xC0, 0x00, // colorimetry: BT.2020 YCC and RGB
+	0xE6, 0x06, 0x0D, 0x01, 0x73, 0x6D, 0x07, // HDR static metadata
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// displayID is a DisplayID 2.0 section with Display Parameters and Display
+// Interface Features blocks, gamma 2.6, 600 nits peak and 400 nits full screen
+var displayID = []byte{
+	0x20, 0x00, 0x00, 0x00, // version, size (filled in), product type, extension count
+	0x21, 0x00, 0x1D, // Display Parameters
+	0x54, 0x17, 0x26, 0x0D, 0x00, 0x0F, 0x70, 0x08, 0x00,
+	0xE1, 0xFA, 0x51, 0x3D, 0xA4, 0xB0, 0x66, 0x62, 0x0F, 0x01, 0x45, 0x54, // DCI-P3, D65
+	0x40, 0x5E, // 400 nits full screen
+	0xB0, 0x60, // 600 nits in a 10% window
+	0x66, 0x2A, // 0.05 nits
+	0x27, 0xA0, // 10 bpc, gamma 2.6
+	0x26, 0x00, 0x09, // Display Interface Features
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x41, 0x00, 0x00, // sRGB, BT.2020 PQ
+}
+
+// build assembles an EDID from the U2720Q base block with the given gamma
+// byte and extension blocks, fixing up every checksum
+func build(gamma byte, extensions ...[]byte) []byte {
+	data := append([]byte(nil), u2720q...)
+	data[23] = gamma
+	data[126] = byte(len(extensions))
+	data[127] -= checksum(data[:BlockSize])
+
+	for _, ext := range extensions {
+		block := make([]byte, BlockSize)
+		copy(block, ext)
+		block[127] -= checksum(block)
+		data = append(data, block...)
+	}
+	return data
+}
+
+// ctaBlock returns the CTA-861 extension
+func ctaBlock() []byte {
+	return cta
+}
+
+// displayIDBlock returns the DisplayID extension with its section checksum
+func displayIDBlock() []byte {
+	section := append([]byte(nil), displayID...)
+	section[1] = byte(len(section) - 4)
+	section = append(section, 0)
+	section[len(section)-1] -= checksum(section)
+	return append([]byte{0x70}, section...)
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestParseBase(t *testing.T) {
+	e, err := Parse(build(0x78))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Manufacturer != "DEL" || e.ProductCode != 0x40B4 || e.SerialNumber != 0x4C41324C {
+		t.Errorf("identity = %s %04X %08X, want DEL 40B4 4C41324C", e.Manufacturer, e.ProductCode, e.SerialNumber)
+	}
+	if e.Name != "DELL U2720Q" || e.SerialString != "B74TSM2" {
+		t.Errorf("descriptors = %q %q, want \"DELL U2720Q\" \"B74TSM2\"", e.Name, e.SerialString)
+	}
+	if e.Year != 2020 || e.Week != 1 || e.Version != 1 || e.Revision != 4 {
+		t.Errorf("date and version = %d/%d %d.%d, want 1/2020 1.4", e.Week, e.Year, e.Version, e.Revision)
+	}
+	if !e.Digital || e.WidthCm != 60 || e.HeightCm != 34 {
+		t.Errorf("size = %dx%d cm digital %v, want 60x34 cm digital", e.WidthCm, e.HeightCm, e.Digital)
+	}
+	if len(e.ColorSpaces) != 1 || e.ColorSpaces[0] != "sRGB" {
+		t.Errorf("color spaces = %v, want [sRGB]", e.ColorSpaces)
+	}
+	if !near(e.Primaries.White.X, 0.3135, 1e-3) || !near(e.Primaries.White.Y, 0.3291, 1e-3) {
+		t.Errorf("white point = %v, want about D65", e.Primaries.White)
+	}
+
+	nt := e.NativeTiming
+	if nt == nil || nt.Width != 3840 || nt.Height != 2160 || !near(nt.RefreshRate, 60, 0.01) || nt.WidthMm != 596 || nt.HeightMm != 335 {
+		t.Errorf("native timing = %+v, want 3840x2160 at 60 Hz, 596x335 mm", nt)
+	}
+	if e.HDR != nil || len(e.Extensions) != 0 {
+		t.Errorf("HDR = %+v, extensions = %v, want none", e.HDR, e.Extensions)
+	}
+}
+
+func TestParseGamma(t *testing.T) {
+	tests := []struct {
+		b    byte
+		want float64
+	}{
+		{0x00, 1.0},
+		{0x78, 2.2},
+		{0x9B, 2.55},
+		{0x9C, 2.56}, // b+100 overflows a byte from here on
+		{0xA0, 2.6},
+		{0xFE, 3.54},
+		{0xFF, 0}, // Not given
+	}
+	for _, tt := range tests {
+		e, err := Parse(build(tt.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !near(e.Gamma, tt.want, 1e-9) {
+			t.Errorf("gamma byte %#02x: Gamma = %v, want %v", tt.b, e.Gamma, tt.want)
+		}
+	}
+}
+
+func TestParseCTA(t *testing.T) {
+	e, err := Parse(build(0x78, ctaBlock()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.Extensions) != 1 || e.Extensions[0] != "CTA-861 rev 3" {
+		t.Errorf("extensions = %v, want [CTA-861 rev 3]", e.Extensions)
+	}
+	want := []string{"sRGB", "BT.2020 YCC", "BT.2020 RGB"}
+	if len(e.ColorSpaces) != len(want) {
+		t.Fatalf("color spaces = %v, want %v", e.ColorSpaces, want)
+	}
+	for i := range want {
+		if e.ColorSpaces[i] != want[i] {
+			t.Errorf("color spaces = %v, want %v", e.ColorSpaces, want)
+			break
+		}
+	}
+
+	h := e.HDR
+	if h == nil || h.Source != "CTA-861" {
+		t.Fatalf("HDR = %+v, want CTA-861 metadata", h)
+	}
+	if len(h.EOTFs) != 3 || h.EOTFs[0] != "SDR" || h.EOTFs[1] != "PQ" || h.EOTFs[2] != "HLG" {
+		t.Errorf("EOTFs = %v, want [SDR PQ HLG]", h.EOTFs)
+	}
+	if !e.SupportsHDR() {
+		t.Error("SupportsHDR = false, want true")
+	}
+	// 50 * 2^(115/32), 50 * 2^(109/32) and max * (7/255)^2 / 100
+	if !near(h.MaxLuminance, 603.7, 0.1) || !near(h.MaxFrameAvgLuminance, 530.1, 0.1) || !near(h.MinLuminance, 0.00455, 1e-5) {
+		t.Errorf("luminance = %v / %v / %v, want 603.7 / 530.1 / 0.00455", h.MaxLuminance, h.MaxFrameAvgLuminance, h.MinLuminance)
+	}
+}
+
+func TestParseDisplayID(t *testing.T) {
+	e, err := Parse(build(0xFF, displayIDBlock()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.Extensions) != 1 || e.Extensions[0] != "DisplayID 2.0" {
+		t.Errorf("extensions = %v, want [DisplayID 2.0]", e.Extensions)
+	}
+	if !near(e.Gamma, 2.6, 1e-9) {
+		t.Errorf("Gamma = %v, want 2.6", e.Gamma)
+	}
+
+	h := e.HDR
+	if h == nil || h.Source != "DisplayID" {
+		t.Fatalf("HDR = %+v, want DisplayID metadata", h)
+	}
+	if h.MaxLuminance != 600 || h.MaxFrameAvgLuminance != 400 || !near(h.MinLuminance, 0.05, 1e-4) {
+		t.Errorf("luminance = %v / %v / %v, want 600 / 400 / 0.05", h.MaxLuminance, h.MaxFrameAvgLuminance, h.MinLuminance)
+	}
+	if !e.SupportsHDR() {
+		t.Error("SupportsHDR = false, want true")
+	}
+	if !near(e.Primaries.Red.X, 0.68, 1e-3) || !near(e.Primaries.White.Y, 0.3291, 1e-3) {
+		t.Errorf("primaries = %+v, want DCI-P3 with a D65 white", e.Primaries)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	bad := build(0x78)
+	bad[127]++
+
+	badExtension := build(0x78, ctaBlock())
+	badExtension[BlockSize+127]++
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short", build(0x78)[:BlockSize-1]},
+		{"header", append([]byte{0x01}, build(0x78)[1:]...)},
+		{"checksum", bad},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.data); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", tt.name)
+		}
+	}
+
+	// A corrupt extension is skipped and the base block still parses
+	e, err := Parse(badExtension)
+	if err != nil || e.HDR != nil || len(e.Extensions) != 0 {
+		t.Errorf("bad extension: got %+v, %v, want the base block only", e, err)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(build(0x78))
+	f.Add(build(0xA0, ctaBlock()))
+	f.Add(build(0xFF, displayIDBlock()))
+	f.Add(build(0xFF, ctaBlock(), displayIDBlock()))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		e, err := Parse(data)
+		if err != nil {
+			return
+		}
+		if e.Gamma != 0 && (e.Gamma < 1 || e.Gamma > 3.55) {
+			t.Errorf("Gamma = %v, outside 1.00-3.54", e.Gamma)
+		}
+		if e.NativeTiming != nil && (e.NativeTiming.Width <= 0 || e.NativeTiming.Height <= 0) {
+			t.Errorf("native timing = %+v", e.NativeTiming)
+		}
+	})
+}
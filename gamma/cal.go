@@ -0,0 +1,116 @@
+package gamma
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// calFields are the data columns of an Argyll calibration file
+var calFields = []string{"RGB_I", "RGB_R", "RGB_G", "RGB_B"}
+
+// ReadCal reads the calibration curves of an Argyll .cal file (CGATS text
+// with RGB_I, RGB_R, RGB_G and RGB_B columns)
+func ReadCal(r io.Reader) (Table, string, error) {
+	var t Table
+	var description string
+	var columns []string
+	header, inFormat, inData := false, false, false
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+
+		switch {
+		case !header:
+			if fields[0] != "CAL" {
+				return t, "", errors.New("not an Argyll calibration file (missing CAL header)")
+			}
+			header = true
+		case text == "BEGIN_DATA_FORMAT":
+			inFormat = true
+		case text == "END_DATA_FORMAT":
+			inFormat = false
+		case text == "BEGIN_DATA":
+			for _, name := range calFields {
+				if indexOf(columns, name) < 0 {
+					return t, "", fmt.Errorf("calibration file has no %s column", name)
+				}
+			}
+			inData = true
+		case text == "END_DATA":
+			inData = false
+		case inFormat:
+			columns = append(columns, fields...)
+		case inData:
+			if len(fields) != len(columns) {
+				return t, "", fmt.Errorf("line %d: expected %d values, got %d", line, len(columns), len(fields))
+			}
+			for c, name := range calFields[1:] {
+				i := indexOf(columns, name)
+				v, err := strconv.ParseFloat(fields[i], 64)
+				if err != nil {
+					return t, "", fmt.Errorf("line %d: invalid %s value %s", line, name, fields[i])
+				}
+				t.Curves[c] = append(t.Curves[c], v)
+			}
+		case fields[0] == "DESCRIPTOR":
+			description = strings.Trim(strings.TrimSpace(strings.TrimPrefix(text, "DESCRIPTOR")), `"`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return t, "", err
+	}
+	if t.Size() == 0 {
+		return t, "", errors.New("calibration file has no data")
+	}
+	return t, description, t.validate()
+}
+
+// WriteCal writes the curves as an Argyll .cal file
+func WriteCal(w io.Writer, t Table, description string) error {
+	if description == "" {
+		description = "Argyll Device Calibration State"
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "CAL")
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "DESCRIPTOR \"%s\"\n", strings.ReplaceAll(description, `"`, "'"))
+	fmt.Fprintln(bw, `ORIGINATOR "lumos"`)
+	fmt.Fprintln(bw, `KEYWORD "DEVICE_CLASS"`)
+	fmt.Fprintln(bw, `DEVICE_CLASS "DISPLAY"`)
+	fmt.Fprintln(bw, `KEYWORD "COLOR_REP"`)
+	fmt.Fprintln(bw, `COLOR_REP "RGB"`)
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, `KEYWORD "RGB_I"`)
+	fmt.Fprintf(bw, "NUMBER_OF_FIELDS %d\n", len(calFields))
+	fmt.Fprintln(bw, "BEGIN_DATA_FORMAT")
+	fmt.Fprintln(bw, strings.Join(calFields, " "))
+	fmt.Fprintln(bw, "END_DATA_FORMAT")
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "NUMBER_OF_SETS %d\n", t.Size())
+	fmt.Fprintln(bw, "BEGIN_DATA")
+	for i := range t.Size() {
+		x := float64(i) / float64(t.Size()-1)
+		fmt.Fprintf(bw, "%.7f %.7f %.7f %.7f\n", x, t.Curves[0][i], t.Curves[1][i], t.Curves[2][i])
+	}
+	fmt.Fprintln(bw, "END_DATA")
+	return bw.Flush()
+}
+
+func indexOf(values []string, s string) int {
+	for i, v := range values {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
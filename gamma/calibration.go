@@ -0,0 +1,62 @@
+package gamma
+
+import (
+	"path/filepath"
+
+	"github.com/jipaix/lumos/display"
+	"github.com/jipaix/lumos/icc"
+)
+
+// Calibration layer values besides a profile path
+const (
+	// CalibrationActive loads the video card gamma table of the profile
+	// Windows uses for the display
+	CalibrationActive = "active"
+	// CalibrationNone uses a linear base instead of the active profile
+	CalibrationNone = "none"
+)
+
+// LoadCalibration reads a ramp file (.cube, .csv, .cal, or the vcgt tag of
+// an .icc/.icm profile) as a calibration layer
+func LoadCalibration(path string) (*CalibrationLayer, error) {
+	t, description, err := ReadTable(path)
+	if err != nil {
+		return nil, err
+	}
+
+	source := filepath.Base(path)
+	if description != "" && description != source {
+		source += " (" + description + ")"
+	}
+	return &CalibrationLayer{Source: source, Ramp: t.Ramp()}, nil
+}
+
+// ActiveCalibration loads the calibration curves of the profile Windows uses
+// for a display. It returns nil without an error when that profile has no
+// curves or they are linear.
+func ActiveCalibration(d display.Display) (*CalibrationLayer, error) {
+	path, err := activeProfilePath(d.DeviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := icc.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.VCGT == nil || p.VCGT.IsLinear() {
+		return nil, nil
+	}
+	return &CalibrationLayer{Source: filepath.Base(path) + " (active profile)", Ramp: Table{Curves: p.VCGT.Curves}.Ramp()}, nil
+}
+
+// LinearCalibration is a calibration layer that keeps the ramp linear, so the
+// active profile isn't picked up as the base
+func LinearCalibration() *CalibrationLayer {
+	l := &CalibrationLayer{Source: CalibrationNone}
+	for i := range 256 {
+		v := uint16(i * 65535 / 255)
+		l.Ramp.Red[i], l.Ramp.Green[i], l.Ramp.Blue[i] = v, v, v
+	}
+	return l
+}
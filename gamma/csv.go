@@ -0,0 +1,147 @@
+package gamma
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCSV reads index,red,green,blue rows, with or without a header row.
+// Rows are placed by their index, which must cover 0 to rows-1. The value
+// scale comes from the header when the color columns carry a bit depth,
+// e.g. red16 or red8. Otherwise values written with a decimal point are
+// taken as 0-1, and integers as 8 bit when none is above 255 and as 16 bit
+// otherwise.
+func ReadCSV(r io.Reader) (Table, error) {
+	var t Table
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return t, err
+	}
+
+	scale := 0.0
+	if len(records) > 0 && !isNumber(records[0][0]) {
+		if scale, err = headerScale(records[0]); err != nil {
+			return t, err
+		}
+		records = records[1:]
+	}
+	if len(records) == 0 {
+		return t, errors.New("no ramp rows found")
+	}
+
+	for c := range t.Curves {
+		t.Curves[c] = make([]float64, len(records))
+	}
+	seen := make([]bool, len(records))
+	decimal := false
+	max := 0.0
+	for i, rec := range records {
+		if len(rec) != 4 {
+			return t, fmt.Errorf("row %d: expected index,red,green,blue, got %d columns", i+1, len(rec))
+		}
+		index, err := strconv.Atoi(strings.TrimSpace(rec[0]))
+		if err != nil || index < 0 || index >= len(records) {
+			return t, fmt.Errorf("row %d: index %s is not between 0 and %d", i+1, rec[0], len(records)-1)
+		}
+		if seen[index] {
+			return t, fmt.Errorf("row %d: duplicate index %d", i+1, index)
+		}
+		seen[index] = true
+
+		values, err := parseFloats(strings.Join(rec[1:], ","))
+		if err != nil {
+			return t, fmt.Errorf("row %d: %v", i+1, err)
+		}
+		for c, v := range values {
+			t.Curves[c][index] = v
+			max = maxFloat(max, v)
+			decimal = decimal || strings.ContainsAny(rec[1+c], ".eE")
+		}
+	}
+
+	if scale == 0 {
+		switch {
+		case decimal:
+			scale = 1
+		case max <= 255:
+			scale = 255
+		default:
+			scale = 65535
+		}
+	}
+	for _, curve := range t.Curves {
+		for i := range curve {
+			curve[i] /= scale
+		}
+	}
+	return t, t.validate()
+}
+
+// headerScale returns the maximum value given by the bit depth of the color
+// columns of a header row, or 0 when the header doesn't say
+func headerScale(header []string) (float64, error) {
+	if len(header) != 4 {
+		return 0, fmt.Errorf("header: expected index,red,green,blue, got %d columns", len(header))
+	}
+
+	scale := 0.0
+	for i, name := range header[1:] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		color := strings.TrimRight(name, "0123456789")
+		if color != []string{"red", "green", "blue"}[i] && color != []string{"r", "g", "b"}[i] {
+			return 0, fmt.Errorf("header: unexpected column %q, expected index,red,green,blue", header[i+1])
+		}
+
+		var s float64
+		switch depth := name[len(color):]; depth {
+		case "":
+		case "8":
+			s = 255
+		case "16":
+			s = 65535
+		default:
+			return 0, fmt.Errorf("header: unsupported bit depth %s in column %q, use 8 or 16", depth, header[i+1])
+		}
+		if i > 0 && s != scale {
+			return 0, errors.New("header: color columns have different bit depths")
+		}
+		scale = s
+	}
+	return scale, nil
+}
+
+// WriteCSV writes index,red16,green16,blue16 rows with 16 bit values
+func WriteCSV(w io.Writer, t Table) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"index", "red16", "green16", "blue16"})
+	for i := range t.Size() {
+		row := []string{strconv.Itoa(i)}
+		for _, curve := range t.Curves {
+			row = append(row, strconv.Itoa(int(curve[i]*65535+0.5)))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
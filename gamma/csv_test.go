@@ -0,0 +1,117 @@
+package gamma
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want [3][]float64
+	}{
+		{
+			name: "0-1 values",
+			csv:  "0,0,0,0.1\n1,0.5,0.25,0.55\n2,1,0.5,1\n",
+			want: [3][]float64{{0, 0.5, 1}, {0, 0.25, 0.5}, {0.1, 0.55, 1}},
+		},
+		{
+			name: "0-1 values that are all whole",
+			csv:  "index,red,green,blue\n0,0.0,0.0,0.0\n1,1.0,1.0,1.0\n",
+			want: [3][]float64{{0, 1}, {0, 1}, {0, 1}},
+		},
+		{
+			name: "8 bit",
+			csv:  "0,0,0,0\n1,51,102,255\n",
+			want: [3][]float64{{0, 0.2}, {0, 0.4}, {0, 1}},
+		},
+		{
+			name: "8 bit black and white",
+			csv:  "0,0,0,0\n1,1,1,1\n",
+			want: [3][]float64{{0, 1.0 / 255}, {0, 1.0 / 255}, {0, 1.0 / 255}},
+		},
+		{
+			name: "16 bit",
+			csv:  "# From a calibration tool\nindex,red,green,blue\n0,0,0,0\n1,65535,32768,256\n",
+			want: [3][]float64{{0, 1}, {0, 32768.0 / 65535}, {0, 256.0 / 65535}},
+		},
+		{
+			name: "16 bit from the header, dark ramp",
+			csv:  "index,red16,green16,blue16\n0,0,0,0\n1,255,255,255\n",
+			want: [3][]float64{{0, 255.0 / 65535}, {0, 255.0 / 65535}, {0, 255.0 / 65535}},
+		},
+		{
+			name: "8 bit from the header",
+			csv:  "i, R8, G8, B8\n0,0,0,0\n1,255,255,255\n",
+			want: [3][]float64{{0, 1}, {0, 1}, {0, 1}},
+		},
+		{
+			name: "rows out of order",
+			csv:  "2,1,1,1\n0,0,0,0\n1,0.5,0.4,0.3\n",
+			want: [3][]float64{{0, 0.5, 1}, {0, 0.4, 1}, {0, 0.3, 1}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ReadCSV(strings.NewReader(tt.csv))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for c := range tt.want {
+			if len(got.Curves[c]) != len(tt.want[c]) {
+				t.Errorf("%s: channel %d = %v, want %v", tt.name, c, got.Curves[c], tt.want[c])
+				continue
+			}
+			for i := range tt.want[c] {
+				if d := got.Curves[c][i] - tt.want[c][i]; d > 1e-9 || d < -1e-9 {
+					t.Errorf("%s: channel %d = %v, want %v", tt.name, c, got.Curves[c], tt.want[c])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestReadCSVInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		err  string
+	}{
+		{"empty", "", "no ramp rows"},
+		{"header only", "index,red,green,blue\n", "no ramp rows"},
+		{"columns", "0,0,0\n1,1,1\n", "expected index,red,green,blue"},
+		{"header columns", "index,red,green\n0,0,0,0\n", "header"},
+		{"header names", "index,blue,green,red\n0,0,0,0\n1,1,1,1\n", "unexpected column"},
+		{"header depth", "index,red10,green10,blue10\n0,0,0,0\n", "bit depth 10"},
+		{"mixed depths", "index,red8,green16,blue16\n0,0,0,0\n", "different bit depths"},
+		{"index", "0,0,0,0\n2,1,1,1\n", "index 2 is not between 0 and 1"},
+		{"fractional index", "0,0,0,0\n0.5,1,1,1\n", "index 0.5"},
+		{"duplicate index", "0,0,0,0\n0,1,1,1\n", "duplicate index 0"},
+		{"value", "0,0,0,0\n1,1,x,1\n", "invalid number"},
+		{"above 16 bit", "0,0,0,0\n1,70000,1,1\n", "outside 0-1"},
+		{"above 8 bit from the header", "index,red8,green8,blue8\n0,0,0,0\n1,256,1,1\n", "outside 0-1"},
+		{"above 1", "0,0,0,0\n1,1.5,1,1\n", "outside 0-1"},
+		{"negative", "0,0,0,0\n1,-1,1,1\n", "outside 0-1"},
+		{"single row", "0,0,0,0\n", "at least 2"},
+	}
+	for _, tt := range tests {
+		_, err := ReadCSV(strings.NewReader(tt.csv))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: ReadCSV = %v, want an error containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, Table{Curves: [3][]float64{{0, 1}, {0, 0.5}, {0.25, 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "index,red16,green16,blue16\n0,0,0,16384\n1,65535,32768,65535\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV = %q, want %q", buf.String(), want)
+	}
+}
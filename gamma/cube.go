@@ -0,0 +1,100 @@
+package gamma
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCube reads a 1D LUT in the cube format. Inputs outside the domain
+// are not supported, so DOMAIN_MIN and DOMAIN_MAX must be 0 and 1.
+func ReadCube(r io.Reader) (Table, string, error) {
+	var t Table
+	var title string
+	size := 0
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		switch fields[0] {
+		case "TITLE":
+			title = strings.Trim(strings.TrimSpace(strings.TrimPrefix(text, "TITLE")), `"`)
+			continue
+		case "LUT_1D_SIZE":
+			if len(fields) != 2 {
+				return t, "", fmt.Errorf("line %d: invalid LUT_1D_SIZE", line)
+			}
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 2 || n > 65536 {
+				return t, "", fmt.Errorf("line %d: invalid LUT_1D_SIZE %s", line, fields[1])
+			}
+			size = n
+			continue
+		case "LUT_3D_SIZE":
+			return t, "", errors.New("3D LUTs can't be used as a gamma ramp, export a 1D LUT")
+		case "DOMAIN_MIN", "DOMAIN_MAX":
+			want := 0.0
+			if fields[0] == "DOMAIN_MAX" {
+				want = 1
+			}
+			if len(fields) != 4 || !isFloat(fields[1], want) || !isFloat(fields[2], want) || !isFloat(fields[3], want) {
+				return t, "", fmt.Errorf("line %d: only a %s of %g %g %g is supported", line, fields[0], want, want, want)
+			}
+			continue
+		case "LUT_1D_INPUT_RANGE":
+			if len(fields) != 3 || !isFloat(fields[1], 0) || !isFloat(fields[2], 1) {
+				return t, "", fmt.Errorf("line %d: only a LUT_1D_INPUT_RANGE of 0 1 is supported", line)
+			}
+			continue
+		}
+
+		values, err := parseFloats(strings.Join(fields, ","))
+		if err != nil || len(values) != 3 {
+			return t, "", fmt.Errorf("line %d: expected three values, got %q", line, text)
+		}
+		for c := range t.Curves {
+			t.Curves[c] = append(t.Curves[c], values[c])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return t, "", err
+	}
+
+	if size == 0 {
+		return t, "", errors.New("missing LUT_1D_SIZE")
+	}
+	if t.Size() != size {
+		return t, "", fmt.Errorf("LUT_1D_SIZE is %d but the file has %d entries", size, t.Size())
+	}
+	return t, title, t.validate()
+}
+
+// WriteCube writes a 1D LUT in the cube format
+func WriteCube(w io.Writer, t Table, title string) error {
+	bw := bufio.NewWriter(w)
+	if title != "" {
+		fmt.Fprintf(bw, "TITLE \"%s\"\n", strings.ReplaceAll(title, `"`, "'"))
+	}
+	fmt.Fprintf(bw, "LUT_1D_SIZE %d\n", t.Size())
+	fmt.Fprintln(bw, "DOMAIN_MIN 0.0 0.0 0.0")
+	fmt.Fprintln(bw, "DOMAIN_MAX 1.0 1.0 1.0")
+	fmt.Fprintln(bw)
+	for i := range t.Size() {
+		fmt.Fprintf(bw, "%.6f %.6f %.6f\n", t.Curves[0][i], t.Curves[1][i], t.Curves[2][i])
+	}
+	return bw.Flush()
+}
+
+// isFloat reports whether s is the number want
+func isFloat(s string, want float64) bool {
+	v, err := strconv.ParseFloat(s, 64)
+	return err == nil && v == want
+}
@@ -2,31 +2,11 @@ package gamma
 
 import (
 	"errors"
-	"syscall"
-	"unsafe"
-)
+	"fmt"
 
-var (
-	user32                 = syscall.NewLazyDLL("user32.dll")
-	gdi32                  = syscall.NewLazyDLL("gdi32.dll")
-	procGetDC              = user32.NewProc("GetDC")
-	procReleaseDC          = user32.NewProc("ReleaseDC")
-	procCreateDC           = gdi32.NewProc("CreateDCW")
-	procDeleteDC           = gdi32.NewProc("DeleteDC")
-	procSetGammaRamp       = gdi32.NewProc("SetDeviceGammaRamp")
-	procEnumDisplayDevices = user32.NewProc("EnumDisplayDevicesW")
+	"github.com/jipaix/lumos/display"
 )
 
-// DISPLAY_DEVICE structure
-type displayDevice struct {
-	cb           uint32
-	DeviceName   [32]uint16
-	DeviceString [128]uint16
-	StateFlags   uint32
-	DeviceID     [128]uint16
-	DeviceKey    [128]uint16
-}
-
 // GammaRamp represents the gamma ramp structure
 type GammaRamp struct {
 	Red   [256]uint16
@@ -34,152 +14,83 @@ type GammaRamp struct {
 	Blue  [256]uint16
 }
 
-// SetGamma sets the screen gamma with brightness (0-100) for ALL displays
-func SetGamma(brightness int) error {
-	if brightness < 0 || brightness > 100 {
-		return errors.New("brightness must be between 0 and 100")
-	}
-
-	// Calculate factors based on PowerShell logic
-	brightnessFactor := 0.5 + (float64(brightness)/100.0)*0.5
-	contrast := 120.0 - (0.2 * float64(brightness))
-	contrastFactor := contrast / 100.0
-
-	// Create gamma ramp
-	var ramp GammaRamp
-
-	for i := range 256 {
-		x := float64(i) / 255.0
-
-		// Apply contrast adjustment
-		x = ((x - 0.5) * contrastFactor) + 0.5
+// Result reports how a display responded to a gamma change
+type Result struct {
+	Display display.Display
+	Action  string // e.g. "gamma 75%"
+	Err     error
+	Warning error // Set when the ramp was changed before writing, e.g. a *RangeError
+}
 
-		// Clamp to [0, 1]
-		if x < 0 {
-			x = 0
+// Failed returns the errors of the failed results joined together, or nil
+// when every display was changed
+func Failed(results []Result) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Display.Label(), r.Err))
 		}
-		if x > 1 {
-			x = 1
-		}
-
-		// Apply brightness and convert to 16-bit value
-		val := uint16(x * 65535 * brightnessFactor)
-
-		ramp.Red[i] = val
-		ramp.Green[i] = val
-		ramp.Blue[i] = val
 	}
-
-	// Apply the gamma ramp to ALL displays
-	return setDeviceGammaRampForAllDisplays(&ramp)
+	return errors.Join(errs...)
 }
 
-// setDeviceGammaRampForAllDisplays sets gamma ramp for all active displays
-func setDeviceGammaRampForAllDisplays(ramp *GammaRamp) error {
-	displays := getActiveDisplayDevices()
-	var lastError error
-	successCount := 0
-
-	// Convert "DISPLAY" once outside the loop since it's constant
-	displayPtr, err := syscall.UTF16PtrFromString("DISPLAY")
-	if err != nil {
-		return errors.New("failed to convert DISPLAY constant")
+// SetGamma sets the screen gamma with brightness (0-100) on the selected
+// displays, replacing the whole ramp; use a Pipeline to keep other layers.
+// Each display gets its own result; the error is only set when no display
+// could be tried.
+func SetGamma(brightness int, sel display.Selector) ([]Result, error) {
+	if brightness < 0 || brightness > 100 {
+		return nil, errors.New("brightness must be between 0 and 100")
 	}
 
-	for _, deviceName := range displays {
-		// Use UTF16PtrFromString for deviceName with proper error handling
-		deviceNamePtr, err := syscall.UTF16PtrFromString(deviceName)
-		if err != nil {
-			// Skip this monitor if conversion fails
-			continue
-		}
-
-		hdc, _, _ := procCreateDC.Call(
-			uintptr(unsafe.Pointer(displayPtr)),
-			uintptr(unsafe.Pointer(deviceNamePtr)),
-			0, 0,
-		)
+	ramp := BrightnessRamp(brightness)
+	return SetRamp(&ramp, fmt.Sprintf("gamma %d%%", brightness), sel)
+}
 
-		if hdc != 0 {
-			err := setGammaWithHDC(hdc, ramp)
-			procDeleteDC.Call(hdc) // Clean up DC
+// BrightnessRamp returns the gamma ramp for a brightness (0-100)
+func BrightnessRamp(brightness int) GammaRamp {
+	p := Pipeline{Brightness: &BrightnessLayer{Percent: brightness}}
+	ramp, _ := p.Ramp() // Brightness alone never falls below the safety minimum
+	return ramp
+}
 
-			if err != nil {
-				lastError = err
-			} else {
-				successCount++
-			}
-		}
+// SetRamp applies a gamma ramp to the selected displays. action describes
+// the change in the results. A ramp Windows would reject isn't written, the
+// results explain why with a *RangeError.
+func SetRamp(ramp *GammaRamp, action string, sel display.Selector) ([]Result, error) {
+	displays, err := sel.Resolve()
+	if err != nil {
+		return nil, err
 	}
-
-	// If no specific displays worked, fallback to primary display
-	if successCount == 0 {
-		hdc, _, _ := procGetDC.Call(0)
-		if hdc == 0 {
-			if lastError != nil {
-				return lastError
-			}
-			return errors.New("failed to set gamma on any display")
-		}
-		defer procReleaseDC.Call(0, hdc)
-
-		return setGammaWithHDC(hdc, ramp)
+	if len(displays) == 0 {
+		return nil, errors.New("no displays found")
 	}
 
-	if lastError != nil && successCount == 0 {
-		return lastError
+	gammaRange, _ := GammaRange()
+	var rangeErr error
+	if violations := Validate(ramp, gammaRange); len(violations) > 0 {
+		rangeErr = &RangeError{Range: gammaRange, Violations: violations}
 	}
 
-	return nil
-}
-
-// setGammaWithHDC sets gamma ramp using a specific HDC
-func setGammaWithHDC(hdc uintptr, ramp *GammaRamp) error {
-	ret, _, err := procSetGammaRamp.Call(hdc, uintptr(unsafe.Pointer(&ramp.Red[0])))
-	if ret == 0 {
-		return errors.New("failed to set gamma ramp: " + err.Error())
-	}
-	return nil
-}
-
-// getActiveDisplayDevices returns a list of active display device names
-func getActiveDisplayDevices() []string {
-	var devices []string
-	var dd displayDevice
-	dd.cb = uint32(unsafe.Sizeof(dd))
-
-	index := 0
-	for {
-		// EnumDisplayDevices with nullptr for device name to enumerate all devices
-		ret, _, _ := procEnumDisplayDevices.Call(
-			0,
-			uintptr(index),
-			uintptr(unsafe.Pointer(&dd)),
-			0,
-		)
-
-		if ret == 0 {
-			break
+	results := make([]Result, 0, len(displays))
+	for _, d := range displays {
+		r := Result{Display: d, Action: action, Err: rangeErr}
+		if rangeErr == nil {
+			r.Err = setDeviceGammaRamp(d.DeviceName, ramp)
 		}
-
-		// Check if this is an active display device (attached to desktop)
-		active := (dd.StateFlags & 0x00000001) != 0 // DISPLAY_DEVICE_ACTIVE
-
-		// Only include active displays that are attached to desktop
-		if active {
-			deviceName := syscall.UTF16ToString(dd.DeviceName[:])
-			devices = append(devices, deviceName)
-		}
-
-		index++
+		results = append(results, r)
 	}
+	return results, nil
+}
 
-	return devices
+// GetRamp returns the current gamma ramp of a display
+func GetRamp(d display.Display) (GammaRamp, error) {
+	return getDeviceGammaRamp(d.DeviceName)
 }
 
-// ResetGamma resets the gamma to default (brightness 100) for all displays
-func ResetGamma() error {
-	return SetGamma(100)
+// ResetGamma resets the gamma to default (brightness 100) on the selected displays
+func ResetGamma(sel display.Selector) ([]Result, error) {
+	return SetGamma(100, sel)
 }
 
 // GetDisplayCount returns the number of available active displays
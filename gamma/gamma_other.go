@@ -0,0 +1,26 @@
+//go:build !windows
+
+package gamma
+
+import "errors"
+
+// setDeviceGammaRamp sets the gamma ramp of one display by GDI device name
+func setDeviceGammaRamp(deviceName string, ramp *GammaRamp) error {
+	return errors.New("gamma control is only supported on Windows")
+}
+
+// getDeviceGammaRamp reads the gamma ramp of one display by GDI device name
+func getDeviceGammaRamp(deviceName string) (GammaRamp, error) {
+	return GammaRamp{}, errors.New("gamma control is only supported on Windows")
+}
+
+// activeProfilePath returns the file of the ICC profile Windows uses for a
+// display by GDI device name
+func activeProfilePath(deviceName string) (string, error) {
+	return "", errors.New("color profiles are only supported on Windows")
+}
+
+// getActiveDisplayDevices returns a list of active display device names
+func getActiveDisplayDevices() []string {
+	return nil
+}
@@ -0,0 +1,141 @@
+package gamma
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32                 = syscall.NewLazyDLL("user32.dll")
+	gdi32                  = syscall.NewLazyDLL("gdi32.dll")
+	procCreateDC           = gdi32.NewProc("CreateDCW")
+	procDeleteDC           = gdi32.NewProc("DeleteDC")
+	procSetGammaRamp       = gdi32.NewProc("SetDeviceGammaRamp")
+	procGetGammaRamp       = gdi32.NewProc("GetDeviceGammaRamp")
+	procGetICMProfile      = gdi32.NewProc("GetICMProfileW")
+	procEnumDisplayDevices = user32.NewProc("EnumDisplayDevicesW")
+)
+
+// DISPLAY_DEVICE structure
+type displayDevice struct {
+	cb           uint32
+	DeviceName   [32]uint16
+	DeviceString [128]uint16
+	StateFlags   uint32
+	DeviceID     [128]uint16
+	DeviceKey    [128]uint16
+}
+
+// setDeviceGammaRamp sets the gamma ramp of one display by GDI device name
+func setDeviceGammaRamp(deviceName string, ramp *GammaRamp) error {
+	hdc, err := createDisplayDC(deviceName)
+	if err != nil {
+		return err
+	}
+	defer procDeleteDC.Call(hdc) // Clean up DC
+
+	return setGammaWithHDC(hdc, ramp)
+}
+
+// getDeviceGammaRamp reads the gamma ramp of one display by GDI device name
+func getDeviceGammaRamp(deviceName string) (GammaRamp, error) {
+	var ramp GammaRamp
+
+	hdc, err := createDisplayDC(deviceName)
+	if err != nil {
+		return ramp, err
+	}
+	defer procDeleteDC.Call(hdc)
+
+	ret, _, err := procGetGammaRamp.Call(hdc, uintptr(unsafe.Pointer(&ramp.Red[0])))
+	if ret == 0 {
+		return ramp, errors.New("failed to get gamma ramp: " + err.Error())
+	}
+	return ramp, nil
+}
+
+// activeProfilePath returns the file of the ICC profile Windows uses for a
+// display by GDI device name
+func activeProfilePath(deviceName string) (string, error) {
+	hdc, err := createDisplayDC(deviceName)
+	if err != nil {
+		return "", err
+	}
+	defer procDeleteDC.Call(hdc)
+
+	var buf [260]uint16 // MAX_PATH
+	size := uint32(len(buf))
+	ret, _, err := procGetICMProfile.Call(hdc, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&buf[0])))
+	if ret == 0 {
+		return "", errors.New("failed to get color profile: " + err.Error())
+	}
+	return syscall.UTF16ToString(buf[:]), nil
+}
+
+// createDisplayDC creates a device context for a display, to be released
+// with DeleteDC
+func createDisplayDC(deviceName string) (uintptr, error) {
+	displayPtr, err := syscall.UTF16PtrFromString("DISPLAY")
+	if err != nil {
+		return 0, errors.New("failed to convert DISPLAY constant")
+	}
+	deviceNamePtr, err := syscall.UTF16PtrFromString(deviceName)
+	if err != nil {
+		return 0, errors.New("invalid device name: " + deviceName)
+	}
+
+	hdc, _, err := procCreateDC.Call(
+		uintptr(unsafe.Pointer(displayPtr)),
+		uintptr(unsafe.Pointer(deviceNamePtr)),
+		0, 0,
+	)
+	if hdc == 0 {
+		return 0, errors.New("failed to create device context: " + err.Error())
+	}
+	return hdc, nil
+}
+
+// setGammaWithHDC sets gamma ramp using a specific HDC
+func setGammaWithHDC(hdc uintptr, ramp *GammaRamp) error {
+	ret, _, err := procSetGammaRamp.Call(hdc, uintptr(unsafe.Pointer(&ramp.Red[0])))
+	if ret == 0 {
+		return errors.New("failed to set gamma ramp: " + err.Error())
+	}
+	return nil
+}
+
+// getActiveDisplayDevices returns a list of active display device names
+func getActiveDisplayDevices() []string {
+	var devices []string
+	var dd displayDevice
+	dd.cb = uint32(unsafe.Sizeof(dd))
+
+	index := 0
+	for {
+		// EnumDisplayDevices with nullptr for device name to enumerate all devices
+		ret, _, _ := procEnumDisplayDevices.Call(
+			0,
+			uintptr(index),
+			uintptr(unsafe.Pointer(&dd)),
+			0,
+		)
+
+		if ret == 0 {
+			break
+		}
+
+		// Check if this is an active display device (attached to desktop)
+		active := (dd.StateFlags & 0x00000001) != 0 // DISPLAY_DEVICE_ACTIVE
+
+		// Only include active displays that are attached to desktop
+		if active {
+			deviceName := syscall.UTF16ToString(dd.DeviceName[:])
+			devices = append(devices, deviceName)
+		}
+
+		index++
+	}
+
+	return devices
+}
@@ -0,0 +1,27 @@
+package gamma
+
+import (
+	"image"
+	"image/color"
+)
+
+// ApplyToImage returns a copy of img as a display would show it with the
+// ramp loaded: each 8 bit channel value is looked up in the ramp like the
+// video card does. Alpha is kept.
+func ApplyToImage(img image.Image, r *GammaRamp) *image.NRGBA {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r.Red[c.R] >> 8),
+				G: uint8(r.Green[c.G] >> 8),
+				B: uint8(r.Blue[c.B] >> 8),
+				A: c.A,
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,667 @@
+package gamma
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/display"
+)
+
+// Channel is a color channel of a gamma ramp
+type Channel int
+
+const (
+	Red Channel = iota
+	Green
+	Blue
+)
+
+// Layer is one stage of a gamma pipeline. Apply maps a channel value in
+// [0, 1] to a new value, usually also in [0, 1].
+type Layer interface {
+	Name() string
+	Apply(c Channel, v float64) float64
+	String() string // Parameters, e.g. "5000K"
+}
+
+// Layer names in pipeline order
+const (
+	LayerCalibration = "calibration"
+	LayerCurve       = "curve"
+	LayerTemperature = "temperature"
+	LayerBrightness  = "brightness"
+	LayerClamp       = "clamp"
+)
+
+// LayerNames lists the pipeline stages in the order they are applied
+var LayerNames = []string{LayerCalibration, LayerCurve, LayerTemperature, LayerBrightness, LayerClamp}
+
+// MinPeakLuminance is the lowest relative luminance the pipeline may map
+// full white to, so a bad combination of layers can't black out the screen.
+// The ramp is decoded with the same 2.2 power the temperature gains assume.
+const MinPeakLuminance = 0.1
+
+// Pipeline composes the gamma ramp of a display from layers: calibration
+// base, user curve, temperature, brightness/contrast and a safety clamp.
+// Nil layers are skipped. Changing one layer keeps the others.
+type Pipeline struct {
+	Calibration *CalibrationLayer `json:"calibration,omitempty"`
+	Curve       *CurveLayer       `json:"curve,omitempty"`
+	Temperature *TemperatureLayer `json:"temperature,omitempty"`
+	Brightness  *BrightnessLayer  `json:"brightness,omitempty"`
+	Clamp       *ClampLayer       `json:"clamp,omitempty"`
+}
+
+// Layers returns the set layers in pipeline order
+func (p *Pipeline) Layers() []Layer {
+	var layers []Layer
+	if p.Calibration != nil {
+		layers = append(layers, p.Calibration)
+	}
+	if p.Curve != nil {
+		layers = append(layers, p.Curve)
+	}
+	if p.Temperature != nil {
+		layers = append(layers, p.Temperature)
+	}
+	if p.Brightness != nil {
+		layers = append(layers, p.Brightness)
+	}
+	if p.Clamp != nil {
+		layers = append(layers, p.Clamp)
+	}
+	return layers
+}
+
+// Eval runs a channel value in [0, 1] through every layer
+func (p *Pipeline) Eval(c Channel, x float64) float64 {
+	for _, l := range p.Layers() {
+		x = l.Apply(c, x)
+	}
+	return clamp01(x)
+}
+
+// Ramp computes the gamma ramp. It fails when the result would make full
+// white darker than MinPeakLuminance.
+func (p *Pipeline) Ramp() (GammaRamp, error) {
+	ramp := p.ramp()
+	peak := 0.2126*luminance(ramp.Red[255]) + 0.7152*luminance(ramp.Green[255]) + 0.0722*luminance(ramp.Blue[255])
+	if peak < MinPeakLuminance {
+		return ramp, fmt.Errorf("the gamma pipeline maps white to %.0f%% luminance, below the %.0f%% safety minimum", peak*100, MinPeakLuminance*100)
+	}
+	return ramp, nil
+}
+
+// ramp computes the gamma ramp without the safety check
+func (p *Pipeline) ramp() GammaRamp {
+	var ramp GammaRamp
+	for c, ch := range []*[256]uint16{&ramp.Red, &ramp.Green, &ramp.Blue} {
+		for i := range 256 {
+			ch[i] = uint16(math.Round(p.Eval(Channel(c), float64(i)/255) * 65535))
+		}
+	}
+	return ramp
+}
+
+// luminance decodes a ramp value to linear light
+func luminance(v uint16) float64 {
+	return math.Pow(float64(v)/65535, encodingGamma)
+}
+
+// SetLayer parses value and sets the named layer
+func (p *Pipeline) SetLayer(name, value string) error {
+	switch name {
+	case LayerCurve:
+		l, err := ParseCurve(value)
+		if err != nil {
+			return err
+		}
+		p.Curve = l
+	case LayerTemperature:
+		l, err := ParseTemperature(value)
+		if err != nil {
+			return err
+		}
+		p.Temperature = l
+	case LayerBrightness:
+		l, err := ParseBrightness(value)
+		if err != nil {
+			return err
+		}
+		p.Brightness = l
+	case LayerClamp:
+		l, err := ParseClamp(value)
+		if err != nil {
+			return err
+		}
+		p.Clamp = l
+	case LayerCalibration:
+		switch value {
+		case CalibrationNone:
+			p.Calibration = LinearCalibration()
+		case CalibrationActive:
+			return errors.New("the active profile depends on the display, use ActiveCalibration")
+		default:
+			l, err := LoadCalibration(value)
+			if err != nil {
+				return err
+			}
+			p.Calibration = l
+		}
+	default:
+		return fmt.Errorf("unknown gamma layer: %s (must be one of %s)", name, strings.Join(LayerNames, ", "))
+	}
+	return nil
+}
+
+// ResetLayer removes the named layer
+func (p *Pipeline) ResetLayer(name string) error {
+	switch name {
+	case LayerCalibration:
+		p.Calibration = nil
+	case LayerCurve:
+		p.Curve = nil
+	case LayerTemperature:
+		p.Temperature = nil
+	case LayerBrightness:
+		p.Brightness = nil
+	case LayerClamp:
+		p.Clamp = nil
+	default:
+		return fmt.Errorf("unknown gamma layer: %s (must be one of %s)", name, strings.Join(LayerNames, ", "))
+	}
+	return nil
+}
+
+// CalibrationLayer is a per-channel base ramp, e.g. from a calibration
+// profile. Values between entries are interpolated linearly.
+type CalibrationLayer struct {
+	Source string    `json:"source,omitempty"` // Where the ramp came from
+	Ramp   GammaRamp `json:"ramp"`
+}
+
+func (l *CalibrationLayer) Name() string { return LayerCalibration }
+
+func (l *CalibrationLayer) String() string {
+	if l.Source != "" {
+		return l.Source
+	}
+	return "custom ramp"
+}
+
+func (l *CalibrationLayer) Apply(c Channel, v float64) float64 {
+	ch := &l.Ramp.Red
+	switch c {
+	case Green:
+		ch = &l.Ramp.Green
+	case Blue:
+		ch = &l.Ramp.Blue
+	}
+
+	pos := clamp01(v) * 255
+	i := int(pos)
+	if i >= 255 {
+		return float64(ch[255]) / 65535
+	}
+	frac := pos - float64(i)
+	return (float64(ch[i])*(1-frac) + float64(ch[i+1])*frac) / 65535
+}
+
+// CurveLayer is a tone curve per channel, either a power curve or control
+// points interpolated with a monotone cubic spline. A gamma above 1
+// brightens midtones, below 1 darkens them.
+type CurveLayer struct {
+	Gamma  []float64      `json:"gamma,omitempty"`  // Red, green, blue
+	Points [][]CurvePoint `json:"points,omitempty"` // Red, green, blue; nil channels use Gamma
+
+	splines [3]*monotoneSpline
+}
+
+// curveChannels maps the channel prefixes of per channel control points
+var curveChannels = map[string]Channel{"r": Red, "red": Red, "g": Green, "green": Green, "b": Blue, "blue": Blue}
+
+// ParseCurve parses one gamma value for all channels ("1.1"), one per
+// channel ("1.0,1.1,0.9"), control points for all channels
+// ("0:0,0.25:0.2,0.75:0.85,1:1") or per channel ("r=0:0,1:0.9;b=0:0.05,1:1")
+func ParseCurve(s string) (*CurveLayer, error) {
+	if strings.Contains(s, ":") {
+		return parseCurvePoints(s)
+	}
+
+	values, err := parseFloats(s)
+	if err != nil || (len(values) != 1 && len(values) != 3) {
+		return nil, fmt.Errorf("invalid curve: %s (use a gamma like 1.1, one per channel like 1.0,1.1,0.9, or control points like 0:0,0.5:0.45,1:1)", s)
+	}
+	if len(values) == 1 {
+		values = []float64{values[0], values[0], values[0]}
+	}
+
+	for _, g := range values {
+		if g < 0.1 || g > 10 {
+			return nil, fmt.Errorf("curve gamma must be between 0.1 and 10, got %g", g)
+		}
+	}
+	return &CurveLayer{Gamma: values}, nil
+}
+
+// parseCurvePoints parses control points for all channels or per channel
+func parseCurvePoints(s string) (*CurveLayer, error) {
+	l := &CurveLayer{Points: make([][]CurvePoint, 3)}
+
+	if !strings.Contains(s, "=") {
+		points, err := ParseCurvePoints(s)
+		if err != nil {
+			return nil, err
+		}
+		l.Points[Red], l.Points[Green], l.Points[Blue] = points, points, points
+		return l, nil
+	}
+
+	for _, spec := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		c, known := curveChannels[strings.ToLower(strings.TrimSpace(name))]
+		if !ok || !known {
+			return nil, fmt.Errorf("invalid curve channel: %s (use r=, g= or b= followed by control points)", spec)
+		}
+		if l.Points[c] != nil {
+			return nil, fmt.Errorf("curve channel %s given twice", name)
+		}
+		points, err := ParseCurvePoints(value)
+		if err != nil {
+			return nil, err
+		}
+		l.Points[c] = points
+	}
+	return l, nil
+}
+
+func (l *CurveLayer) Name() string { return LayerCurve }
+
+func (l *CurveLayer) String() string {
+	if len(l.Points) == 3 {
+		if pointsEqual(l.Points[Red], l.Points[Green]) && pointsEqual(l.Points[Green], l.Points[Blue]) && l.Points[Red] != nil {
+			return formatCurvePoints(l.Points[Red])
+		}
+		var specs []string
+		for c, prefix := range []string{"r", "g", "b"} {
+			if l.Points[c] != nil {
+				specs = append(specs, prefix+"="+formatCurvePoints(l.Points[c]))
+			}
+		}
+		return strings.Join(specs, ";")
+	}
+
+	if len(l.Gamma) != 3 {
+		return "linear"
+	}
+	if l.Gamma[0] == l.Gamma[1] && l.Gamma[1] == l.Gamma[2] {
+		return fmt.Sprintf("gamma %g", l.Gamma[0])
+	}
+	return fmt.Sprintf("gamma %g,%g,%g", l.Gamma[0], l.Gamma[1], l.Gamma[2])
+}
+
+func (l *CurveLayer) Apply(c Channel, v float64) float64 {
+	if len(l.Points) == 3 && l.Points[c] != nil {
+		if l.splines[c] == nil {
+			spline, err := newMonotoneSpline(l.Points[c])
+			if err != nil {
+				return v // Checked when parsed, only a hand-edited file gets here
+			}
+			l.splines[c] = spline
+		}
+		return l.splines[c].eval(clamp01(v))
+	}
+	if len(l.Gamma) == 3 {
+		return math.Pow(clamp01(v), 1/l.Gamma[c])
+	}
+	return v
+}
+
+// TemperatureLayer shifts the white point to a color temperature, or to a
+// white point given as chromaticity
+type TemperatureLayer struct {
+	Kelvin     int           `json:"kelvin,omitempty"`
+	Algorithm  string        `json:"algorithm,omitempty"`  // Empty for TemperatureHelland
+	WhitePoint *Chromaticity `json:"whitepoint,omitempty"` // Set instead of Kelvin
+}
+
+// Temperature range accepted by ParseTemperature
+const (
+	MinTemperature = 1000
+	MaxTemperature = 10000
+	// NeutralTemperature leaves the colors unchanged with TemperatureHelland
+	NeutralTemperature = 6500
+)
+
+// ParseTemperature parses a color temperature like "5000" or "5000K",
+// optionally followed by an algorithm like "5000K daylight", or a white point
+// like "0.3457,0.3585" or "D50"
+func ParseTemperature(s string) (*TemperatureLayer, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid temperature: %s (use kelvin like 5000)", s)
+	}
+
+	// A white point has a comma or names an illuminant
+	if _, ok := Illuminants[strings.ToUpper(fields[0])]; ok || strings.Contains(s, ",") {
+		c, err := ParseWhitePoint(s)
+		if err != nil {
+			return nil, err
+		}
+		return &TemperatureLayer{WhitePoint: &c}, nil
+	}
+
+	k, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(fields[0]), "K"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid temperature: %s (use kelvin like 5000)", s)
+	}
+	if k < MinTemperature || k > MaxTemperature {
+		return nil, fmt.Errorf("temperature must be between %dK and %dK, got %d", MinTemperature, MaxTemperature, k)
+	}
+
+	l := &TemperatureLayer{Kelvin: k}
+	if len(fields) == 2 {
+		switch algorithm := strings.ToLower(fields[1]); algorithm {
+		case TemperatureHelland:
+		case TemperatureBlackbody, TemperatureDaylight:
+			if _, err := temperatureChromaticity(algorithm, float64(k)); err != nil {
+				return nil, err
+			}
+			l.Algorithm = algorithm
+		default:
+			return nil, fmt.Errorf("invalid temperature algorithm: %s (must be 'helland', 'blackbody', or 'daylight')", fields[1])
+		}
+	}
+	return l, nil
+}
+
+func (l *TemperatureLayer) Name() string { return LayerTemperature }
+
+func (l *TemperatureLayer) String() string {
+	switch {
+	case l.WhitePoint != nil:
+		return l.WhitePoint.String()
+	case l.Algorithm != "":
+		return fmt.Sprintf("%dK %s", l.Kelvin, l.Algorithm)
+	default:
+		return fmt.Sprintf("%dK", l.Kelvin)
+	}
+}
+
+func (l *TemperatureLayer) Apply(c Channel, v float64) float64 {
+	return v * l.gains()[c]
+}
+
+// gains returns the channel multipliers of the layer
+func (l *TemperatureLayer) gains() [3]float64 {
+	c := l.WhitePoint
+	if c == nil {
+		if l.Algorithm == "" || l.Algorithm == TemperatureHelland {
+			return whitepoint(float64(l.Kelvin))
+		}
+		wp, err := temperatureChromaticity(l.Algorithm, float64(l.Kelvin))
+		if err != nil {
+			return [3]float64{1, 1, 1}
+		}
+		c = &wp
+	}
+	// The display is assumed to be calibrated to D65, so white points and
+	// the blackbody and daylight temperatures are absolute: daylight is
+	// neutral at 6504K, and the Planckian locus passes below D65, leaving
+	// 6500K blackbody slightly magenta
+	return c.gains(Illuminants["D65"])
+}
+
+// whitepoint returns the channel multipliers for a color temperature,
+// normalized so NeutralTemperature is (1, 1, 1). It uses Tanner Helland's
+// fit of the blackbody colors.
+func whitepoint(kelvin float64) [3]float64 {
+	rgb := blackbodyRGB(kelvin)
+	neutral := blackbodyRGB(NeutralTemperature)
+	for i := range rgb {
+		rgb[i] = clamp01(rgb[i] / neutral[i])
+	}
+	return rgb
+}
+
+func blackbodyRGB(kelvin float64) [3]float64 {
+	t := kelvin / 100
+	var r, g, b float64
+
+	if t <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+
+	switch {
+	case t >= 66:
+		b = 255
+	case t <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+
+	return [3]float64{clamp01(r / 255), clamp01(g / 255), clamp01(b / 255)}
+}
+
+// BrightnessLayer dims the ramp and raises contrast together, like the
+// original --gamma percentage. With a perceptual scale it sets the luminance
+// of white instead, no lower than MinPeakLuminance.
+type BrightnessLayer struct {
+	Percent int              `json:"percent"`         // 0-100
+	Scale   brightness.Scale `json:"scale,omitempty"` // Empty for linear
+}
+
+// ParseBrightness parses a brightness percentage like "75" or "75%",
+// optionally followed by a scale like "50% lightness"
+func ParseBrightness(s string) (*BrightnessLayer, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid brightness: %s (use a percentage like 75)", s)
+	}
+	p, err := strconv.Atoi(strings.TrimSuffix(fields[0], "%"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid brightness: %s (use a percentage like 75)", s)
+	}
+	if p < 0 || p > 100 {
+		return nil, errors.New("brightness must be between 0 and 100")
+	}
+
+	l := &BrightnessLayer{Percent: p}
+	if len(fields) == 2 {
+		scale, err := brightness.ParseScale(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		if !scale.IsLinear() {
+			l.Scale = scale
+		}
+	}
+	return l, nil
+}
+
+func (l *BrightnessLayer) Name() string { return LayerBrightness }
+
+func (l *BrightnessLayer) String() string {
+	if l.Scale.IsLinear() {
+		return fmt.Sprintf("%d%%", l.Percent)
+	}
+	return fmt.Sprintf("%d%% %s", l.Percent, l.Scale)
+}
+
+func (l *BrightnessLayer) Apply(c Channel, v float64) float64 {
+	if !l.Scale.IsLinear() {
+		// White is decoded with a 2.2 power, so encode its luminance
+		peak := math.Max(l.Scale.Level(float64(l.Percent)), MinPeakLuminance)
+		return v * math.Pow(peak, 1/encodingGamma)
+	}
+	percent := float64(l.Percent)
+
+	// Calculate factors based on PowerShell logic
+	brightnessFactor := 0.5 + (percent/100.0)*0.5
+	contrast := 120.0 - (0.2 * percent)
+	contrastFactor := contrast / 100.0
+
+	// Apply contrast adjustment, then brightness
+	v = clamp01(((v - 0.5) * contrastFactor) + 0.5)
+	return v * brightnessFactor
+}
+
+// ClampLayer limits the output range, e.g. to keep blacks from crushing
+type ClampLayer struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// ParseClamp parses an output range like "0.02,1"
+func ParseClamp(s string) (*ClampLayer, error) {
+	values, err := parseFloats(s)
+	if err != nil || len(values) != 2 {
+		return nil, fmt.Errorf("invalid clamp: %s (use min,max like 0.02,1)", s)
+	}
+	l := &ClampLayer{Min: values[0], Max: values[1]}
+	if l.Min < 0 || l.Max > 1 || l.Min >= l.Max {
+		return nil, fmt.Errorf("clamp must satisfy 0 <= min < max <= 1, got %s", s)
+	}
+	return l, nil
+}
+
+func (l *ClampLayer) Name() string   { return LayerClamp }
+func (l *ClampLayer) String() string { return fmt.Sprintf("%g-%g", l.Min, l.Max) }
+
+func (l *ClampLayer) Apply(c Channel, v float64) float64 {
+	return math.Max(l.Min, math.Min(l.Max, v))
+}
+
+// Pipelines holds the gamma pipeline of each display by stable ID
+type Pipelines map[string]*Pipeline
+
+// For returns the pipeline of a display, adding an empty one if needed
+func (p Pipelines) For(id string) *Pipeline {
+	if pl, ok := p[id]; ok && pl != nil {
+		return pl
+	}
+	pl := &Pipeline{}
+	p[id] = pl
+	return pl
+}
+
+// Clone returns a deep copy
+func (p Pipelines) Clone() Pipelines {
+	data, _ := json.Marshal(p)
+	var c Pipelines
+	json.Unmarshal(data, &c)
+	if c == nil {
+		c = Pipelines{}
+	}
+	return c
+}
+
+// PipelinesPath returns where the gamma pipelines are stored
+func PipelinesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lumos", "gamma.json"), nil
+}
+
+// LoadPipelines reads the pipelines at path. A missing file gives empty
+// pipelines.
+func LoadPipelines(path string) (Pipelines, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Pipelines{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := Pipelines{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid gamma pipeline file %s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the pipelines to path, creating its directory
+func (p Pipelines) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted save keeps the old file
+	tmp := path + "." + rand.Text() + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// ApplyPipelines computes the ramp of each selected display from its
+// pipeline and applies it. Ramps Windows would reject are clamped into the
+// accepted range, with a *RangeError warning. action describes the change
+// in the results.
+func ApplyPipelines(p Pipelines, action string, sel display.Selector) ([]Result, error) {
+	displays, err := sel.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if len(displays) == 0 {
+		return nil, errors.New("no displays found")
+	}
+
+	gammaRange, _ := GammaRange()
+	results := make([]Result, 0, len(displays))
+	for _, d := range displays {
+		r := Result{Display: d, Action: action}
+		ramp, err := p.For(d.ID).Ramp()
+		if err == nil {
+			var rangeErr *RangeError
+			if ramp, rangeErr = Fit(ramp, gammaRange); rangeErr != nil {
+				r.Warning = rangeErr
+			}
+			err = setDeviceGammaRamp(d.DeviceName, &ramp)
+		}
+		r.Err = err
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func parseFloats(s string) ([]float64, error) {
+	var values []float64
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid number: %s", part)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
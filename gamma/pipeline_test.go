@@ -0,0 +1,202 @@
+package gamma
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fullPipeline sets every layer to a value that changes the ramp
+func fullPipeline(t *testing.T) *Pipeline {
+	t.Helper()
+	p := &Pipeline{Calibration: &CalibrationLayer{Source: "test", Ramp: BrightnessRamp(90)}}
+	for _, l := range []struct{ name, value string }{
+		{LayerCurve, "1.2"},
+		{LayerTemperature, "5000"},
+		{LayerBrightness, "80"},
+		{LayerClamp, "0.05,0.95"},
+	} {
+		if err := p.SetLayer(l.name, l.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestPipelineOrder(t *testing.T) {
+	p := fullPipeline(t)
+
+	var names []string
+	for _, l := range p.Layers() {
+		names = append(names, l.Name())
+	}
+	if !slices.Equal(names, LayerNames) {
+		t.Errorf("layers %v, want %v", names, LayerNames)
+	}
+
+	// Calibration, then curve, temperature, brightness and clamp
+	for c := Red; c <= Blue; c++ {
+		for _, x := range []float64{0, 0.1, 0.5, 0.9, 1} {
+			want := p.Clamp.Apply(c, p.Brightness.Apply(c, p.Temperature.Apply(c, p.Curve.Apply(c, p.Calibration.Apply(c, x)))))
+			if got := p.Eval(c, x); math.Abs(got-want) > 1e-12 {
+				t.Errorf("Eval(%d, %v) = %v, want %v", c, x, got, want)
+			}
+		}
+	}
+
+	// The clamp is last, so nothing before it can go past its limits
+	ramp, err := p.Ramp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ramp.Red[0] != uint16(math.Round(0.05*65535)) {
+		t.Errorf("black = %d, want the clamp minimum", ramp.Red[0])
+	}
+
+	if got := (&Pipeline{}).Eval(Green, 0.25); got != 0.25 {
+		t.Errorf("empty pipeline Eval = %v, want the input", got)
+	}
+}
+
+func TestSetResetLayer(t *testing.T) {
+	p := fullPipeline(t)
+	before := *p
+
+	if err := p.SetLayer(LayerTemperature, "3400"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Temperature.Kelvin != 3400 || p.Calibration != before.Calibration || p.Curve != before.Curve || p.Brightness != before.Brightness || p.Clamp != before.Clamp {
+		t.Errorf("setting the temperature changed other layers: %+v", p)
+	}
+
+	if err := p.ResetLayer(LayerCurve); err != nil {
+		t.Fatal(err)
+	}
+	if p.Curve != nil || p.Calibration != before.Calibration || p.Temperature.Kelvin != 3400 || p.Brightness != before.Brightness || p.Clamp != before.Clamp {
+		t.Errorf("resetting the curve changed other layers: %+v", p)
+	}
+
+	if err := p.SetLayer(LayerCalibration, CalibrationNone); err != nil || p.Calibration.Ramp != IdentityRamp() {
+		t.Errorf("calibration none = %v, want a linear ramp", err)
+	}
+
+	invalid := []struct{ name, value string }{
+		{"gamma", "1"},
+		{LayerCalibration, CalibrationActive},
+		{LayerBrightness, "101"},
+		{LayerClamp, "0.5,0.4"},
+		{LayerTemperature, "500"},
+		{LayerCurve, "x"},
+	}
+	kept := *p
+	for _, tt := range invalid {
+		if err := p.SetLayer(tt.name, tt.value); err == nil {
+			t.Errorf("SetLayer(%q, %q) = nil error", tt.name, tt.value)
+		}
+	}
+	if *p != kept {
+		t.Error("a failed SetLayer changed the pipeline")
+	}
+	if err := p.ResetLayer("gamma"); err == nil || !strings.Contains(err.Error(), "unknown gamma layer") {
+		t.Errorf("ResetLayer(gamma) = %v", err)
+	}
+}
+
+func TestPipelinesSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lumos", "gamma.json")
+
+	if p, err := LoadPipelines(path); err != nil || len(p) != 0 {
+		t.Fatalf("LoadPipelines without a file = %v, %v, want empty", p, err)
+	}
+
+	// One invocation sets a pipeline per display
+	saved := Pipelines{"DEL-A0B4-1A2B": fullPipeline(t)}
+	if err := saved.For("GSM-5B7F").SetLayer(LayerCurve, "0:0,0.5:0.45,1:1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]GammaRamp{}
+	for id, p := range saved {
+		want[id], _ = p.Ramp()
+	}
+
+	// A later one changes one layer of one display
+	loaded, err := LoadPipelines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, p := range loaded {
+		if got, _ := p.Ramp(); got != want[id] {
+			t.Errorf("%s: ramp changed by the round trip", id)
+		}
+	}
+	if err := loaded.For("DEL-A0B4-1A2B").SetLayer(LayerBrightness, "50"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := LoadPipelines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dell, lg := again["DEL-A0B4-1A2B"], again["GSM-5B7F"]
+	if dell.Brightness.Percent != 50 || dell.Temperature.String() != "5000K" || dell.Curve.String() != "gamma 1.2" || dell.Clamp.String() != "0.05-0.95" || dell.Calibration.Ramp != saved["DEL-A0B4-1A2B"].Calibration.Ramp {
+		t.Errorf("changed pipeline = %+v, want only the brightness changed", dell)
+	}
+	if got, _ := lg.Ramp(); got != want["GSM-5B7F"] || lg.Curve.String() != "0:0,0.5:0.45,1:1" {
+		t.Errorf("other display's pipeline changed: %+v", lg)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory has %d entries (%v), want only gamma.json", len(entries), err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPipelines(path); err == nil || !strings.Contains(err.Error(), "invalid gamma pipeline file") {
+		t.Errorf("LoadPipelines of a broken file = %v", err)
+	}
+}
+
+func TestMinPeakLuminance(t *testing.T) {
+	tests := []struct {
+		clamp string
+		ok    bool
+	}{
+		{"0,1", true},
+		{"0,0.5", true},   // 22% luminance
+		{"0,0.36", true},  // 10.5%
+		{"0,0.35", false}, // 9.9%
+		{"0,0.1", false},
+	}
+	for _, tt := range tests {
+		p := &Pipeline{}
+		if err := p.SetLayer(LayerClamp, tt.clamp); err != nil {
+			t.Fatal(err)
+		}
+		_, err := p.Ramp()
+		if (err == nil) != tt.ok {
+			t.Errorf("clamp %s: Ramp = %v, want ok %v", tt.clamp, err, tt.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), "safety minimum") {
+			t.Errorf("clamp %s: error %q", tt.clamp, err)
+		}
+	}
+
+	// Brightness alone stays above the minimum
+	for percent := 0; percent <= 100; percent += 5 {
+		p := &Pipeline{Brightness: &BrightnessLayer{Percent: percent}}
+		if _, err := p.Ramp(); err != nil {
+			t.Errorf("brightness %d%%: %v", percent, err)
+		}
+	}
+}
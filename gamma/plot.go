@@ -0,0 +1,156 @@
+package gamma
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// PlotStyle is how a series is drawn
+type PlotStyle int
+
+const (
+	PlotSolid  PlotStyle = iota // Thick lines, e.g. the target ramp
+	PlotThin                    // Thin lines, e.g. intermediate layers
+	PlotDotted                  // e.g. the current ramp
+	PlotDashed                  // Gray, e.g. the identity ramp
+)
+
+// PlotSeries is a ramp drawn in a plot
+type PlotSeries struct {
+	Name  string
+	Ramp  GammaRamp
+	Style PlotStyle
+}
+
+// LayerRamp is the ramp after a pipeline layer
+type LayerRamp struct {
+	Name string
+	Ramp GammaRamp
+}
+
+// LayerRamps returns the ramp after each set layer, so a plot can show how
+// the layers build the final ramp
+func (p *Pipeline) LayerRamps() []LayerRamp {
+	var ramps []LayerRamp
+	var partial Pipeline
+	for _, l := range p.Layers() {
+		switch l := l.(type) {
+		case *CalibrationLayer:
+			partial.Calibration = l
+		case *CurveLayer:
+			partial.Curve = l
+		case *TemperatureLayer:
+			partial.Temperature = l
+		case *BrightnessLayer:
+			partial.Brightness = l
+		case *ClampLayer:
+			partial.Clamp = l
+		}
+		ramps = append(ramps, LayerRamp{Name: "after " + l.Name() + " " + l.String(), Ramp: partial.ramp()})
+	}
+	return ramps
+}
+
+// IdentityRamp returns the linear ramp
+func IdentityRamp() GammaRamp {
+	var ramp GammaRamp
+	for i := range 256 {
+		v := uint16(i * 257)
+		ramp.Red[i], ramp.Green[i], ramp.Blue[i] = v, v, v
+	}
+	return ramp
+}
+
+// Plot size and margins in SVG units
+const (
+	plotWidth  = 720
+	plotHeight = 480
+	plotLeft   = 60
+	plotRight  = 220 // Room for the legend
+	plotTop    = 40
+	plotBottom = 50
+)
+
+var plotColors = [3]string{"#d62728", "#2ca02c", "#1f77b4"}
+
+// WritePlotSVG draws the red, green and blue curves of each series as an
+// SVG line chart, input index 0-255 against output 0-65535
+func WritePlotSVG(w io.Writer, title string, series []PlotSeries) error {
+	var b strings.Builder
+	innerW := float64(plotWidth - plotLeft - plotRight)
+	innerH := float64(plotHeight - plotTop - plotBottom)
+	px := func(i int) float64 { return plotLeft + float64(i)/255*innerW }
+	py := func(v uint16) float64 { return plotTop + innerH - float64(v)/65535*innerH }
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		plotWidth, plotHeight, plotWidth, plotHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", plotWidth, plotHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="24" font-size="15" font-weight="bold">%s</text>`+"\n", plotLeft, html.EscapeString(title))
+
+	// Grid and axis labels at every quarter
+	for q := 0; q <= 4; q++ {
+		x := px(q * 255 / 4)
+		y := py(uint16(q * 65535 / 4))
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n", x, plotTop, x, plotTop+innerH)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n", plotLeft, y, plotLeft+innerW, y)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%d</text>`+"\n", x, plotTop+innerH+18, q*255/4)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%d</text>`+"\n", plotLeft-6, y+4, q*65535/4)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">input</text>`+"\n", plotLeft+innerW/2, plotHeight-10)
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%.1f" fill="none" stroke="#888"/>`+"\n", plotLeft, plotTop, innerW, innerH)
+
+	for n, s := range series {
+		channels := []*[256]uint16{&s.Ramp.Red, &s.Ramp.Green, &s.Ramp.Blue}
+		gray := s.Ramp.Red == s.Ramp.Green && s.Ramp.Green == s.Ramp.Blue
+		for c, ch := range channels {
+			if gray && c > 0 {
+				break // Draw one gray line when the channels match
+			}
+			color := plotColors[c]
+			if gray || s.Style == PlotDashed {
+				color = "#555"
+			}
+
+			points := make([]string, 256)
+			for i, v := range ch {
+				points[i] = fmt.Sprintf("%.1f,%.1f", px(i), py(v))
+			}
+			fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" %s points="%s"/>`+"\n", color, plotStroke(s.Style), strings.Join(points, " "))
+		}
+
+		// Legend entry with a sample of the line style
+		y := plotTop + 10 + n*20
+		x := plotWidth - plotRight + 16
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#555" %s/>`+"\n", x, y, x+24, y, plotStroke(s.Style))
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", x+30, y+4, html.EscapeString(shorten(s.Name, 28)))
+	}
+
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shorten cuts s to n runes with an ellipsis
+func shorten(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// plotStroke returns the SVG stroke attributes of a style
+func plotStroke(style PlotStyle) string {
+	switch style {
+	case PlotThin:
+		return `stroke-width="1" stroke-opacity="0.6"`
+	case PlotDotted:
+		return `stroke-width="1.5" stroke-dasharray="2,3"`
+	case PlotDashed:
+		return `stroke-width="1" stroke-dasharray="6,4"`
+	default:
+		return `stroke-width="2.5"`
+	}
+}
@@ -0,0 +1,96 @@
+package gamma
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLayerRamps(t *testing.T) {
+	p := fullPipeline(t)
+	ramps := p.LayerRamps()
+
+	names := []string{"after calibration test", "after curve gamma 1.2", "after temperature 5000K", "after brightness 80%", "after clamp 0.05-0.95"}
+	if len(ramps) != len(names) {
+		t.Fatalf("%d layer ramps, want %d", len(ramps), len(names))
+	}
+	for i, r := range ramps {
+		if r.Name != names[i] {
+			t.Errorf("layer ramp %d is %q, want %q", i, r.Name, names[i])
+		}
+	}
+	if ramps[0].Ramp != p.Calibration.Ramp {
+		t.Error("the first layer ramp isn't the calibration ramp")
+	}
+
+	// The last one is what lumos programs
+	want, err := p.Ramp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ramps[len(ramps)-1].Ramp != want {
+		t.Error("the last layer ramp differs from Ramp")
+	}
+
+	if ramps := (&Pipeline{}).LayerRamps(); len(ramps) != 0 {
+		t.Errorf("empty pipeline has %d layer ramps", len(ramps))
+	}
+}
+
+func TestWritePlotSVG(t *testing.T) {
+	title := `Gamma of "DELL" <U2720Q> & co`
+	long := strings.Repeat("x", 40)
+	series := []PlotSeries{
+		{Name: "identity", Ramp: IdentityRamp(), Style: PlotDashed},
+		{Name: `<5000K> & "warm"`, Ramp: fullPipeline(t).ramp(), Style: PlotThin},
+		{Name: long, Ramp: BrightnessRamp(50), Style: PlotSolid},
+	}
+
+	var b strings.Builder
+	if err := WritePlotSVG(&b, title, series); err != nil {
+		t.Fatal(err)
+	}
+
+	// Well-formed XML, with the text read back unescaped
+	var texts []string
+	polylines := 0
+	d := xml.NewDecoder(strings.NewReader(b.String()))
+	var inText bool
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			inText = tok.Name.Local == "text"
+			if tok.Name.Local == "polyline" {
+				polylines++
+			}
+		case xml.CharData:
+			if inText {
+				texts = append(texts, string(tok))
+			}
+		case xml.EndElement:
+			inText = false
+		}
+	}
+
+	for _, want := range []string{title, `<5000K> & "warm"`, strings.Repeat("x", 27) + "…", "identity"} {
+		found := false
+		for _, text := range texts {
+			found = found || text == want
+		}
+		if !found {
+			t.Errorf("text %q not in the plot", want)
+		}
+	}
+	// Gray ramps are one line, colored ones one per channel
+	if polylines != 1+3+1 {
+		t.Errorf("%d polylines, want 5", polylines)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package gamma
+
+import "errors"
+
+// GammaRange returns the GdiIcmGammaRange Windows checks gamma ramps
+// against, DefaultGammaRange when it isn't set
+func GammaRange() (int, error) {
+	return DefaultGammaRange, nil
+}
+
+// SetExtendedRange sets GdiIcmGammaRange to ExtendedGammaRange, or removes
+// it to go back to the default. Windows reads it at startup.
+func SetExtendedRange(enable bool) error {
+	return errors.New("GdiIcmGammaRange is only supported on Windows")
+}
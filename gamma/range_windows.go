@@ -0,0 +1,56 @@
+package gamma
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// GammaRange returns the GdiIcmGammaRange Windows checks gamma ramps
+// against, DefaultGammaRange when it isn't set
+func GammaRange() (int, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, ICM_KEY_PATH, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return DefaultGammaRange, nil
+	}
+	if err != nil {
+		return DefaultGammaRange, err
+	}
+	defer key.Close()
+
+	value, _, err := key.GetIntegerValue("GdiIcmGammaRange")
+	if errors.Is(err, registry.ErrNotExist) {
+		return DefaultGammaRange, nil
+	}
+	if err != nil {
+		return DefaultGammaRange, err
+	}
+	return int(min(value, ExtendedGammaRange)), nil
+}
+
+// SetExtendedRange sets GdiIcmGammaRange to ExtendedGammaRange, or removes
+// it to go back to the default. Windows reads it at startup.
+func SetExtendedRange(enable bool) error {
+	key, _, err := registry.CreateKey(registry.LOCAL_MACHINE, ICM_KEY_PATH, registry.SET_VALUE)
+	if err != nil {
+		return registryError(err)
+	}
+	defer key.Close()
+
+	if enable {
+		err = key.SetDWordValue("GdiIcmGammaRange", ExtendedGammaRange)
+	} else if err = key.DeleteValue("GdiIcmGammaRange"); errors.Is(err, registry.ErrNotExist) {
+		err = nil
+	}
+	return registryError(err)
+}
+
+// registryError explains access denied errors, HKEY_LOCAL_MACHINE needs an
+// elevated prompt
+func registryError(err error) error {
+	if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+		return errors.New("changing GdiIcmGammaRange requires administrator privileges")
+	}
+	return err
+}
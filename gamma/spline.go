@@ -0,0 +1,136 @@
+package gamma
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CurvePoint is a control point of a tone curve, input X to output Y, both
+// in [0, 1]
+type CurvePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// ParseCurvePoints parses control points like "0:0,0.25:0.2,0.75:0.85,1:1"
+func ParseCurvePoints(s string) ([]CurvePoint, error) {
+	var points []CurvePoint
+	for _, part := range strings.Split(s, ",") {
+		xy := strings.Split(strings.TrimSpace(part), ":")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("invalid control point: %s (use input:output like 0.25:0.2)", part)
+		}
+		values, err := parseFloats(xy[0] + "," + xy[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid control point: %s (use input:output like 0.25:0.2)", part)
+		}
+		points = append(points, CurvePoint{X: values[0], Y: values[1]})
+	}
+	if _, err := newMonotoneSpline(points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// formatCurvePoints formats control points the way ParseCurvePoints reads them
+func formatCurvePoints(points []CurvePoint) string {
+	parts := make([]string, len(points))
+	for i, p := range points {
+		parts[i] = fmt.Sprintf("%g:%g", p.X, p.Y)
+	}
+	return strings.Join(parts, ",")
+}
+
+func pointsEqual(a, b []CurvePoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// monotoneSpline is a monotone cubic Hermite spline through control points
+// (Fritsch-Carlson), so the curve never overshoots or decreases between
+// points that don't
+type monotoneSpline struct {
+	x, y, m []float64 // Points and tangents
+}
+
+// newMonotoneSpline checks the control points and computes the tangents.
+// Inputs must increase, outputs must not decrease, both within [0, 1].
+func newMonotoneSpline(points []CurvePoint) (*monotoneSpline, error) {
+	n := len(points)
+	if n < 2 {
+		return nil, errors.New("a curve needs at least two control points")
+	}
+
+	s := &monotoneSpline{x: make([]float64, n), y: make([]float64, n), m: make([]float64, n)}
+	for i, p := range points {
+		if p.X < 0 || p.X > 1 || p.Y < 0 || p.Y > 1 {
+			return nil, fmt.Errorf("control point %g:%g is outside 0-1", p.X, p.Y)
+		}
+		if i > 0 && p.X <= points[i-1].X {
+			return nil, fmt.Errorf("control point inputs must increase, got %g after %g", p.X, points[i-1].X)
+		}
+		if i > 0 && p.Y < points[i-1].Y {
+			return nil, fmt.Errorf("control point outputs must not decrease, got %g after %g", p.Y, points[i-1].Y)
+		}
+		s.x[i], s.y[i] = p.X, p.Y
+	}
+
+	// Secant slopes, then tangents averaged from them
+	d := make([]float64, n-1)
+	for i := range d {
+		d[i] = (s.y[i+1] - s.y[i]) / (s.x[i+1] - s.x[i])
+	}
+	s.m[0], s.m[n-1] = d[0], d[n-2]
+	for i := 1; i < n-1; i++ {
+		if d[i-1]*d[i] > 0 {
+			s.m[i] = (d[i-1] + d[i]) / 2
+		}
+	}
+
+	// Limit the tangents so each segment stays monotone
+	for i, slope := range d {
+		if slope == 0 {
+			s.m[i], s.m[i+1] = 0, 0
+			continue
+		}
+		a, b := s.m[i]/slope, s.m[i+1]/slope
+		if h := math.Hypot(a, b); h > 3 {
+			s.m[i], s.m[i+1] = 3/h*a*slope, 3/h*b*slope
+		}
+	}
+	return s, nil
+}
+
+// eval returns the curve output for x. Outside the control points the curve
+// holds the first and last outputs.
+func (s *monotoneSpline) eval(x float64) float64 {
+	n := len(s.x)
+	switch {
+	case x <= s.x[0]:
+		return s.y[0]
+	case x >= s.x[n-1]:
+		return s.y[n-1]
+	}
+
+	i := 0
+	for i < n-2 && x >= s.x[i+1] {
+		i++
+	}
+	h := s.x[i+1] - s.x[i]
+	t := (x - s.x[i]) / h
+	t2, t3 := t*t, t*t*t
+
+	return (2*t3-3*t2+1)*s.y[i] +
+		(t3-2*t2+t)*h*s.m[i] +
+		(-2*t3+3*t2)*s.y[i+1] +
+		(t3-t2)*h*s.m[i+1]
+}
@@ -0,0 +1,120 @@
+package gamma
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// checkSpline fails when the curve misses a control point, falls between
+// samples or leaves the range of the outputs
+func checkSpline(t *testing.T, points []CurvePoint) {
+	t.Helper()
+	s, err := newMonotoneSpline(points)
+	if err != nil {
+		t.Fatalf("%v: %v", points, err)
+	}
+
+	first, last := points[0], points[len(points)-1]
+	if got := s.eval(0); got != first.Y {
+		t.Errorf("%v: f(0) = %v, want %v", points, got, first.Y)
+	}
+	if got := s.eval(1); got != last.Y {
+		t.Errorf("%v: f(1) = %v, want %v", points, got, last.Y)
+	}
+	for _, p := range points {
+		if got := s.eval(p.X); math.Abs(got-p.Y) > 1e-12 {
+			t.Errorf("%v: f(%v) = %v, want %v", points, p.X, got, p.Y)
+		}
+	}
+
+	prev := s.eval(0)
+	for i := 1; i <= 4096; i++ {
+		x := float64(i) / 4096
+		y := s.eval(x)
+		if y < prev-1e-12 {
+			t.Fatalf("%v: f(%v) = %v after %v", points, x, y, prev)
+		}
+		if y < first.Y-1e-12 || y > last.Y+1e-12 {
+			t.Fatalf("%v: f(%v) = %v outside %v-%v", points, x, y, first.Y, last.Y)
+		}
+		prev = y
+	}
+}
+
+func TestMonotoneSpline(t *testing.T) {
+	curves := []string{
+		"0:0,1:1",
+		"0:0,0.25:0.3,0.75:0.85,1:1",
+		"0:0.1,1:0.9",
+		"0.2:0,0.8:1",                   // Held outside the points
+		"0:0,0.5:0.5,0.51:0.5,1:1",      // Flat segment
+		"0:0,0.01:0.9,1:1",              // Steep then flat
+		"0:0,0.5:0,0.6:1,1:1",           // Step
+		"0:0,0.1:0.5,0.2:0.5,0.3:1,1:1", // Stairs
+	}
+	for _, c := range curves {
+		points, err := ParseCurvePoints(c)
+		if err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+		checkSpline(t, points)
+	}
+
+	// Collinear points give a straight line
+	s, _ := newMonotoneSpline([]CurvePoint{{0, 0}, {0.5, 0.5}, {1, 1}})
+	for _, x := range []float64{0.1, 0.33, 0.9} {
+		if got := s.eval(x); math.Abs(got-x) > 1e-12 {
+			t.Errorf("identity f(%v) = %v", x, got)
+		}
+	}
+}
+
+func TestParseCurvePointsInvalid(t *testing.T) {
+	tests := []struct {
+		s   string
+		err string
+	}{
+		{"0:0", "at least two"},
+		{"0:0,1", "invalid control point"},
+		{"0:0,x:1", "invalid control point"},
+		{"0:0,1.5:1", "outside 0-1"},
+		{"0:0,1:-0.1", "outside 0-1"},
+		{"0:0,0.5:0.5,0.5:0.6,1:1", "inputs must increase"},
+		{"0:0,0.5:0.6,0.7:0.5,1:1", "outputs must not decrease"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCurvePoints(tt.s); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseCurvePoints(%q) = %v, want an error containing %q", tt.s, err, tt.err)
+		}
+	}
+}
+
+func FuzzMonotoneSpline(f *testing.F) {
+	f.Add([]byte{1, 1, 1, 1})
+	f.Add([]byte{10, 0, 1, 200, 50, 3, 2, 2})
+	f.Add([]byte{0, 255, 255, 0, 1, 1, 0, 0, 9, 9})
+
+	// Each pair of bytes is the step to the next point; the curve is scaled
+	// to span inputs 0-1 and outputs up to 1
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < 4 || len(data) > 64 {
+			return
+		}
+		points := []CurvePoint{{0, 0}}
+		x, y := 0.0, 0.0
+		for i := 0; i+1 < len(data); i += 2 {
+			x += float64(data[i]) + 1
+			y += float64(data[i+1])
+			points = append(points, CurvePoint{x, y})
+		}
+		for i := range points {
+			points[i].X /= x
+			if y > 0 {
+				points[i].Y /= y
+			}
+		}
+		points[len(points)-1].X = 1
+		checkSpline(t, points)
+	})
+}
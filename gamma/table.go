@@ -0,0 +1,172 @@
+package gamma
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jipaix/lumos/icc"
+)
+
+// Table is a ramp of any size, e.g. 1024 entries from a calibration tool.
+// Channel values are in [0, 1] for evenly spaced inputs.
+type Table struct {
+	Curves [3][]float64 // Red, green, blue
+}
+
+// TableFromRamp converts a gamma ramp to a 256 entry table
+func TableFromRamp(r *GammaRamp) Table {
+	var t Table
+	for c, ch := range []*[256]uint16{&r.Red, &r.Green, &r.Blue} {
+		t.Curves[c] = make([]float64, 256)
+		for i, v := range ch {
+			t.Curves[c][i] = float64(v) / 65535
+		}
+	}
+	return t
+}
+
+// Size returns the number of entries per channel
+func (t Table) Size() int {
+	return len(t.Curves[0])
+}
+
+// validate checks that every channel has the same number of entries, at
+// least two, with values in [0, 1]
+func (t Table) validate() error {
+	size := t.Size()
+	if size < 2 {
+		return fmt.Errorf("ramp has %d entries, expected at least 2", size)
+	}
+	for c, curve := range t.Curves {
+		if len(curve) != size {
+			return fmt.Errorf("ramp channels have %d and %d entries", size, len(curve))
+		}
+		for i, v := range curve {
+			if math.IsNaN(v) || v < 0 || v > 1 {
+				return fmt.Errorf("ramp value %g of channel %d, entry %d is outside 0-1", v, c, i)
+			}
+		}
+	}
+	return nil
+}
+
+// Eval returns the output of a channel for an input in [0, 1],
+// interpolating linearly between entries
+func (t Table) Eval(c Channel, x float64) float64 {
+	curve := t.Curves[c]
+	pos := clamp01(x) * float64(len(curve)-1)
+	i := int(pos)
+	if i >= len(curve)-1 {
+		return curve[len(curve)-1]
+	}
+	frac := pos - float64(i)
+	return curve[i]*(1-frac) + curve[i+1]*frac
+}
+
+// Ramp resamples the table to a 256 entry gamma ramp
+func (t Table) Ramp() GammaRamp {
+	var ramp GammaRamp
+	for c, ch := range []*[256]uint16{&ramp.Red, &ramp.Green, &ramp.Blue} {
+		for i := range 256 {
+			ch[i] = uint16(math.Round(clamp01(t.Eval(Channel(c), float64(i)/255)) * 65535))
+		}
+	}
+	return ramp
+}
+
+// Ramp file formats, chosen by file extension
+const (
+	FormatCube = ".cube" // 1D LUT in the Adobe/Resolve cube format
+	FormatCSV  = ".csv"  // index,red,green,blue rows
+	FormatCal  = ".cal"  // Argyll calibration file
+	FormatICC  = ".icc"  // ICC profile with a vcgt tag, also .icm
+)
+
+// formatOf returns the ramp format of a file by its extension
+func formatOf(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case FormatCube, FormatCSV, FormatCal, FormatICC:
+		return ext, nil
+	case ".icm":
+		return FormatICC, nil
+	}
+	return "", fmt.Errorf("unknown ramp file type: %s (use .cube, .csv, .cal, .icc or .icm)", filepath.Base(path))
+}
+
+// ReadTable reads a ramp from a .cube, .csv, .cal, .icc or .icm file.
+// description is the title or description stored in the file, if any.
+func ReadTable(path string) (t Table, description string, err error) {
+	format, err := formatOf(path)
+	if err != nil {
+		return t, "", err
+	}
+
+	if format == FormatICC {
+		p, err := icc.ReadFile(path)
+		if err != nil {
+			return t, "", err
+		}
+		if p.VCGT == nil {
+			return t, "", fmt.Errorf("%s has no calibration curves (vcgt tag)", filepath.Base(path))
+		}
+		return Table{Curves: p.VCGT.Curves}, p.Description, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return t, "", err
+	}
+	defer f.Close()
+
+	switch format {
+	case FormatCube:
+		t, description, err = ReadCube(f)
+	case FormatCSV:
+		t, err = ReadCSV(f)
+	case FormatCal:
+		t, description, err = ReadCal(f)
+	}
+	if err != nil {
+		return t, "", fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return t, description, nil
+}
+
+// WriteTable writes a ramp to a .cube, .csv, .cal, .icc or .icm file
+func WriteTable(path string, t Table, description string) error {
+	format, err := formatOf(path)
+	if err != nil {
+		return err
+	}
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	if format == FormatICC {
+		data, err := icc.Encode(&icc.Profile{Description: description, VCGT: &icc.VCGT{Curves: t.Curves}})
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatCube:
+		err = WriteCube(f, t, description)
+	case FormatCSV:
+		err = WriteCSV(f, t)
+	case FormatCal:
+		err = WriteCal(f, t, description)
+	}
+	return errors.Join(err, f.Close())
+}
@@ -0,0 +1,92 @@
+package gamma
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// calibrated returns a ramp of size entries with a different curve per
+// channel, like the output of a calibration tool
+func calibrated(size int) Table {
+	var t Table
+	for c := range t.Curves {
+		t.Curves[c] = make([]float64, size)
+		for i := range size {
+			x := float64(i) / float64(size-1)
+			t.Curves[c][i] = 0.02*float64(c) + (0.95-0.02*float64(c))*math.Pow(x, 1+0.1*float64(c))
+		}
+	}
+	return t
+}
+
+func TestTableRoundTrip(t *testing.T) {
+	tests := []struct {
+		file        string
+		tolerance   float64
+		description bool // The format stores a description
+	}{
+		{"desk.cube", 1e-6, true},
+		{"desk.csv", 1.0 / 65535, false},
+		{"desk.cal", 1e-7, true},
+		{"desk.icc", 1.0 / 65535, true},
+		{"desk.icm", 1.0 / 65535, true},
+	}
+	for _, size := range []int{256, 1024, 17} {
+		want := calibrated(size)
+		for _, tt := range tests {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := WriteTable(path, want, "DELL U2720Q D65"); err != nil {
+				t.Fatalf("%s: %v", tt.file, err)
+			}
+			got, description, err := ReadTable(path)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.file, err)
+			}
+
+			if tt.description && description != "DELL U2720Q D65" {
+				t.Errorf("%s: description = %q", tt.file, description)
+			}
+			if got.Size() != size {
+				t.Fatalf("%s: %d entries, want %d", tt.file, got.Size(), size)
+			}
+			for c := range want.Curves {
+				for i, v := range want.Curves[c] {
+					if math.Abs(got.Curves[c][i]-v) > tt.tolerance {
+						t.Fatalf("%s with %d entries: channel %d, entry %d = %v, want %v", tt.file, size, c, i, got.Curves[c][i], v)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTableRamp(t *testing.T) {
+	r := BrightnessRamp(100)
+	table := TableFromRamp(&r)
+	if table.Size() != 256 || table.Ramp() != r {
+		t.Error("linear ramp doesn't survive a table round trip")
+	}
+
+	// A 2 entry table resamples to a straight line
+	two := Table{Curves: [3][]float64{{0, 1}, {0, 0.5}, {0.2, 1}}}
+	ramp := two.Ramp()
+	if ramp.Red[128] != 32896 || ramp.Green[255] != 32768 || ramp.Blue[0] != 13107 {
+		t.Errorf("resampled ramp = %d %d %d", ramp.Red[128], ramp.Green[255], ramp.Blue[0])
+	}
+}
+
+func TestWriteTableInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteTable(filepath.Join(dir, "desk.png"), calibrated(256), ""); err == nil {
+		t.Error("WriteTable to .png succeeded")
+	}
+	bad := calibrated(256)
+	bad.Curves[1][10] = 1.5
+	if err := WriteTable(filepath.Join(dir, "desk.cube"), bad, ""); err == nil {
+		t.Error("WriteTable with a value above 1 succeeded")
+	}
+	if _, _, err := ReadTable(filepath.Join(dir, "missing.cal")); err == nil {
+		t.Error("ReadTable of a missing file succeeded")
+	}
+}
@@ -0,0 +1,103 @@
+package gamma
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ICM_KEY_PATH holds the GdiIcmGammaRange value under HKEY_LOCAL_MACHINE
+const ICM_KEY_PATH = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ICM`
+
+// Gamma ranges of the GdiIcmGammaRange registry value. Windows compares the
+// high byte of each ramp entry with its index and rejects the whole ramp
+// when any entry is more than the range away, so by default full white
+// can't go below about 50%.
+const (
+	DefaultGammaRange  = 128
+	ExtendedGammaRange = 256 // Accepts any ramp
+)
+
+// channelNames names channels in messages
+var channelNames = [3]string{"red", "green", "blue"}
+
+// Violation is a ramp entry outside the range Windows accepts
+type Violation struct {
+	Channel Channel
+	Index   int
+	Value   uint16
+	Min     uint16 // Lowest accepted value at Index
+	Max     uint16 // Highest accepted value at Index
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s entry %d is %d (%.0f%%) but must be %d-%d (%.0f%%-%.0f%%)",
+		channelNames[v.Channel], v.Index, v.Value, percent16(v.Value),
+		v.Min, v.Max, percent16(v.Min), percent16(v.Max))
+}
+
+// RangeError explains why Windows would reject a ramp
+type RangeError struct {
+	Range      int // GdiIcmGammaRange in effect
+	Violations []Violation
+	Clamped    bool // Set when the ramp was clamped into range instead
+}
+
+func (e *RangeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Windows rejects gamma ramps more than %d steps from linear: %s", e.Range, e.Violations[0])
+	if len(e.Violations) > 1 {
+		fmt.Fprintf(&b, " (and %d more entries)", len(e.Violations)-1)
+	}
+	if e.Clamped {
+		b.WriteString("; clamped to the accepted range")
+	}
+	if e.Range < ExtendedGammaRange {
+		b.WriteString("; 'lumos gamma extended-range on' lifts the limit")
+	}
+	return b.String()
+}
+
+// AcceptedRange returns the lowest and highest value Windows accepts for
+// ramp entry i with the given GdiIcmGammaRange, taken as 0-256
+func AcceptedRange(i, gammaRange int) (uint16, uint16) {
+	gammaRange = min(max(gammaRange, 0), ExtendedGammaRange)
+	lo := max(i-gammaRange, 0) * 256
+	hi := min(i+gammaRange, 255)*256 + 255
+	return uint16(lo), uint16(hi)
+}
+
+// Validate returns the entries of a ramp that Windows would reject with the
+// given GdiIcmGammaRange, nil if it accepts the ramp
+func Validate(r *GammaRamp, gammaRange int) []Violation {
+	var violations []Violation
+	for c, ch := range []*[256]uint16{&r.Red, &r.Green, &r.Blue} {
+		for i, v := range ch {
+			lo, hi := AcceptedRange(i, gammaRange)
+			if v < lo || v > hi {
+				violations = append(violations, Violation{Channel: Channel(c), Index: i, Value: v, Min: lo, Max: hi})
+			}
+		}
+	}
+	return violations
+}
+
+// Fit clamps a ramp into the range Windows accepts. The error describes
+// the clamped entries and is nil when the ramp was accepted as is.
+func Fit(r GammaRamp, gammaRange int) (GammaRamp, *RangeError) {
+	violations := Validate(&r, gammaRange)
+	if len(violations) == 0 {
+		return r, nil
+	}
+
+	for _, ch := range []*[256]uint16{&r.Red, &r.Green, &r.Blue} {
+		for i, v := range ch {
+			lo, hi := AcceptedRange(i, gammaRange)
+			ch[i] = min(max(v, lo), hi)
+		}
+	}
+	return r, &RangeError{Range: gammaRange, Violations: violations, Clamped: true}
+}
+
+func percent16(v uint16) float64 {
+	return float64(v) / 65535 * 100
+}
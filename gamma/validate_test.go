@@ -0,0 +1,144 @@
+package gamma
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedRange(t *testing.T) {
+	tests := []struct {
+		i, gammaRange int
+		lo, hi        uint16
+	}{
+		{0, DefaultGammaRange, 0, 33023},
+		{127, DefaultGammaRange, 0, 65535},
+		{128, DefaultGammaRange, 0, 65535},
+		{129, DefaultGammaRange, 256, 65535},
+		{255, DefaultGammaRange, 32512, 65535},
+		{255, 64, 48896, 65535},
+		{0, 64, 0, 16639},
+		{100, 0, 25600, 25855},
+		{255, ExtendedGammaRange, 0, 65535},
+		{255, 1000, 0, 65535},   // Taken as ExtendedGammaRange
+		{100, -5, 25600, 25855}, // Taken as 0
+	}
+	for _, tt := range tests {
+		lo, hi := AcceptedRange(tt.i, tt.gammaRange)
+		if lo != tt.lo || hi != tt.hi {
+			t.Errorf("AcceptedRange(%d, %d) = %d-%d, want %d-%d", tt.i, tt.gammaRange, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestValidateBoundary(t *testing.T) {
+	tests := []struct {
+		name       string
+		gammaRange int
+		index      int
+		value      uint16
+		ok         bool
+	}{
+		{"white at the default floor", DefaultGammaRange, 255, 32512, true},
+		{"white below the default floor", DefaultGammaRange, 255, 32511, false},
+		{"black at the default ceiling", DefaultGammaRange, 0, 33023, true},
+		{"black above the default ceiling", DefaultGammaRange, 0, 33024, false},
+		{"white at a custom floor", 64, 255, 48896, true},
+		{"white below a custom floor", 64, 255, 48895, false},
+		{"mid gray above a custom ceiling", 64, 128, 49408, false},
+		{"white off with the extended range", ExtendedGammaRange, 255, 0, true},
+	}
+	for _, tt := range tests {
+		r := IdentityRamp()
+		r.Green[tt.index] = tt.value
+		violations := Validate(&r, tt.gammaRange)
+		if tt.ok {
+			if len(violations) != 0 {
+				t.Errorf("%s: Validate = %v, want none", tt.name, violations)
+			}
+			continue
+		}
+		if len(violations) != 1 || violations[0].Channel != Green || violations[0].Index != tt.index || violations[0].Value != tt.value {
+			t.Errorf("%s: Validate = %v, want green entry %d", tt.name, violations, tt.index)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	r := IdentityRamp()
+	if got, err := Fit(r, DefaultGammaRange); err != nil || got != r {
+		t.Errorf("Fit of the linear ramp = %v, want it unchanged", err)
+	}
+
+	// Full white at 40% is below the default range
+	var dim GammaRamp
+	for i := range 256 {
+		v := uint16(i * 257 * 2 / 5)
+		dim.Red[i], dim.Green[i], dim.Blue[i] = v, v, v
+	}
+	got, err := Fit(dim, DefaultGammaRange)
+	if err == nil || !err.Clamped || err.Range != DefaultGammaRange {
+		t.Fatalf("Fit of a 40%% ramp = %v, want a clamped range error", err)
+	}
+	if got.Red[255] != 32512 || got.Red[0] != dim.Red[0] {
+		t.Errorf("clamped white = %d, black = %d, want 32512 and %d", got.Red[255], got.Red[0], dim.Red[0])
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "more than 128 steps") || !strings.Contains(msg, "extended-range on") || !strings.Contains(msg, "clamped") {
+		t.Errorf("Error() = %q", msg)
+	}
+
+	if _, err := Fit(dim, ExtendedGammaRange); err != nil {
+		t.Errorf("Fit with the extended range = %v, want no error", err)
+	}
+}
+
+func TestFitPassesValidate(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for n := range 200 {
+		var r GammaRamp
+		for i := range 256 {
+			r.Red[i], r.Green[i], r.Blue[i] = uint16(rng.Uint32()), uint16(rng.Uint32()), uint16(rng.Uint32())
+		}
+		gammaRange := []int{0, 1, 64, DefaultGammaRange, 200, ExtendedGammaRange}[n%6]
+		checkFit(t, r, gammaRange)
+	}
+}
+
+// checkFit fails when Fit leaves entries Validate rejects, or changes a
+// ramp that was accepted
+func checkFit(t *testing.T, r GammaRamp, gammaRange int) {
+	t.Helper()
+	got, err := Fit(r, gammaRange)
+	if v := Validate(&got, gammaRange); len(v) != 0 {
+		t.Fatalf("range %d: Fit output has %d violations, first %v", gammaRange, len(v), v[0])
+	}
+	if err == nil && got != r {
+		t.Fatalf("range %d: Fit changed an accepted ramp", gammaRange)
+	}
+	if err != nil && len(err.Violations) != len(Validate(&r, gammaRange)) {
+		t.Fatalf("range %d: Fit reported %d violations, Validate %d", gammaRange, len(err.Violations), len(Validate(&r, gammaRange)))
+	}
+}
+
+func FuzzFit(f *testing.F) {
+	f.Add(make([]byte, 8), int16(DefaultGammaRange))
+	f.Add([]byte{0xFF, 0xFF, 0, 0, 0x80, 0}, int16(64))
+	f.Add([]byte{1, 2, 3}, int16(-1))
+
+	// The bytes are repeated over the ramp as 16 bit values
+	f.Fuzz(func(t *testing.T, data []byte, gammaRange int16) {
+		if len(data) < 2 {
+			return
+		}
+		var r GammaRamp
+		for c, ch := range []*[256]uint16{&r.Red, &r.Green, &r.Blue} {
+			for i := range ch {
+				pos := (c*256 + i) * 2 % (len(data) - 1)
+				ch[i] = binary.BigEndian.Uint16(data[pos:])
+			}
+		}
+		checkFit(t, r, int(gammaRange))
+	})
+}
@@ -0,0 +1,231 @@
+package gamma
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Color temperature algorithms of the temperature layer
+const (
+	TemperatureHelland   = "helland"   // Tanner Helland's fit of blackbody sRGB colors, the default
+	TemperatureBlackbody = "blackbody" // Planckian locus
+	TemperatureDaylight  = "daylight"  // CIE daylight illuminant series
+)
+
+// TemperatureAlgorithms are the algorithms in the order they are listed in help
+var TemperatureAlgorithms = []string{TemperatureHelland, TemperatureBlackbody, TemperatureDaylight}
+
+// MinDaylightTemperature is the lowest temperature of the CIE daylight series
+const MinDaylightTemperature = 4000
+
+// encodingGamma converts the linear channel gains of a white point into ramp
+// multipliers, assuming the display decodes with a 2.2 power
+const encodingGamma = 2.2
+
+// Chromaticity is a CIE 1931 xy chromaticity
+type Chromaticity struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Standard illuminants accepted by ParseWhitePoint, with the 2° observer
+// chromaticities of CIE 15
+var Illuminants = map[string]Chromaticity{
+	"A":   {0.44757, 0.40745},
+	"D50": {0.34567, 0.35850},
+	"D55": {0.33242, 0.34743},
+	"D65": {0.31271, 0.32902},
+	"D75": {0.29902, 0.31485},
+}
+
+// ParseWhitePoint parses a white point given as xy chromaticity like
+// "0.3457,0.3585" or as a standard illuminant like "D50"
+func ParseWhitePoint(s string) (Chromaticity, error) {
+	if c, ok := Illuminants[strings.ToUpper(strings.TrimSpace(s))]; ok {
+		return c, nil
+	}
+
+	values, err := parseFloats(s)
+	if err != nil || len(values) != 2 {
+		return Chromaticity{}, fmt.Errorf("invalid white point: %s (use x,y chromaticity like 0.3457,0.3585 or D50)", s)
+	}
+	c := Chromaticity{X: values[0], Y: values[1]}
+	if c.X <= 0 || c.Y <= 0 || c.X+c.Y >= 1 {
+		return Chromaticity{}, fmt.Errorf("white point %s is not a valid chromaticity", s)
+	}
+	if gains := c.linearRGB(); gains[0] <= 0 || gains[1] <= 0 || gains[2] <= 0 {
+		return Chromaticity{}, fmt.Errorf("white point %s is outside the sRGB gamut", s)
+	}
+	return c, nil
+}
+
+func (c Chromaticity) String() string {
+	return fmt.Sprintf("%.4f,%.4f", c.X, c.Y)
+}
+
+// PlanckianChromaticity returns the chromaticity of a blackbody radiator,
+// from Planck's law summed over the CIE 1931 color matching functions
+func PlanckianChromaticity(kelvin float64) Chromaticity {
+	const c2 = 1.4388e-2 // Second radiation constant, m·K
+
+	var x, y, z float64
+	for i, cmf := range cie1931 {
+		l := float64(cie1931Start+cie1931Step*i) * 1e-9
+		radiance := 1 / (l * l * l * l * l * (math.Exp(c2/(l*kelvin)) - 1))
+		x += radiance * cmf[0]
+		y += radiance * cmf[1]
+		z += radiance * cmf[2]
+	}
+	return Chromaticity{X: x / (x + y + z), Y: y / (x + y + z)}
+}
+
+// cie1931 are the CIE 1931 2° color matching functions x̄, ȳ and z̄ from
+// cie1931Start nm in cie1931Step nm steps
+var cie1931 = [][3]float64{
+	{0.001368, 0.000039, 0.006450},
+	{0.002236, 0.000064, 0.010550},
+	{0.004243, 0.000120, 0.020050},
+	{0.007650, 0.000217, 0.036210},
+	{0.014310, 0.000396, 0.067850},
+	{0.023190, 0.000640, 0.110200},
+	{0.043510, 0.001210, 0.207400},
+	{0.077630, 0.002180, 0.371300},
+	{0.134380, 0.004000, 0.645600},
+	{0.214770, 0.007300, 1.039050},
+	{0.283900, 0.011600, 1.385600},
+	{0.328500, 0.016840, 1.622960},
+	{0.348280, 0.023000, 1.747060},
+	{0.348060, 0.029800, 1.782600},
+	{0.336200, 0.038000, 1.772110},
+	{0.318700, 0.048000, 1.744100},
+	{0.290800, 0.060000, 1.669200},
+	{0.251100, 0.073900, 1.528100},
+	{0.195360, 0.090980, 1.287640},
+	{0.142100, 0.112600, 1.041900},
+	{0.095640, 0.139020, 0.812950},
+	{0.057950, 0.169300, 0.616200},
+	{0.032010, 0.208020, 0.465180},
+	{0.014700, 0.258600, 0.353300},
+	{0.004900, 0.323000, 0.272000},
+	{0.002400, 0.407300, 0.212300},
+	{0.009300, 0.503000, 0.158200},
+	{0.029100, 0.608200, 0.111700},
+	{0.063270, 0.710000, 0.078250},
+	{0.109600, 0.793200, 0.057250},
+	{0.165500, 0.862000, 0.042160},
+	{0.225750, 0.914850, 0.029840},
+	{0.290400, 0.954000, 0.020300},
+	{0.359700, 0.980300, 0.013400},
+	{0.433450, 0.994950, 0.008750},
+	{0.512050, 1.000000, 0.005750},
+	{0.594500, 0.995000, 0.003900},
+	{0.678400, 0.978600, 0.002750},
+	{0.762100, 0.952000, 0.002100},
+	{0.842500, 0.915400, 0.001800},
+	{0.916300, 0.870000, 0.001650},
+	{0.978600, 0.816300, 0.001400},
+	{1.026300, 0.757000, 0.001100},
+	{1.056700, 0.694900, 0.001000},
+	{1.062200, 0.631000, 0.000800},
+	{1.045600, 0.566800, 0.000600},
+	{1.002600, 0.503000, 0.000340},
+	{0.938400, 0.441200, 0.000240},
+	{0.854450, 0.381000, 0.000190},
+	{0.751400, 0.321000, 0.000100},
+	{0.642400, 0.265000, 0.000050},
+	{0.541900, 0.217000, 0.000030},
+	{0.447900, 0.175000, 0.000020},
+	{0.360800, 0.138200, 0.000010},
+	{0.283500, 0.107000, 0.000000},
+	{0.218700, 0.081600, 0.000000},
+	{0.164900, 0.061000, 0.000000},
+	{0.121200, 0.044580, 0.000000},
+	{0.087400, 0.032000, 0.000000},
+	{0.063600, 0.023200, 0.000000},
+	{0.046770, 0.017000, 0.000000},
+	{0.032900, 0.011920, 0.000000},
+	{0.022700, 0.008210, 0.000000},
+	{0.015840, 0.005723, 0.000000},
+	{0.011359, 0.004102, 0.000000},
+	{0.008111, 0.002929, 0.000000},
+	{0.005790, 0.002091, 0.000000},
+	{0.004109, 0.001484, 0.000000},
+	{0.002899, 0.001047, 0.000000},
+	{0.002049, 0.000740, 0.000000},
+	{0.001440, 0.000520, 0.000000},
+	{0.001000, 0.000361, 0.000000},
+	{0.000690, 0.000249, 0.000000},
+	{0.000476, 0.000172, 0.000000},
+	{0.000332, 0.000120, 0.000000},
+	{0.000235, 0.000085, 0.000000},
+	{0.000166, 0.000060, 0.000000},
+	{0.000117, 0.000042, 0.000000},
+	{0.000083, 0.000030, 0.000000},
+	{0.000059, 0.000021, 0.000000},
+	{0.000041, 0.000015, 0.000000},
+}
+
+const (
+	cie1931Start = 380
+	cie1931Step  = 5
+)
+
+// DaylightChromaticity returns the chromaticity of the CIE daylight
+// illuminant of a correlated color temperature, from 4000K to 25000K. D65 is
+// at 6504K, because the illuminants predate the revised radiation constant.
+func DaylightChromaticity(kelvin float64) (Chromaticity, error) {
+	if kelvin < MinDaylightTemperature || kelvin > 25000 {
+		return Chromaticity{}, fmt.Errorf("daylight is only defined from %dK to 25000K, got %.0fK", MinDaylightTemperature, kelvin)
+	}
+
+	t := kelvin
+	var x float64
+	if t <= 7000 {
+		x = -4.6070e9/(t*t*t) + 2.9678e6/(t*t) + 0.09911e3/t + 0.244063
+	} else {
+		x = -2.0064e9/(t*t*t) + 1.9018e6/(t*t) + 0.24748e3/t + 0.237040
+	}
+	return Chromaticity{X: x, Y: -3*x*x + 2.870*x - 0.275}, nil
+}
+
+// linearRGB returns the linear sRGB of the white with chromaticity c and
+// luminance 1. D65 is (1, 1, 1).
+func (c Chromaticity) linearRGB() [3]float64 {
+	x, y, z := c.X/c.Y, 1.0, (1-c.X-c.Y)/c.Y
+	return [3]float64{
+		3.2404542*x - 1.5371385*y - 0.4985314*z,
+		-0.9692660*x + 1.8760108*y + 0.0415560*z,
+		0.0556434*x - 0.2040259*y + 1.0572252*z,
+	}
+}
+
+// gains returns ramp multipliers that turn the white of a display into the
+// chromaticity c, relative to the white ref of the display. The brightest
+// channel stays at 1.
+func (c Chromaticity) gains(ref Chromaticity) [3]float64 {
+	rgb, neutral := c.linearRGB(), ref.linearRGB()
+	var peak float64
+	for i := range rgb {
+		rgb[i] = math.Max(rgb[i]/neutral[i], 0)
+		peak = math.Max(peak, rgb[i])
+	}
+	for i := range rgb {
+		rgb[i] = math.Pow(rgb[i]/peak, 1/encodingGamma)
+	}
+	return rgb
+}
+
+// temperatureChromaticity returns the chromaticity of a color temperature
+// with a temperature algorithm other than TemperatureHelland
+func temperatureChromaticity(algorithm string, kelvin float64) (Chromaticity, error) {
+	switch algorithm {
+	case TemperatureBlackbody:
+		return PlanckianChromaticity(kelvin), nil
+	case TemperatureDaylight:
+		return DaylightChromaticity(kelvin)
+	default:
+		return Chromaticity{}, errors.New("unknown temperature algorithm: " + algorithm)
+	}
+}
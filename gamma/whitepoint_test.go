@@ -0,0 +1,112 @@
+package gamma
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReferenceChromaticities(t *testing.T) {
+	daylight := func(kelvin float64) Chromaticity {
+		c, err := DaylightChromaticity(kelvin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	tests := []struct {
+		name string
+		got  Chromaticity
+		want Chromaticity
+	}{
+		{"2856K blackbody is A", PlanckianChromaticity(2856), Illuminants["A"]},
+		{"5003K daylight is D50", daylight(5003), Illuminants["D50"]},
+		{"6504K daylight is D65", daylight(6504), Illuminants["D65"]},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got.X-tt.want.X) > 1e-4 || math.Abs(tt.got.Y-tt.want.Y) > 1e-4 {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Equal energy white sums to the same in each channel
+	var sum [3]float64
+	for _, cmf := range cie1931 {
+		for i := range sum {
+			sum[i] += cmf[i]
+		}
+	}
+	if math.Abs(sum[0]-sum[1]) > 1e-3 || math.Abs(sum[2]-sum[1]) > 1e-3 {
+		t.Errorf("color matching function sums %v, want equal", sum)
+	}
+}
+
+func TestTemperatureGains(t *testing.T) {
+	gains := func(s string) [3]float64 {
+		l, err := ParseTemperature(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l.gains()
+	}
+	near := func(a, b [3]float64, tolerance float64) bool {
+		for i := range a {
+			if math.Abs(a[i]-b[i]) > tolerance {
+				return false
+			}
+		}
+		return true
+	}
+	identity := [3]float64{1, 1, 1}
+
+	tests := []struct {
+		name      string
+		got, want [3]float64
+		tolerance float64
+	}{
+		{"6500K is neutral", gains("6500"), identity, 0},
+		{"D65 is neutral", gains("D65"), identity, 1e-9},
+		{"6504K daylight is neutral", gains("6504K daylight"), identity, 1e-3},
+		{"2856K blackbody is A", gains("2856 blackbody"), gains("A"), 1e-3},
+		{"5003K daylight is D50", gains("5003 daylight"), gains("D50"), 1e-3},
+	}
+	for _, tt := range tests {
+		if !near(tt.got, tt.want, tt.tolerance) {
+			t.Errorf("%s: gains %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// The Planckian locus passes below D65: less green at 6500K
+	if g := gains("6500 blackbody"); g[0] != 1 || g[1] > 0.99 || g[2] < 0.98 {
+		t.Errorf("6500K blackbody gains %v, want slightly magenta", g)
+	}
+	// Warmer is redder with every algorithm
+	for _, algorithm := range TemperatureAlgorithms {
+		warm, cool := gains("4000 "+algorithm), gains("6000 "+algorithm)
+		if warm[0] != 1 || warm[2] >= cool[2] {
+			t.Errorf("%s: 4000K %v, 6000K %v, want less blue when warmer", algorithm, warm, cool)
+		}
+	}
+}
+
+func TestParseWhitePoint(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Chromaticity
+		ok   bool
+	}{
+		{"0.3457,0.3585", Chromaticity{0.3457, 0.3585}, true},
+		{" d50 ", Illuminants["D50"], true},
+		{"A", Illuminants["A"], true},
+		{"0.3457", Chromaticity{}, false},
+		{"0.6,0.5", Chromaticity{}, false}, // x+y past 1
+		{"0.1,0.8", Chromaticity{}, false}, // Outside sRGB
+		{"0,0.3", Chromaticity{}, false},   // Not a chromaticity
+		{"D60", Chromaticity{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseWhitePoint(tt.s)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseWhitePoint(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
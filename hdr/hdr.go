@@ -2,247 +2,387 @@ package hdr
 
 import (
 	"errors"
+	"fmt"
 	"syscall"
 	"unsafe"
-)
 
-// Windows API constants
-const (
-	QDC_ALL_PATHS                                     = 0x00000001
-	QDC_ONLY_ACTIVE_PATHS                             = 0x00000002
-	DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_INFO = 0x00000010
-	DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO = 15
+	"github.com/jipaix/lumos/display"
+	dc "github.com/jipaix/lumos/displayconfig"
 )
 
-// Windows structures
-type LUID struct {
-	LowPart  uint32
-	HighPart int32
+// HDR struct controls Windows HDR settings
+type HDR struct {
+	api    colorAPI
+	device deviceInfo
+	clock  Clock
+	retry  RetryPolicy
 }
 
-type DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY uint32
-
-type DISPLAYCONFIG_PATH_INFO struct {
-	Source DISPLAYCONFIG_PATH_SOURCE_INFO
-	Target DISPLAYCONFIG_PATH_TARGET_INFO
-	Flags  uint32
+// NewHDR creates a new HDR controller for the running Windows build
+func NewHDR() *HDR {
+	return newHDRForBuild(osBuild())
 }
 
-type DISPLAYCONFIG_PATH_SOURCE_INFO struct {
-	AdapterId   LUID
-	Id          uint32
-	ModeInfoIdx uint32
-	StatusFlags uint32
+// newHDRForBuild creates an HDR controller that uses the display APIs of the
+// given Windows build
+func newHDRForBuild(build uint32) *HDR {
+	return &HDR{api: colorAPIForBuild(build), device: systemDeviceInfo{}, clock: systemClock{}, retry: DefaultRetryPolicy}
 }
 
-type DISPLAYCONFIG_PATH_TARGET_INFO struct {
-	AdapterId        LUID
-	Id               uint32
-	ModeInfoIdx      uint32
-	OutputTechnology DISPLAYCONFIG_VIDEO_OUTPUT_TECHNOLOGY
-	Rotation         uint32
-	Scaling          uint32
-	RefreshRate      DISPLAYCONFIG_RATIONAL
-	ScanLineOrdering uint32
-	TargetAvailable  uint32 // BOOL in Windows is 4 bytes
-	StatusFlags      uint32
+// deviceInfo sends DisplayConfig device info requests so tests can fake the
+// display driver
+type deviceInfo interface {
+	Get(header *dc.DISPLAYCONFIG_DEVICE_INFO_HEADER) error
+	Set(header *dc.DISPLAYCONFIG_DEVICE_INFO_HEADER) error
 }
 
-type DISPLAYCONFIG_RATIONAL struct {
-	Numerator   uint32
-	Denominator uint32
-}
+// systemDeviceInfo sends requests to Windows
+type systemDeviceInfo struct{}
 
-type DISPLAYCONFIG_ADVANCED_COLOR_INFO struct {
-	Header DISPLAYCONFIG_DEVICE_INFO_HEADER
-	Value  uint32
+func (systemDeviceInfo) Get(header *dc.DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	return dc.GetDeviceInfo(header)
 }
 
-type DISPLAYCONFIG_DEVICE_INFO_HEADER struct {
-	Type      uint32
-	Size      uint32
-	AdapterId LUID
-	Id        uint32
+func (systemDeviceInfo) Set(header *dc.DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	return dc.SetDeviceInfo(header)
 }
 
-type DISPLAYCONFIG_SOURCE_MODE struct {
-	Width       uint32
-	Height      uint32
-	PixelFormat uint32
-	Position    POINTL
+// rejectedRequest reports whether a 24H2 request failed because the build
+// or driver doesn't know it, which happens when the build number is wrong,
+// e.g. under a compatibility shim, or on older display drivers
+func rejectedRequest(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && (errno == dc.ERROR_NOT_SUPPORTED || errno == dc.ERROR_INVALID_PARAMETER)
 }
 
-type DISPLAYCONFIG_TARGET_MODE struct {
-	TargetVideoSignalInfo DISPLAYCONFIG_VIDEO_SIGNAL_INFO
+// State is the advanced color state of a display
+type State struct {
+	Display      display.Display
+	HDRSupported bool
+	HDREnabled   bool
+	ACMSupported bool // Auto Color Management, Windows 11 24H2 and later
+	ACMEnabled   bool
+	Err          error // Set when the state couldn't be read
 }
 
-type DISPLAYCONFIG_VIDEO_SIGNAL_INFO struct {
-	PixelRate  uint64
-	HSyncFreq  DISPLAYCONFIG_RATIONAL
-	VSyncFreq  DISPLAYCONFIG_RATIONAL
-	ActiveSize POINTL
-	TotalSize  POINTL
+// target is an active path target and the display it drives
+type target struct {
+	display display.Display
+	target  dc.DISPLAYCONFIG_PATH_TARGET_INFO
 }
 
-type POINTL struct {
-	X int32
-	Y int32
-}
+// activeTargets returns the active path targets of the selected displays
+func activeTargets(sel display.Selector) ([]target, error) {
+	displays, err := sel.Resolve()
+	if err != nil {
+		return nil, err
+	}
 
-type DISPLAYCONFIG_MODE_INFO struct {
-	InfoType  uint32
-	Id        uint32
-	AdapterId LUID
-	ModeInfo  unionModeInfo
-}
+	paths, _, _, err := dc.Query(dc.QDC_ONLY_ACTIVE_PATHS)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []target
+	for _, path := range paths {
+		name, err := dc.SourceName(path.SourceInfo.AdapterId, path.SourceInfo.Id)
+		if err != nil {
+			continue
+		}
+		d, ok := display.FindByDeviceName(displays, name)
+		if !ok {
+			continue
+		}
+		targets = append(targets, target{display: d, target: path.TargetInfo})
+	}
 
-type unionModeInfo struct {
-	targetMode DISPLAYCONFIG_TARGET_MODE
-	sourceMode DISPLAYCONFIG_SOURCE_MODE
+	if len(targets) == 0 {
+		return nil, errors.New("no active displays found")
+	}
+	return targets, nil
 }
 
-// HDR struct controls Windows HDR settings
-type HDR struct{}
+// SetHDR enables or disables HDR on the selected displays, waits until each
+// display reports the new state and returns the outcome per display
+func (h *HDR) SetHDR(enable bool, sel display.Selector) ([]Result, error) {
+	targets, err := activeTargets(sel)
+	if err != nil {
+		return nil, err
+	}
 
-// NewHDR creates a new HDR controller
-func NewHDR() *HDR {
-	return &HDR{}
+	results := make([]Result, 0, len(targets))
+	for _, t := range targets {
+		results = append(results, h.applyVerified(t, enable))
+	}
+	return results, nil
 }
 
-var (
-	user32 = syscall.NewLazyDLL("user32.dll")
-
-	procGetDisplayConfigBufferSizes = user32.NewProc("GetDisplayConfigBufferSizes")
-	procQueryDisplayConfig          = user32.NewProc("QueryDisplayConfig")
-	procDisplayConfigSetDeviceInfo  = user32.NewProc("DisplayConfigSetDeviceInfo")
-)
+// setHDRForDisplay sets HDR state for a specific display target. Windows 11
+// 24H2 takes SET_HDR_STATE, older builds only have SET_ADVANCED_COLOR_STATE,
+// which on 24H2 switches wide color instead of HDR on some displays.
+func (h *HDR) setHDRForDisplay(target *dc.DISPLAYCONFIG_PATH_TARGET_INFO, enable bool) error {
+	var value uint32
+	if enable {
+		value = 1
+	}
 
-// SetHDR enables or disables HDR on all compatible displays
-func (h *HDR) SetHDR(enable bool) error {
-	var pathCount, modeCount uint32
+	// SET_HDR_STATE and SET_ADVANCED_COLOR_STATE share the same layout
+	state := dc.DISPLAYCONFIG_SET_HDR_STATE{Value: value}
+	state.Header.Type, _ = h.hdrRequest()
+	state.Header.Size = uint32(unsafe.Sizeof(state))
+	state.Header.AdapterId = target.AdapterId
+	state.Header.Id = target.Id
+
+	// Set the HDR state, falling back to the legacy request when 24H2's is
+	// rejected
+	err := h.device.Set(&state.Header)
+	if err != nil && h.api == colorAPI24H2 && rejectedRequest(err) {
+		state.Header.Type = dc.DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE
+		if h.device.Set(&state.Header) == nil {
+			h.api = colorAPILegacy
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set HDR state: %w", err)
+	}
 
-	// Get buffer sizes
-	ret, _, err := procGetDisplayConfigBufferSizes.Call(
-		uintptr(QDC_ONLY_ACTIVE_PATHS),
-		uintptr(unsafe.Pointer(&pathCount)),
-		uintptr(unsafe.Pointer(&modeCount)),
-	)
+	return nil
+}
 
-	if ret != 0 {
-		return errors.New("failed to get display config buffer sizes: " + err.Error())
+// hdrRequest returns the DisplayConfigSetDeviceInfo request type that
+// switches HDR on this Windows build, and its name
+func (h *HDR) hdrRequest() (uint32, string) {
+	if h.api == colorAPI24H2 {
+		return dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE, "DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE"
 	}
+	return dc.DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE, "DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE"
+}
 
-	if pathCount == 0 || modeCount == 0 {
-		return errors.New("no active displays found")
+// setACMForDisplay sets Auto Color Management for a specific display target
+func (h *HDR) setACMForDisplay(target *dc.DISPLAYCONFIG_PATH_TARGET_INFO, enable bool) error {
+	if h.api != colorAPI24H2 {
+		return fmt.Errorf("Auto Color Management requires Windows 11 24H2 (build %d) or later", BUILD_WIN11_24H2)
 	}
 
-	// Allocate arrays for paths and modes
-	paths := make([]DISPLAYCONFIG_PATH_INFO, pathCount)
-	modes := make([]DISPLAYCONFIG_MODE_INFO, modeCount)
-
-	// Query current display config
-	ret, _, err = procQueryDisplayConfig.Call(
-		uintptr(QDC_ONLY_ACTIVE_PATHS),
-		uintptr(unsafe.Pointer(&pathCount)),
-		uintptr(unsafe.Pointer(&paths[0])),
-		uintptr(unsafe.Pointer(&modeCount)),
-		uintptr(unsafe.Pointer(&modes[0])),
-		uintptr(0),
-	)
+	state := dc.DISPLAYCONFIG_SET_WCG_STATE{}
+	state.Header.Type = dc.DISPLAYCONFIG_DEVICE_INFO_SET_WCG_STATE
+	state.Header.Size = uint32(unsafe.Sizeof(state))
+	state.Header.AdapterId = target.AdapterId
+	state.Header.Id = target.Id
+	if enable {
+		state.Value = 1
+	}
 
-	if ret != 0 {
-		return errors.New("failed to query display config: " + err.Error())
+	if err := h.device.Set(&state.Header); err != nil {
+		return fmt.Errorf("failed to set ACM state: %w", err)
 	}
+	return nil
+}
 
-	var lastError error
-	successCount := 0
+// state reads the advanced color state of a display target. When the 24H2
+// query is rejected the controller switches to the legacy API for good.
+func (h *HDR) state(target *dc.DISPLAYCONFIG_PATH_TARGET_INFO) (State, error) {
+	if h.api != colorAPI24H2 {
+		return h.legacyState(target)
+	}
 
-	// Apply HDR setting to each active display
-	for i := uint32(0); i < pathCount; i++ {
-		path := &paths[i]
-		if path.Target.TargetAvailable != 0 {
-			if err := h.setHDRForDisplay(&path.Target, enable); err != nil {
-				lastError = err
-			} else {
-				successCount++
+	var s State
+	info := dc.DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2{}
+	info.Header.Type = dc.DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO_2
+	info.Header.Size = uint32(unsafe.Sizeof(info))
+	info.Header.AdapterId = target.AdapterId
+	info.Header.Id = target.Id
+	if err := h.device.Get(&info.Header); err != nil {
+		if rejectedRequest(err) {
+			if legacy, legacyErr := h.legacyState(target); legacyErr == nil {
+				h.api = colorAPILegacy
+				return legacy, nil
 			}
 		}
+		return s, fmt.Errorf("failed to get advanced color info: %w", err)
 	}
+	s.HDRSupported = info.HighDynamicRangeSupported()
+	s.HDREnabled = info.HighDynamicRangeUserEnabled()
+	s.ACMSupported = info.WideColorSupported()
+	s.ACMEnabled = info.WideColorUserEnabled()
+	return s, nil
+}
 
-	if successCount == 0 && lastError != nil {
-		return lastError
+// legacyState reads the advanced color state with the pre-24H2 query
+func (h *HDR) legacyState(target *dc.DISPLAYCONFIG_PATH_TARGET_INFO) (State, error) {
+	var s State
+	info := dc.DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO{}
+	info.Header.Type = dc.DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO
+	info.Header.Size = uint32(unsafe.Sizeof(info))
+	info.Header.AdapterId = target.AdapterId
+	info.Header.Id = target.Id
+	if err := h.device.Get(&info.Header); err != nil {
+		return s, fmt.Errorf("failed to get advanced color info: %w", err)
 	}
+	s.HDRSupported = info.AdvancedColorSupported()
+	s.HDREnabled = info.AdvancedColorEnabled()
+	return s, nil
+}
 
-	if successCount == 0 {
-		return errors.New("no compatible displays were configured")
+// hdrEnabled reports whether HDR is on for a display target
+func (h *HDR) hdrEnabled(target *dc.DISPLAYCONFIG_PATH_TARGET_INFO) bool {
+	s, err := h.state(target)
+	return err == nil && s.HDREnabled
+}
+
+// States returns the advanced color state of the selected displays. A
+// display whose state can't be read gets an entry with Err set.
+func (h *HDR) States(sel display.Selector) ([]State, error) {
+	targets, err := activeTargets(sel)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	states := make([]State, 0, len(targets))
+	for _, t := range targets {
+		s, err := h.state(&t.target)
+		s.Display, s.Err = t.display, err
+		states = append(states, s)
+	}
+	return states, nil
 }
 
-// setHDRForDisplay sets HDR state for a specific display target
-func (h *HDR) setHDRForDisplay(target *DISPLAYCONFIG_PATH_TARGET_INFO, enable bool) error {
-	// Prepare the advanced color info structure
-	colorInfo := DISPLAYCONFIG_ADVANCED_COLOR_INFO{}
-	colorInfo.Header.Type = DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_INFO
-	colorInfo.Header.Size = uint32(unsafe.Sizeof(colorInfo))
-	colorInfo.Header.AdapterId = target.AdapterId
-	colorInfo.Header.Id = target.Id
-
-	if enable {
-		colorInfo.Value = 1 // Enable HDR
-	} else {
-		colorInfo.Value = 0 // Disable HDR
+// SetACM enables or disables Auto Color Management on the selected displays
+// and returns the outcome per display
+func (h *HDR) SetACM(enable bool, sel display.Selector) ([]Result, error) {
+	if h.api != colorAPI24H2 {
+		return nil, fmt.Errorf("Auto Color Management requires Windows 11 24H2 (build %d) or later", BUILD_WIN11_24H2)
 	}
 
-	// Set the HDR state
-	ret, _, err := procDisplayConfigSetDeviceInfo.Call(
-		uintptr(unsafe.Pointer(&colorInfo.Header)),
-	)
+	targets, err := activeTargets(sel)
+	if err != nil {
+		return nil, err
+	}
 
-	if ret != 0 {
-		return errors.New("failed to set HDR state: " + err.Error())
+	results := make([]Result, 0, len(targets))
+	for _, t := range targets {
+		r := Result{Display: t.display, Action: "ACM " + onOff(enable, true)}
+		s, err := h.state(&t.target)
+		switch {
+		case err != nil:
+			r.Outcome, r.Err = Failure, err
+		case !s.ACMSupported:
+			r.Outcome = Unsupported
+		case s.ACMEnabled == enable:
+			r.Outcome = AlreadySet
+		default:
+			r.Attempts = 1
+			if err := h.setACMForDisplay(&t.target, enable); err != nil {
+				r.Outcome, r.Err = Failure, err
+			} else {
+				r.Outcome = Changed
+			}
+		}
+		results = append(results, r)
 	}
+	return results, nil
+}
 
-	return nil
+// ACMSupported reports whether this Windows build has Auto Color Management
+func (h *HDR) ACMSupported() bool {
+	return h.api == colorAPI24H2
 }
 
-// Enable turns on HDR for all compatible displays
-func (h *HDR) Enable() error {
-	return h.SetHDR(true)
+// Enable turns on HDR for the selected displays
+func (h *HDR) Enable(sel display.Selector) ([]Result, error) {
+	return h.SetHDR(true, sel)
 }
 
-// Disable turns off HDR for all compatible displays
-func (h *HDR) Disable() error {
-	return h.SetHDR(false)
+// Disable turns off HDR for the selected displays
+func (h *HDR) Disable(sel display.Selector) ([]Result, error) {
+	return h.SetHDR(false, sel)
 }
 
-// Toggle switches HDR state on all compatible displays
-func (h *HDR) Toggle() error {
-	// For simplicity, we'll just try to disable first, then enable if that fails
-	// In a real implementation, you'd query the current state first
-	err := h.Disable()
+// Toggle switches HDR on each selected display based on its current state
+func (h *HDR) Toggle(sel display.Selector) ([]Result, error) {
+	targets, err := activeTargets(sel)
 	if err != nil {
-		return h.Enable()
+		return nil, err
 	}
-	return nil
+
+	results := make([]Result, 0, len(targets))
+	for _, t := range targets {
+		s, err := h.state(&t.target)
+		if err != nil {
+			results = append(results, Result{Display: t.display, Action: "HDR toggle", Outcome: Failure, Err: err})
+			continue
+		}
+		results = append(results, h.applyVerified(t, !s.HDREnabled))
+	}
+	return results, nil
 }
 
-// GetState returns placeholder HDR state information
+// Failed returns the errors of the failed results joined together, or nil
+// when every display was changed, already set or doesn't support HDR
+func Failed(results []Result) error {
+	var errs []error
+	for _, r := range results {
+		if r.Outcome == Failure {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Display.Label(), r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetState returns the HDR and ACM state of every display as text
 func (h *HDR) GetState() ([]string, error) {
-	// This would require DisplayConfigGetDeviceInfo to get current state
-	// For now, return a placeholder
-	return []string{"HDR state detection not implemented"}, nil
+	states, err := h.States(display.All)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(states))
+	for _, s := range states {
+		if s.Err != nil {
+			lines = append(lines, s.Display.Label()+": "+s.Err.Error())
+			continue
+		}
+		line := s.Display.Label() + ": HDR " + s.HDRStatus()
+		if h.ACMSupported() {
+			line += ", ACM " + s.ACMStatus()
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// HDRStatus returns "on", "off", "not supported" or "unknown"
+func (s State) HDRStatus() string {
+	if s.Err != nil {
+		return "unknown"
+	}
+	return onOff(s.HDREnabled, s.HDRSupported)
+}
+
+// ACMStatus returns "on", "off", "not supported" or "unknown"
+func (s State) ACMStatus() string {
+	if s.Err != nil {
+		return "unknown"
+	}
+	return onOff(s.ACMEnabled, s.ACMSupported)
+}
+
+// onOff describes a feature state
+func onOff(enabled, supported bool) string {
+	switch {
+	case !supported:
+		return "not supported"
+	case enabled:
+		return "on"
+	default:
+		return "off"
+	}
 }
 
 // IsHDRSupported checks if HDR operations are likely supported
 func (h *HDR) IsHDRSupported() bool {
 	// Try a simple operation to see if the API is available
-	var pathCount, modeCount uint32
-	ret, _, _ := procGetDisplayConfigBufferSizes.Call(
-		uintptr(QDC_ONLY_ACTIVE_PATHS),
-		uintptr(unsafe.Pointer(&pathCount)),
-		uintptr(unsafe.Pointer(&modeCount)),
-	)
-	return ret == 0
+	_, _, _, err := dc.Query(dc.QDC_ONLY_ACTIVE_PATHS)
+	return err == nil
 }
@@ -0,0 +1,201 @@
+package hdr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	dc "github.com/jipaix/lumos/displayconfig"
+)
+
+const (
+	errAccessDenied = syscall.Errno(5)
+	errGenFailure   = syscall.Errno(31)
+)
+
+// fakeDevice is an HDR capable display driver that rejects some requests
+type fakeDevice struct {
+	reject   map[uint32]syscall.Errno // Request types that fail, and how
+	hdr      bool                     // Current HDR state
+	sets     []uint32                 // Set request types received
+	failures int                      // Set calls that fail before one succeeds
+
+	// With a clock, queries keep reporting the old state for settle after a switch
+	clock   *fakeClock
+	settle  time.Duration
+	old     bool
+	readyAt time.Time
+}
+
+// reported returns the HDR state queries see
+func (d *fakeDevice) reported() bool {
+	if d.clock != nil && d.clock.now.Before(d.readyAt) {
+		return d.old
+	}
+	return d.hdr
+}
+
+func (d *fakeDevice) Get(header *dc.DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	if errno, ok := d.reject[header.Type]; ok {
+		return fmt.Errorf("failed to get display device info (type %d): %w", header.Type, errno)
+	}
+
+	switch header.Type {
+	case dc.DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO_2:
+		info := (*dc.DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO_2)(unsafe.Pointer(header))
+		info.Value = 1<<0 | 1<<4 | 1<<6 // Advanced color, HDR and wide color supported
+		if d.reported() {
+			info.Value |= 1<<1 | 1<<5
+		}
+	case dc.DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO:
+		info := (*dc.DISPLAYCONFIG_GET_ADVANCED_COLOR_INFO)(unsafe.Pointer(header))
+		info.Value = 1 << 0
+		if d.reported() {
+			info.Value |= 1 << 1
+		}
+	default:
+		return fmt.Errorf("unexpected get request %d", header.Type)
+	}
+	return nil
+}
+
+func (d *fakeDevice) Set(header *dc.DISPLAYCONFIG_DEVICE_INFO_HEADER) error {
+	d.sets = append(d.sets, header.Type)
+	if errno, ok := d.reject[header.Type]; ok {
+		return fmt.Errorf("failed to set display device info (type %d): %w", header.Type, errno)
+	}
+
+	if d.failures > 0 {
+		d.failures--
+		return fmt.Errorf("failed to set display device info (type %d): %w", header.Type, errGenFailure)
+	}
+
+	switch header.Type {
+	case dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE, dc.DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE:
+		d.old = d.reported()
+		d.hdr = (*dc.DISPLAYCONFIG_SET_HDR_STATE)(unsafe.Pointer(header)).Value&1 != 0
+		if d.clock != nil {
+			d.readyAt = d.clock.now.Add(d.settle)
+		}
+	default:
+		return fmt.Errorf("unexpected set request %d", header.Type)
+	}
+	return nil
+}
+
+// fakeClock is a Clock whose Sleep advances the time instantly
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+// newFakeHDR returns a controller for a build that drives device on a fake clock
+func newFakeHDR(build uint32, device *fakeDevice) (*HDR, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	h := newHDRForBuild(build)
+	h.device, h.clock = device, clock
+	return h, clock
+}
+
+func TestNewHDRForBuild(t *testing.T) {
+	tests := []struct {
+		build   uint32
+		api     colorAPI
+		request string
+	}{
+		{0, colorAPILegacy, "DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE"},
+		{22631, colorAPILegacy, "DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE"},
+		{BUILD_WIN11_24H2, colorAPI24H2, "DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE"},
+		{26200, colorAPI24H2, "DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE"},
+	}
+	for _, tt := range tests {
+		h := newHDRForBuild(tt.build)
+		_, request := h.hdrRequest()
+		if h.api != tt.api || request != tt.request || h.ACMSupported() != (tt.api == colorAPI24H2) {
+			t.Errorf("build %d: api %v, request %s, ACM %v, want %v, %s", tt.build, h.api, request, h.ACMSupported(), tt.api, tt.request)
+		}
+	}
+}
+
+func TestLegacyFallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		reject map[uint32]syscall.Errno
+		sets   []uint32
+		api    colorAPI
+	}{
+		{
+			name: "24H2 requests accepted",
+			sets: []uint32{dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE},
+			api:  colorAPI24H2,
+		},
+		{
+			name: "24H2 query not supported",
+			reject: map[uint32]syscall.Errno{
+				dc.DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO_2: dc.ERROR_NOT_SUPPORTED,
+				dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE:             dc.ERROR_NOT_SUPPORTED,
+			},
+			sets: []uint32{dc.DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE},
+			api:  colorAPILegacy,
+		},
+		{
+			name: "24H2 switch invalid",
+			reject: map[uint32]syscall.Errno{
+				dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE: dc.ERROR_INVALID_PARAMETER,
+			},
+			sets: []uint32{dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE, dc.DISPLAYCONFIG_DEVICE_INFO_SET_ADVANCED_COLOR_STATE},
+			api:  colorAPILegacy,
+		},
+	}
+	for _, tt := range tests {
+		device := &fakeDevice{reject: tt.reject}
+		h, _ := newFakeHDR(BUILD_WIN11_24H2, device)
+
+		r := h.applyVerified(target{}, true)
+		if r.Outcome != Changed || r.Err != nil || r.Attempts != 1 || !device.hdr {
+			t.Errorf("%s: got %v after %d attempts (%v), HDR %v, want changed after 1", tt.name, r.Outcome, r.Attempts, r.Err, device.hdr)
+		}
+		if !slices.Equal(device.sets, tt.sets) {
+			t.Errorf("%s: set requests %v, want %v", tt.name, device.sets, tt.sets)
+		}
+		if h.api != tt.api {
+			t.Errorf("%s: api %v after the change, want %v", tt.name, h.api, tt.api)
+		}
+	}
+}
+
+func TestNoFallbackForOtherErrors(t *testing.T) {
+	device := &fakeDevice{reject: map[uint32]syscall.Errno{
+		dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE: errAccessDenied,
+	}}
+	h, _ := newFakeHDR(BUILD_WIN11_24H2, device)
+
+	r := h.applyVerified(target{}, true)
+	if r.Outcome != Failure || r.Code() != errAccessDenied || !errors.Is(r.Err, errAccessDenied) {
+		t.Errorf("got %v (%v, code %d), want a failure with ERROR_ACCESS_DENIED", r.Outcome, r.Err, r.Code())
+	}
+	want := slices.Repeat([]uint32{dc.DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE}, DefaultRetryPolicy.Attempts)
+	if !slices.Equal(device.sets, want) || h.api != colorAPI24H2 {
+		t.Errorf("set requests %v on the %v api, want %v on 24H2", device.sets, h.api, want)
+	}
+
+	// Both queries failing keeps the 24H2 error, wrapped
+	device.reject = map[uint32]syscall.Errno{
+		dc.DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO_2: dc.ERROR_NOT_SUPPORTED,
+		dc.DISPLAYCONFIG_DEVICE_INFO_GET_ADVANCED_COLOR_INFO:   errAccessDenied,
+	}
+	if _, err := h.state(&dc.DISPLAYCONFIG_PATH_TARGET_INFO{}); !errors.Is(err, syscall.Errno(dc.ERROR_NOT_SUPPORTED)) || h.api != colorAPI24H2 {
+		t.Errorf("state: got %v on the %v api, want ERROR_NOT_SUPPORTED on 24H2", err, h.api)
+	}
+}
@@ -0,0 +1,80 @@
+package hdr
+
+import (
+	"fmt"
+
+	"github.com/jipaix/lumos/display"
+	dc "github.com/jipaix/lumos/displayconfig"
+)
+
+// Call is a DisplayConfigSetDeviceInfo request computed by PlanSetHDR or
+// PlanToggle. Nothing is changed until the plan is applied with SetHDR.
+type Call struct {
+	Display   display.Display
+	Action    string  // e.g. "HDR on"
+	Outcome   Outcome // Changed when the call would be made
+	Err       error   // Set when the state couldn't be read
+	Request   string  // e.g. "DISPLAYCONFIG_DEVICE_INFO_SET_HDR_STATE"
+	Type      uint32
+	AdapterId dc.LUID
+	TargetId  uint32
+	Value     uint32
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("DisplayConfigSetDeviceInfo(%s (%d), adapter %08X:%08X, target %d, value %d)",
+		c.Request, c.Type, uint32(c.AdapterId.HighPart), c.AdapterId.LowPart, c.TargetId, c.Value)
+}
+
+// PlanSetHDR computes the calls SetHDR would make without changing anything
+func (h *HDR) PlanSetHDR(enable bool, sel display.Selector) ([]Call, error) {
+	return h.plan(sel, func(State) bool { return enable })
+}
+
+// PlanToggle computes the calls Toggle would make without changing anything
+func (h *HDR) PlanToggle(sel display.Selector) ([]Call, error) {
+	return h.plan(sel, func(s State) bool { return !s.HDREnabled })
+}
+
+// plan computes one call per selected display, desired returns the HDR
+// state to switch the display to
+func (h *HDR) plan(sel display.Selector, desired func(State) bool) ([]Call, error) {
+	targets, err := activeTargets(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]Call, 0, len(targets))
+	for _, t := range targets {
+		c := Call{
+			Display:   t.display,
+			AdapterId: t.target.AdapterId,
+			TargetId:  t.target.Id,
+		}
+
+		// Reading the state can switch the controller to the legacy API
+		s, err := h.state(&t.target)
+		c.Type, c.Request = h.hdrRequest()
+		if err != nil {
+			c.Action, c.Outcome, c.Err = "HDR", Failure, err
+			calls = append(calls, c)
+			continue
+		}
+
+		enable := desired(s)
+		c.Action = "HDR " + onOff(enable, true)
+		if enable {
+			c.Value = 1
+		}
+		switch {
+		case !s.HDRSupported:
+			c.Outcome = Unsupported
+		case s.HDREnabled == enable:
+			c.Outcome = AlreadySet
+		default:
+			c.Outcome = Changed
+		}
+		calls = append(calls, c)
+	}
+	return calls, nil
+}
@@ -0,0 +1,186 @@
+package hdr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/display"
+	dc "github.com/jipaix/lumos/displayconfig"
+)
+
+// SDR white level range of the Windows "SDR content brightness" slider. The
+// API uses 1000 units per 80 nits, the slider maps 0-100% to 80-480 nits.
+const (
+	SDRWhiteLevelUnit = 1000 // API units for 80 nits
+	SDRMinNits        = 80.0
+	SDRMaxNits        = 480.0
+)
+
+// NitsToSDRWhiteLevel converts nits to DISPLAYCONFIG SDR white level units,
+// clamped to the slider range
+func NitsToSDRWhiteLevel(nits float64) uint32 {
+	return uint32(math.Round(clampNits(nits) / 80 * SDRWhiteLevelUnit))
+}
+
+// SDRWhiteLevelToNits converts DISPLAYCONFIG SDR white level units to nits
+func SDRWhiteLevelToNits(level uint32) float64 {
+	return float64(level) * 80 / SDRWhiteLevelUnit
+}
+
+// PercentToNits converts a slider percentage (0-100) to nits, clamping
+// percentages outside the slider
+func PercentToNits(percent float64) float64 {
+	return SDRMinNits + math.Max(0, math.Min(percent, 100))/100*(SDRMaxNits-SDRMinNits)
+}
+
+// NitsToPercent converts nits to a slider percentage (0-100), clamping
+// levels set outside the slider
+func NitsToPercent(nits float64) float64 {
+	return (clampNits(nits) - SDRMinNits) / (SDRMaxNits - SDRMinNits) * 100
+}
+
+// clampNits limits nits to the slider range
+func clampNits(nits float64) float64 {
+	return math.Max(SDRMinNits, math.Min(nits, SDRMaxNits))
+}
+
+// ParseSDRBrightness parses an SDR brightness given as a percentage ("40" or
+// "40%") or in nits ("200nits") and returns it in nits. Percentages are slider
+// positions with a linear scale, otherwise a fraction of SDRMaxNits through
+// scale, clamped to the slider range.
+func ParseSDRBrightness(s string, scale brightness.Scale) (float64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+
+	unit := "%"
+	for _, suffix := range []string{"nits", "nit", "%"} {
+		if strings.HasSuffix(value, suffix) {
+			unit = suffix
+			value = strings.TrimSpace(strings.TrimSuffix(value, suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(n) {
+		return 0, fmt.Errorf("invalid SDR brightness: %s (use a percentage like 40 or nits like 200nits)", s)
+	}
+
+	if unit == "%" {
+		if n < 0 || n > 100 {
+			return 0, fmt.Errorf("SDR brightness must be between 0 and 100%%, got %s", s)
+		}
+		if scale.IsLinear() {
+			return PercentToNits(n), nil
+		}
+		return clampNits(scale.Level(n) * SDRMaxNits), nil
+	}
+
+	if n < SDRMinNits || n > SDRMaxNits {
+		return 0, fmt.Errorf("SDR brightness must be between %.0f and %.0f nits, got %s", SDRMinNits, SDRMaxNits, s)
+	}
+	return n, nil
+}
+
+// SDRWhiteLevel is the SDR content brightness of a display
+type SDRWhiteLevel struct {
+	Display    display.Display
+	Level      uint32 // API units, 1000 = 80 nits
+	HDREnabled bool   // The level only applies while HDR is on
+	Err        error  // Set when the level couldn't be read
+}
+
+// Nits returns the white level in nits
+func (l SDRWhiteLevel) Nits() float64 {
+	return SDRWhiteLevelToNits(l.Level)
+}
+
+// Percent returns the white level as a slider percentage
+func (l SDRWhiteLevel) Percent() float64 {
+	return NitsToPercent(l.Nits())
+}
+
+// SDRWhiteLevels returns the SDR white level of the selected displays. A
+// display whose level can't be read gets an entry with Err set.
+func (h *HDR) SDRWhiteLevels(sel display.Selector) ([]SDRWhiteLevel, error) {
+	targets, err := activeTargets(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([]SDRWhiteLevel, 0, len(targets))
+	for _, t := range targets {
+		level, err := getSDRWhiteLevel(&t.target)
+		levels = append(levels, SDRWhiteLevel{
+			Display:    t.display,
+			Level:      level,
+			HDREnabled: err == nil && h.hdrEnabled(&t.target),
+			Err:        err,
+		})
+	}
+	return levels, nil
+}
+
+// SetSDRWhiteLevel sets the SDR white level of the selected displays in nits
+// and returns the outcome per display
+func (h *HDR) SetSDRWhiteLevel(nits float64, sel display.Selector) ([]Result, error) {
+	if nits < SDRMinNits || nits > SDRMaxNits {
+		return nil, fmt.Errorf("SDR white level must be between %.0f and %.0f nits", SDRMinNits, SDRMaxNits)
+	}
+
+	targets, err := activeTargets(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	level := NitsToSDRWhiteLevel(nits)
+	action := fmt.Sprintf("SDR brightness %.0f%% (%.0f nits)", NitsToPercent(nits), nits)
+
+	results := make([]Result, 0, len(targets))
+	for _, t := range targets {
+		r := Result{Display: t.display, Action: action}
+		if current, err := getSDRWhiteLevel(&t.target); err == nil && current == level {
+			r.Outcome = AlreadySet
+		} else if err := setSDRWhiteLevel(&t.target, level); err != nil {
+			r.Outcome, r.Err, r.Attempts = Failure, err, 1
+		} else {
+			r.Outcome, r.Attempts = Changed, 1
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// getSDRWhiteLevel reads the SDR white level of a display target
+func getSDRWhiteLevel(target *dc.DISPLAYCONFIG_PATH_TARGET_INFO) (uint32, error) {
+	info := dc.DISPLAYCONFIG_SDR_WHITE_LEVEL{}
+	info.Header.Type = dc.DISPLAYCONFIG_DEVICE_INFO_GET_SDR_WHITE_LEVEL
+	info.Header.Size = uint32(unsafe.Sizeof(info))
+	info.Header.AdapterId = target.AdapterId
+	info.Header.Id = target.Id
+
+	if err := dc.GetDeviceInfo(&info.Header); err != nil {
+		return 0, errors.New("failed to get SDR white level: " + err.Error())
+	}
+	return info.SDRWhiteLevel, nil
+}
+
+// setSDRWhiteLevel sets the SDR white level of a display target
+func setSDRWhiteLevel(target *dc.DISPLAYCONFIG_PATH_TARGET_INFO, level uint32) error {
+	info := dc.DISPLAYCONFIG_SET_SDR_WHITE_LEVEL{}
+	info.Header.Type = dc.DISPLAYCONFIG_DEVICE_INFO_SET_SDR_WHITE_LEVEL
+	info.Header.Size = uint32(unsafe.Sizeof(info))
+	info.Header.AdapterId = target.AdapterId
+	info.Header.Id = target.Id
+	info.SDRWhiteLevel = level
+	info.FinalValue = 1
+
+	if err := dc.SetDeviceInfo(&info.Header); err != nil {
+		return errors.New("failed to set SDR white level: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package hdr
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/jipaix/lumos/brightness"
+	"github.com/jipaix/lumos/gamma"
+)
+
+func TestSDRWhiteLevelUnits(t *testing.T) {
+	tests := []struct {
+		nits  float64
+		level uint32
+	}{
+		{80, 1000},
+		{200, 2500},
+		{480, 6000},
+		{0, 1000},    // Below the slider
+		{-50, 1000},  // Negative
+		{1000, 6000}, // Above the slider
+	}
+	for _, tt := range tests {
+		if got := NitsToSDRWhiteLevel(tt.nits); got != tt.level {
+			t.Errorf("NitsToSDRWhiteLevel(%v) = %d, want %d", tt.nits, got, tt.level)
+		}
+	}
+
+	for _, level := range []uint32{1000, 2500, 6000} {
+		if got := NitsToSDRWhiteLevel(SDRWhiteLevelToNits(level)); got != level {
+			t.Errorf("level %d round trips to %d", level, got)
+		}
+	}
+	if got := SDRWhiteLevelToNits(1000); got != 80 {
+		t.Errorf("SDRWhiteLevelToNits(1000) = %v, want 80", got)
+	}
+}
+
+func TestSDRPercent(t *testing.T) {
+	tests := []struct {
+		percent float64
+		nits    float64
+	}{
+		{0, 80},
+		{30, 200},
+		{100, 480},
+	}
+	for _, tt := range tests {
+		if got := PercentToNits(tt.percent); got != tt.nits {
+			t.Errorf("PercentToNits(%v) = %v, want %v", tt.percent, got, tt.nits)
+		}
+		if got := NitsToPercent(tt.nits); math.Abs(got-tt.percent) > 1e-9 {
+			t.Errorf("NitsToPercent(%v) = %v, want %v", tt.nits, got, tt.percent)
+		}
+	}
+
+	clamped := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"PercentToNits(-10)", PercentToNits(-10), 80},
+		{"PercentToNits(150)", PercentToNits(150), 480},
+		{"NitsToPercent(40)", NitsToPercent(40), 0},
+		{"NitsToPercent(600)", NitsToPercent(600), 100},
+	}
+	for _, c := range clamped {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	l := SDRWhiteLevel{Level: 2500}
+	if l.Nits() != 200 || math.Abs(l.Percent()-30) > 1e-9 {
+		t.Errorf("level 2500 = %v nits, %v%%, want 200 nits, 30%%", l.Nits(), l.Percent())
+	}
+}
+
+func TestParseSDRBrightness(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"0", 80},
+		{"30%", 200},
+		{"100", 480},
+		{" 200nits ", 200},
+		{"80 nit", 80},
+		{"480NITS", 480},
+	}
+	for _, tt := range tests {
+		got, err := ParseSDRBrightness(tt.s, brightness.Linear)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSDRBrightness(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "bright", "NaN", "-1", "101%", "79nits", "481nits"} {
+		if _, err := ParseSDRBrightness(s, brightness.Linear); err == nil {
+			t.Errorf("ParseSDRBrightness(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestParseSDRBrightnessScale(t *testing.T) {
+	tests := []struct {
+		s     string
+		scale brightness.Scale
+		want  float64
+	}{
+		{"50", brightness.Lightness, 88.41},  // 18.4% of 480 nits
+		{"75", brightness.Lightness, 231.73}, // 48.3%
+		{"0", brightness.Lightness, 80},      // Below the slider
+		{"50", brightness.Power, 104.47},
+		{"100", brightness.Power, 480},
+		{"200nits", brightness.Power, 200}, // Nits aren't scaled
+	}
+	for _, tt := range tests {
+		got, err := ParseSDRBrightness(tt.s, tt.scale)
+		if err != nil || math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("ParseSDRBrightness(%q, %s) = %v, %v, want %v", tt.s, tt.scale, got, err, tt.want)
+		}
+	}
+}
+
+// TestScaleLuminance checks that a perceptual percentage gives white the
+// same fraction of the peak luminance through the SDR slider and the gamma
+// ramp, wherever neither is clamped to its minimum
+func TestScaleLuminance(t *testing.T) {
+	for _, scale := range []brightness.Scale{brightness.Lightness, brightness.Power} {
+		for percent := 0; percent <= 100; percent++ {
+			level := scale.Level(float64(percent))
+
+			nits, err := ParseSDRBrightness(strconv.Itoa(percent), scale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sdr := nits / SDRMaxNits
+
+			p := gamma.Pipeline{Brightness: &gamma.BrightnessLayer{Percent: percent, Scale: scale}}
+			ramp, err := p.Ramp()
+			if err != nil {
+				t.Fatalf("%s %d%%: %v", scale, percent, err)
+			}
+			ramped := math.Pow(float64(ramp.Green[255])/65535, 2.2)
+
+			if math.Abs(sdr-math.Max(level, SDRMinNits/SDRMaxNits)) > 1e-9 {
+				t.Errorf("%s %d%%: SDR white at %.4f of the peak, want %.4f", scale, percent, sdr, level)
+			}
+			if math.Abs(ramped-math.Max(level, gamma.MinPeakLuminance)) > 1e-4 {
+				t.Errorf("%s %d%%: ramp white at %.4f of the peak, want %.4f", scale, percent, ramped, level)
+			}
+			if level >= SDRMinNits/SDRMaxNits && math.Abs(sdr-ramped) > 1e-4 {
+				t.Errorf("%s %d%%: SDR white at %.4f, ramp white at %.4f", scale, percent, sdr, ramped)
+			}
+		}
+	}
+}
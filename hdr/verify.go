@@ -0,0 +1,147 @@
+package hdr
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/jipaix/lumos/display"
+)
+
+// Clock provides time to the verify and retry loop so it can run on a fake
+// clock in tests
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// systemClock is the real time Clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// RetryPolicy controls how HDR changes are verified and retried. The GPU
+// reconfigures asynchronously, so a successful call doesn't mean the
+// display is already in the new state, and calls made while a previous
+// change is still in progress can fail.
+type RetryPolicy struct {
+	Attempts     int           // Set calls per display before giving up, at least 1
+	Backoff      time.Duration // Delay before the first retry, doubled for each retry
+	Timeout      time.Duration // How long to wait for the state to change after a set
+	PollInterval time.Duration // Delay between state reads while waiting
+}
+
+// DefaultRetryPolicy is used by NewHDR
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:     3,
+	Backoff:      250 * time.Millisecond,
+	Timeout:      3 * time.Second,
+	PollInterval: 100 * time.Millisecond,
+}
+
+// Outcome is the result of an HDR change on one display
+type Outcome int
+
+const (
+	Changed Outcome = iota
+	AlreadySet
+	Unsupported
+	Failure
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Changed:
+		return "changed"
+	case AlreadySet:
+		return "already set"
+	case Unsupported:
+		return "not supported"
+	case Failure:
+		return "failed"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// Result reports how a display responded to an HDR, ACM or SDR white level
+// change
+type Result struct {
+	Display  display.Display
+	Action   string // e.g. "HDR on", "ACM off" or "SDR brightness 40% (240 nits)"
+	Outcome  Outcome
+	Attempts int   // Set calls made, 0 when nothing had to change
+	Err      error // Set for Failure
+}
+
+// Code returns the Win32 error code of a failed change, or 0
+func (r Result) Code() syscall.Errno {
+	var errno syscall.Errno
+	if errors.As(r.Err, &errno) {
+		return errno
+	}
+	return 0
+}
+
+// errNotApplied is returned when the display didn't reach the requested
+// state before the timeout
+var errNotApplied = errors.New("display did not switch before the timeout")
+
+// applyVerified sets the HDR state of a target, waits until the display
+// reports it and retries with backoff on failure
+func (h *HDR) applyVerified(t target, enable bool) Result {
+	r := Result{Display: t.display, Action: "HDR " + onOff(enable, true)}
+
+	s, err := h.state(&t.target)
+	if err != nil {
+		r.Outcome, r.Err = Failure, err
+		return r
+	}
+	if !s.HDRSupported {
+		r.Outcome = Unsupported
+		return r
+	}
+	if s.HDREnabled == enable {
+		r.Outcome = AlreadySet
+		return r
+	}
+
+	backoff := h.retry.Backoff
+	for r.Attempts < max(h.retry.Attempts, 1) {
+		if r.Attempts > 0 {
+			h.clock.Sleep(backoff)
+			backoff *= 2
+		}
+		r.Attempts++
+
+		if err = h.setHDRForDisplay(&t.target, enable); err == nil {
+			if err = h.waitForHDR(&t, enable); err == nil {
+				r.Outcome = Changed
+				return r
+			}
+		}
+	}
+
+	r.Outcome, r.Err = Failure, err
+	return r
+}
+
+// waitForHDR polls the HDR state of a target until it matches enable or the
+// timeout expires
+func (h *HDR) waitForHDR(t *target, enable bool) error {
+	deadline := h.clock.Now().Add(h.retry.Timeout)
+	for {
+		s, err := h.state(&t.target)
+		if err == nil && s.HDREnabled == enable {
+			return nil
+		}
+		if !h.clock.Now().Before(deadline) {
+			if err != nil {
+				return err
+			}
+			return errNotApplied
+		}
+		h.clock.Sleep(h.retry.PollInterval)
+	}
+}
@@ -0,0 +1,121 @@
+package hdr
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestApplyVerified(t *testing.T) {
+	policy := RetryPolicy{
+		Attempts:     3,
+		Backoff:      250 * time.Millisecond,
+		Timeout:      time.Second,
+		PollInterval: 100 * time.Millisecond,
+	}
+	polls := func(n int) []time.Duration {
+		return slices.Repeat([]time.Duration{policy.PollInterval}, n)
+	}
+
+	tests := []struct {
+		name     string
+		attempts int // Overrides policy.Attempts when set
+		failures int
+		settle   time.Duration
+		outcome  Outcome
+		err      error
+		made     int
+		sleeps   []time.Duration
+	}{
+		{
+			name:    "applied right away",
+			outcome: Changed,
+			made:    1,
+		},
+		{
+			name:    "settles after polling",
+			settle:  450 * time.Millisecond,
+			outcome: Changed,
+			made:    1,
+			sleeps:  polls(5),
+		},
+		{
+			name:     "retried with backoff",
+			failures: 2,
+			outcome:  Changed,
+			made:     3,
+			sleeps:   []time.Duration{250 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:     "retries exhausted",
+			failures: 3,
+			outcome:  Failure,
+			err:      errGenFailure,
+			made:     3,
+			sleeps:   []time.Duration{250 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:    "never settles",
+			settle:  time.Hour,
+			outcome: Failure,
+			err:     errNotApplied,
+			made:    3,
+			sleeps: slices.Concat(polls(10), []time.Duration{250 * time.Millisecond},
+				polls(10), []time.Duration{500 * time.Millisecond}, polls(10)),
+		},
+		{
+			name:     "zero attempts still tries once",
+			attempts: -1,
+			failures: 1,
+			outcome:  Failure,
+			err:      errGenFailure,
+			made:     1,
+		},
+		{
+			name:     "zero attempts succeeds",
+			attempts: -1,
+			outcome:  Changed,
+			made:     1,
+		},
+	}
+	for _, tt := range tests {
+		device := &fakeDevice{failures: tt.failures, settle: tt.settle}
+		h, clock := newFakeHDR(BUILD_WIN11_24H2, device)
+		device.clock = clock
+		h.retry = policy
+		if tt.attempts < 0 {
+			h.retry.Attempts = 0
+		}
+		start := clock.now
+
+		r := h.applyVerified(target{}, true)
+		if r.Outcome != tt.outcome || r.Attempts != tt.made {
+			t.Errorf("%s: got %v after %d attempts, want %v after %d", tt.name, r.Outcome, r.Attempts, tt.outcome, tt.made)
+		}
+		if (tt.err == nil) != (r.Err == nil) || (tt.err != nil && !errors.Is(r.Err, tt.err)) {
+			t.Errorf("%s: err = %v, want %v", tt.name, r.Err, tt.err)
+		}
+		if !slices.Equal(clock.sleeps, tt.sleeps) {
+			t.Errorf("%s: sleeps = %v, want %v", tt.name, clock.sleeps, tt.sleeps)
+		}
+
+		var total time.Duration
+		for _, d := range tt.sleeps {
+			total += d
+		}
+		if elapsed := clock.now.Sub(start); elapsed != total {
+			t.Errorf("%s: took %v, want %v", tt.name, elapsed, total)
+		}
+	}
+}
+
+func TestApplyVerifiedNoChange(t *testing.T) {
+	device := &fakeDevice{hdr: true}
+	h, clock := newFakeHDR(BUILD_WIN11_24H2, device)
+
+	r := h.applyVerified(target{}, true)
+	if r.Outcome != AlreadySet || r.Attempts != 0 || len(device.sets) != 0 || len(clock.sleeps) != 0 {
+		t.Errorf("got %v after %d attempts, %d sets, %d sleeps, want already set without calls", r.Outcome, r.Attempts, len(device.sets), len(clock.sleeps))
+	}
+}
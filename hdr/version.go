@@ -0,0 +1,32 @@
+package hdr
+
+// BUILD_WIN11_24H2 is the first Windows build with the split HDR and Auto
+// Color Management display APIs
+const BUILD_WIN11_24H2 = 26100
+
+// colorAPI selects which DisplayConfig requests control advanced color
+type colorAPI int
+
+const (
+	// colorAPILegacy uses GET_ADVANCED_COLOR_INFO and SET_ADVANCED_COLOR_STATE
+	colorAPILegacy colorAPI = iota
+	// colorAPI24H2 uses GET_ADVANCED_COLOR_INFO_2, SET_HDR_STATE and SET_WCG_STATE
+	colorAPI24H2
+)
+
+func (a colorAPI) String() string {
+	if a == colorAPI24H2 {
+		return "24H2"
+	}
+	return "legacy"
+}
+
+// colorAPIForBuild returns the advanced color API to use on a Windows build.
+// Build 0 means the build is unknown and uses the legacy API, which newer
+// builds still accept.
+func colorAPIForBuild(build uint32) colorAPI {
+	if build >= BUILD_WIN11_24H2 {
+		return colorAPI24H2
+	}
+	return colorAPILegacy
+}
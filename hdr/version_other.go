@@ -0,0 +1,8 @@
+//go:build !windows
+
+package hdr
+
+// osBuild returns 0, there is no Windows build outside of Windows
+func osBuild() uint32 {
+	return 0
+}
@@ -0,0 +1,39 @@
+package hdr
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	ntdll = syscall.NewLazyDLL("ntdll.dll")
+
+	procRtlGetVersion = ntdll.NewProc("RtlGetVersion")
+)
+
+// RTL_OSVERSIONINFOW structure
+type RTL_OSVERSIONINFOW struct {
+	OSVersionInfoSize uint32
+	MajorVersion      uint32
+	MinorVersion      uint32
+	BuildNumber       uint32
+	PlatformId        uint32
+	CSDVersion        [128]uint16
+}
+
+// osBuild returns the Windows build number, or 0 if it can't be read.
+// RtlGetVersion isn't subject to the manifest based version lie of
+// GetVersionEx.
+func osBuild() uint32 {
+	if procRtlGetVersion.Find() != nil {
+		return 0
+	}
+
+	info := RTL_OSVERSIONINFOW{}
+	info.OSVersionInfoSize = uint32(unsafe.Sizeof(info))
+	ret, _, _ := procRtlGetVersion.Call(uintptr(unsafe.Pointer(&info)))
+	if ret != 0 {
+		return 0
+	}
+	return info.BuildNumber
+}
@@ -0,0 +1,148 @@
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// sRGB colorants and white point adapted to D50, written as the colorimetry
+// of encoded profiles
+var (
+	d50       = [3]float64{0.9642, 1.0, 0.8249}
+	colorants = map[string][3]float64{
+		"rXYZ": {0.4361, 0.2225, 0.0139},
+		"gXYZ": {0.3851, 0.7169, 0.0971},
+		"bXYZ": {0.1431, 0.0606, 0.7141},
+	}
+)
+
+// Encode writes a version 2 display profile with sRGB colorimetry, the
+// description and the calibration curves of p as a 16 bit vcgt table
+func Encode(p *Profile) ([]byte, error) {
+	if p.VCGT == nil {
+		return nil, errors.New("profile has no calibration curves to encode")
+	}
+	size := len(p.VCGT.Curves[0])
+	if size < 2 || size > math.MaxUint16 {
+		return nil, errors.New("calibration curves must have between 2 and 65535 entries")
+	}
+	for _, curve := range p.VCGT.Curves {
+		if len(curve) != size {
+			return nil, errors.New("calibration curves must have the same number of entries")
+		}
+	}
+
+	desc := p.Description
+	if desc == "" {
+		desc = "lumos calibration"
+	}
+	trc := curvGamma(2.2)
+
+	tags := []struct {
+		sig  string
+		body []byte
+	}{
+		{"desc", textDescription(desc)},
+		{"cprt", text("No copyright, use freely")},
+		{"wtpt", xyz(d50)},
+		{"rXYZ", xyz(colorants["rXYZ"])},
+		{"gXYZ", xyz(colorants["gXYZ"])},
+		{"bXYZ", xyz(colorants["bXYZ"])},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+		{"vcgt", vcgtTableBody(p.VCGT)},
+	}
+
+	table := make([]byte, 4+12*len(tags))
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+	var body []byte
+	offset := HeaderSize + len(table)
+	for i, t := range tags {
+		entry := table[4+12*i:]
+		copy(entry[0:4], t.sig)
+		binary.BigEndian.PutUint32(entry[4:8], uint32(offset+len(body)))
+		binary.BigEndian.PutUint32(entry[8:12], uint32(len(t.body)))
+		body = append(body, t.body...)
+		for len(body)%4 != 0 {
+			body = append(body, 0) // Tags start on 4 byte boundaries
+		}
+	}
+
+	out := append(header(), table...)
+	out = append(out, body...)
+	binary.BigEndian.PutUint32(out[0:4], uint32(len(out)))
+	return out, nil
+}
+
+// header returns a display class RGB profile header without the size
+func header() []byte {
+	h := make([]byte, HeaderSize)
+	h[8], h[9] = 2, 0x10 // Version 2.1
+	copy(h[12:16], "mntr")
+	copy(h[16:20], "RGB ")
+	copy(h[20:24], "XYZ ")
+
+	now := time.Now().UTC()
+	for i, v := range []int{now.Year(), int(now.Month()), now.Day(), now.Hour(), now.Minute(), now.Second()} {
+		binary.BigEndian.PutUint16(h[24+i*2:], uint16(v))
+	}
+
+	copy(h[36:40], "acsp")
+	copy(h[68:80], s15Fixed16Bytes(d50[0], d50[1], d50[2])) // PCS illuminant
+	return h
+}
+
+// textDescription encodes an ASCII textDescriptionType with empty Unicode
+// and ScriptCode parts
+func textDescription(s string) []byte {
+	b := []byte("desc\x00\x00\x00\x00")
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)+1))
+	b = append(b, s...)
+	b = append(b, 0)
+	b = append(b, make([]byte, 4+4+2+1+67)...)
+	return b
+}
+
+// text encodes a textType
+func text(s string) []byte {
+	return append(append([]byte("text\x00\x00\x00\x00"), s...), 0)
+}
+
+// xyz encodes an XYZType with one value
+func xyz(v [3]float64) []byte {
+	return append([]byte("XYZ \x00\x00\x00\x00"), s15Fixed16Bytes(v[0], v[1], v[2])...)
+}
+
+// curvGamma encodes a curveType with a single gamma value
+func curvGamma(gamma float64) []byte {
+	b := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01")
+	return binary.BigEndian.AppendUint16(b, uint16(math.Round(gamma*256)))
+}
+
+// vcgtTableBody encodes the curves as a 3 channel, 16 bit vcgt table
+func vcgtTableBody(v *VCGT) []byte {
+	size := len(v.Curves[0])
+	b := []byte("vcgt\x00\x00\x00\x00")
+	b = binary.BigEndian.AppendUint32(b, vcgtTable)
+	b = binary.BigEndian.AppendUint16(b, 3)
+	b = binary.BigEndian.AppendUint16(b, uint16(size))
+	b = binary.BigEndian.AppendUint16(b, 2)
+	for _, curve := range v.Curves {
+		for _, value := range curve {
+			b = binary.BigEndian.AppendUint16(b, uint16(math.Round(math.Max(0, math.Min(1, value))*65535)))
+		}
+	}
+	return b
+}
+
+// s15Fixed16Bytes encodes numbers as signed 15.16 fixed point
+func s15Fixed16Bytes(values ...float64) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(v*65536))))
+	}
+	return b
+}
@@ -0,0 +1,136 @@
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// HeaderSize is the size of the ICC profile header, followed by the tag table
+const HeaderSize = 128
+
+// Profile holds the fields of an ICC color profile that lumos uses
+type Profile struct {
+	Version     string // e.g. "2.1" or "4.3"
+	Class       string // Profile class signature, "mntr" for displays
+	ColorSpace  string // Data color space signature, e.g. "RGB "
+	Description string // From the 'desc' tag, empty if missing
+	VCGT        *VCGT  // Video card gamma table, nil if the profile has none
+}
+
+// tag is an entry of the tag table
+type tag struct {
+	sig    string
+	offset uint32
+	size   uint32
+}
+
+// ReadFile reads and parses an ICC profile file
+func ReadFile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse decodes an ICC profile
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < HeaderSize+4 {
+		return nil, fmt.Errorf("ICC profile too short: %d bytes", len(data))
+	}
+	if string(data[36:40]) != "acsp" {
+		return nil, errors.New("invalid ICC profile signature")
+	}
+	if size := binary.BigEndian.Uint32(data[0:4]); int(size) > len(data) {
+		return nil, fmt.Errorf("ICC profile truncated: header says %d bytes, got %d", size, len(data))
+	}
+
+	p := &Profile{
+		Version:    fmt.Sprintf("%d.%d", data[8], data[9]>>4),
+		Class:      string(data[12:16]),
+		ColorSpace: string(data[16:20]),
+	}
+
+	tags, err := parseTags(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tags {
+		body := data[t.offset : t.offset+t.size]
+		switch t.sig {
+		case "desc":
+			p.Description = parseText(body)
+		case "vcgt":
+			vcgt, err := parseVCGT(body)
+			if err != nil {
+				return nil, err
+			}
+			p.VCGT = vcgt
+		}
+	}
+	return p, nil
+}
+
+// parseTags reads the tag table and checks that every tag is in bounds
+func parseTags(data []byte) ([]tag, error) {
+	count := binary.BigEndian.Uint32(data[HeaderSize:])
+	if uint64(HeaderSize+4)+uint64(count)*12 > uint64(len(data)) {
+		return nil, fmt.Errorf("ICC tag table with %d tags exceeds the profile", count)
+	}
+
+	tags := make([]tag, count)
+	for i := range tags {
+		entry := data[HeaderSize+4+i*12:]
+		t := tag{
+			sig:    string(entry[0:4]),
+			offset: binary.BigEndian.Uint32(entry[4:8]),
+			size:   binary.BigEndian.Uint32(entry[8:12]),
+		}
+		if uint64(t.offset)+uint64(t.size) > uint64(len(data)) {
+			return nil, fmt.Errorf("ICC tag %q exceeds the profile", t.sig)
+		}
+		tags[i] = t
+	}
+	return tags, nil
+}
+
+// parseText decodes a textDescriptionType (ICC v2) or the first record of a
+// multiLocalizedUnicodeType (ICC v4)
+func parseText(body []byte) string {
+	if len(body) < 12 {
+		return ""
+	}
+
+	switch string(body[0:4]) {
+	case "desc":
+		n := binary.BigEndian.Uint32(body[8:12])
+		if uint64(n) > uint64(len(body)-12) {
+			return ""
+		}
+		return strings.TrimRight(string(body[12:12+n]), "\x00")
+	case "mluc":
+		if len(body) < 28 || binary.BigEndian.Uint32(body[8:12]) == 0 {
+			return ""
+		}
+		n := binary.BigEndian.Uint32(body[20:24])
+		off := binary.BigEndian.Uint32(body[24:28])
+		if uint64(off)+uint64(n) > uint64(len(body)) {
+			return ""
+		}
+		units := make([]uint16, n/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(body[off+uint32(i)*2:])
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	}
+	return ""
+}
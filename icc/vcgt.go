@@ -0,0 +1,136 @@
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// vcgt gamma types
+const (
+	vcgtTable   = 0
+	vcgtFormula = 1
+)
+
+// VCGT is the video card gamma table of a profile: the calibration curves
+// that are loaded into the display's gamma ramp
+type VCGT struct {
+	Curves [3][]float64 // Red, green and blue output in [0, 1] for evenly spaced inputs
+}
+
+// parseVCGT decodes the Apple 'vcgt' tag in table or formula form
+func parseVCGT(body []byte) (*VCGT, error) {
+	if len(body) < 12 {
+		return nil, errors.New("vcgt tag too short")
+	}
+
+	switch binary.BigEndian.Uint32(body[8:12]) {
+	case vcgtTable:
+		return parseVCGTTable(body[12:])
+	case vcgtFormula:
+		return parseVCGTFormula(body[12:])
+	default:
+		return nil, fmt.Errorf("unknown vcgt gamma type %d", binary.BigEndian.Uint32(body[8:12]))
+	}
+}
+
+// parseVCGTTable decodes channel count, entry count and entry size followed
+// by the entries of each channel. A single channel applies to all three.
+func parseVCGTTable(body []byte) (*VCGT, error) {
+	if len(body) < 6 {
+		return nil, errors.New("vcgt table too short")
+	}
+	channels := int(binary.BigEndian.Uint16(body[0:2]))
+	count := int(binary.BigEndian.Uint16(body[2:4]))
+	size := int(binary.BigEndian.Uint16(body[4:6]))
+
+	if channels != 1 && channels != 3 {
+		return nil, fmt.Errorf("vcgt table has %d channels, expected 1 or 3", channels)
+	}
+	if size != 1 && size != 2 {
+		return nil, fmt.Errorf("vcgt table has %d byte entries, expected 1 or 2", size)
+	}
+	if count < 2 {
+		return nil, fmt.Errorf("vcgt table has %d entries, expected at least 2", count)
+	}
+	if len(body) < 6+channels*count*size {
+		return nil, errors.New("vcgt table truncated")
+	}
+
+	maxValue := float64(uint(1)<<(8*size) - 1)
+	v := &VCGT{}
+	data := body[6:]
+	for c := range channels {
+		curve := make([]float64, count)
+		for i := range curve {
+			pos := (c*count + i) * size
+			if size == 1 {
+				curve[i] = float64(data[pos]) / maxValue
+			} else {
+				curve[i] = float64(binary.BigEndian.Uint16(data[pos:])) / maxValue
+			}
+		}
+		v.Curves[c] = curve
+	}
+	if channels == 1 {
+		v.Curves[1], v.Curves[2] = v.Curves[0], v.Curves[0]
+	}
+	return v, nil
+}
+
+// parseVCGTFormula decodes gamma, minimum and maximum per channel as
+// s15Fixed16 numbers and samples output = min + (max-min)·input^gamma
+func parseVCGTFormula(body []byte) (*VCGT, error) {
+	if len(body) < 36 {
+		return nil, errors.New("vcgt formula too short")
+	}
+
+	v := &VCGT{}
+	for c := range 3 {
+		gamma := s15Fixed16(body[c*12:])
+		lo := s15Fixed16(body[c*12+4:])
+		hi := s15Fixed16(body[c*12+8:])
+		if gamma <= 0 {
+			return nil, fmt.Errorf("vcgt formula has invalid gamma %g", gamma)
+		}
+
+		curve := make([]float64, 256)
+		for i := range curve {
+			curve[i] = lo + (hi-lo)*math.Pow(float64(i)/255, gamma)
+		}
+		v.Curves[c] = curve
+	}
+	return v, nil
+}
+
+// Eval returns the output of a channel (0 red, 1 green, 2 blue) for an
+// input in [0, 1], interpolating linearly between table entries
+func (v *VCGT) Eval(channel int, x float64) float64 {
+	curve := v.Curves[channel]
+	pos := math.Max(0, math.Min(1, x)) * float64(len(curve)-1)
+	i := int(pos)
+	if i >= len(curve)-1 {
+		return curve[len(curve)-1]
+	}
+	frac := pos - float64(i)
+	return curve[i]*(1-frac) + curve[i+1]*frac
+}
+
+// IsLinear reports whether every curve is the identity within 1/1024
+func (v *VCGT) IsLinear() bool {
+	for c := range v.Curves {
+		for i := range 256 {
+			x := float64(i) / 255
+			if math.Abs(v.Eval(c, x)-x) > 1.0/1024 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// s15Fixed16 decodes a signed 15.16 fixed point number
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
@@ -0,0 +1,257 @@
+package icc
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fixture is a tag signature and its body
+type fixture struct {
+	sig  string
+	body []byte
+}
+
+// profile assembles a display profile of an ICC version from tags
+func profile(version byte, tags ...fixture) []byte {
+	data := make([]byte, HeaderSize+4+12*len(tags))
+	data[8] = version
+	copy(data[12:], "mntr")
+	copy(data[16:], "RGB ")
+	copy(data[36:], "acsp")
+
+	binary.BigEndian.PutUint32(data[HeaderSize:], uint32(len(tags)))
+	for i, t := range tags {
+		entry := data[HeaderSize+4+12*i:]
+		copy(entry[0:4], t.sig)
+		binary.BigEndian.PutUint32(entry[4:8], uint32(len(data)))
+		binary.BigEndian.PutUint32(entry[8:12], uint32(len(t.body)))
+		data = append(data, t.body...)
+	}
+	binary.BigEndian.PutUint32(data[0:4], uint32(len(data)))
+	return data
+}
+
+// be concatenates big-endian 16 and 32 bit values and raw bytes
+func be(values ...any) []byte {
+	var b []byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case uint16:
+			b = binary.BigEndian.AppendUint16(b, v)
+		case uint32:
+			b = binary.BigEndian.AppendUint32(b, v)
+		case string:
+			b = append(b, v...)
+		case []byte:
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+// fixed encodes an s15Fixed16 number
+func fixed(f float64) uint32 {
+	return uint32(int32(math.Round(f * 65536)))
+}
+
+// descTag is an ICC v2 textDescriptionType
+func descTag(s string) fixture {
+	return fixture{"desc", be("desc", uint32(0), uint32(len(s)+1), s, []byte{0}, make([]byte, 4+4+2+1+67))}
+}
+
+// mlucTag is an ICC v4 multiLocalizedUnicodeType with one en-US record
+func mlucTag(s string) fixture {
+	var text []byte
+	for _, r := range s {
+		text = binary.BigEndian.AppendUint16(text, uint16(r))
+	}
+	return fixture{"desc", be("mluc", uint32(0), uint32(1), uint32(12), "enUS", uint32(len(text)), uint32(28), text)}
+}
+
+// tableTag is a vcgt table of 16 bit entries, one slice per channel
+func tableTag(channels ...[]uint16) fixture {
+	b := be("vcgt", uint32(0), uint32(vcgtTable), uint16(len(channels)), uint16(len(channels[0])), uint16(2))
+	for _, c := range channels {
+		for _, v := range c {
+			b = binary.BigEndian.AppendUint16(b, v)
+		}
+	}
+	return fixture{"vcgt", b}
+}
+
+// formulaTag is a vcgt formula of gamma, min and max per channel
+func formulaTag(params ...float64) fixture {
+	b := be("vcgt", uint32(0), uint32(vcgtFormula))
+	for _, p := range params {
+		b = binary.BigEndian.AppendUint32(b, fixed(p))
+	}
+	return fixture{"vcgt", b}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-4
+}
+
+func TestParseVCGTTable(t *testing.T) {
+	data := profile(2, descTag("DELL U2720Q calibrated"), tableTag(
+		[]uint16{0, 0x5555, 0xAAAA, 0xFFFF},
+		[]uint16{0, 0x5000, 0xA000, 0xF000},
+		[]uint16{0x1000, 0x4000, 0x8000, 0xC000},
+	))
+	p, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != "2.0" || p.Class != "mntr" || p.ColorSpace != "RGB " || p.Description != "DELL U2720Q calibrated" {
+		t.Errorf("profile = %s %q %q %q", p.Version, p.Class, p.ColorSpace, p.Description)
+	}
+	if p.VCGT == nil {
+		t.Fatal("VCGT = nil")
+	}
+
+	tests := []struct {
+		channel int
+		x, want float64
+	}{
+		{0, 0, 0},
+		{0, 1.0 / 3, 1.0 / 3},
+		{0, 1, 1},
+		{1, 1, float64(0xF000) / 0xFFFF},
+		{1, 0.5, (float64(0x5000) + 0xA000) / 2 / 0xFFFF}, // Between entries
+		{2, 0, float64(0x1000) / 0xFFFF},
+		{2, -1, float64(0x1000) / 0xFFFF}, // Clamped
+		{2, 2, float64(0xC000) / 0xFFFF},
+	}
+	for _, tt := range tests {
+		if got := p.VCGT.Eval(tt.channel, tt.x); !near(got, tt.want) {
+			t.Errorf("Eval(%d, %v) = %v, want %v", tt.channel, tt.x, got, tt.want)
+		}
+	}
+	if p.VCGT.IsLinear() {
+		t.Error("IsLinear = true for a calibrated table")
+	}
+}
+
+func TestParseVCGTSingleChannel(t *testing.T) {
+	body := be("vcgt", uint32(0), uint32(vcgtTable), uint16(1), uint16(2), uint16(1), []byte{0x00, 0xFF})
+	p, err := Parse(profile(4, mlucTag("Linear"), fixture{"vcgt", body}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != "4.0" || p.Description != "Linear" {
+		t.Errorf("profile = %s %q, want 4.0 \"Linear\"", p.Version, p.Description)
+	}
+	for c := range 3 {
+		if len(p.VCGT.Curves[c]) != 2 {
+			t.Errorf("channel %d has %d entries, want 2", c, len(p.VCGT.Curves[c]))
+		}
+	}
+	if !p.VCGT.IsLinear() {
+		t.Error("IsLinear = false for an identity table")
+	}
+}
+
+func TestParseVCGTFormula(t *testing.T) {
+	p, err := Parse(profile(2, formulaTag(
+		1.0, 0, 1, // Identity
+		2.0, 0, 1,
+		0.5, 0.1, 0.9,
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for c := range 3 {
+		if len(p.VCGT.Curves[c]) != 256 {
+			t.Fatalf("channel %d has %d entries, want 256", c, len(p.VCGT.Curves[c]))
+		}
+	}
+	tests := []struct {
+		channel int
+		x, want float64
+	}{
+		{0, 0.5, 0.5},
+		{1, 0, 0},
+		{1, 0.6, 0.36},
+		{1, 1, 1},
+		{2, 0, 0.1},
+		{2, 0.64, 0.1 + 0.8*0.8},
+		{2, 1, 0.9},
+	}
+	for _, tt := range tests {
+		if got := p.VCGT.Eval(tt.channel, tt.x); !near(got, tt.want) {
+			t.Errorf("Eval(%d, %v) = %v, want %v", tt.channel, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestParseNoVCGT(t *testing.T) {
+	p, err := Parse(profile(2, descTag("sRGB IEC61966-2.1")))
+	if err != nil || p.VCGT != nil || p.Description != "sRGB IEC61966-2.1" {
+		t.Errorf("Parse = %+v, %v, want a profile without vcgt", p, err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	full := profile(2, tableTag(make([]uint16, 256), make([]uint16, 256), make([]uint16, 256)))
+
+	// A vcgt tag cut short, with the tag and profile sizes fixed up to match
+	cut := append([]byte(nil), full[:len(full)-100]...)
+	binary.BigEndian.PutUint32(cut[0:4], uint32(len(cut)))
+	binary.BigEndian.PutUint32(cut[HeaderSize+4+8:], binary.BigEndian.Uint32(cut[HeaderSize+4+8:])-100)
+
+	oversized := append([]byte(nil), full...)
+	binary.BigEndian.PutUint32(oversized[HeaderSize+4+8:], uint32(len(full)))
+
+	badSignature := append([]byte(nil), full...)
+	copy(badSignature[36:], "xxxx")
+
+	tagCount := profile(2)
+	binary.BigEndian.PutUint32(tagCount[HeaderSize:], 5)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "too short"},
+		{"signature", badSignature, "signature"},
+		{"truncated file", full[:len(full)-1], "truncated"},
+		{"truncated vcgt table", cut, "vcgt table truncated"},
+		{"tag out of bounds", oversized, "exceeds the profile"},
+		{"tag count", tagCount, "5 tags exceeds"},
+		{"vcgt header", profile(2, fixture{"vcgt", be("vcgt", uint32(0))}), "vcgt tag too short"},
+		{"unknown type", profile(2, fixture{"vcgt", be("vcgt", uint32(0), uint32(7))}), "unknown vcgt gamma type 7"},
+		{"two channels", profile(2, tableTag([]uint16{0, 1}, []uint16{0, 1})), "2 channels"},
+		{"one entry", profile(2, tableTag([]uint16{0}, []uint16{0}, []uint16{0})), "1 entries"},
+		{"entry size", profile(2, fixture{"vcgt", be("vcgt", uint32(0), uint32(vcgtTable), uint16(3), uint16(2), uint16(4))}), "4 byte entries"},
+		{"short formula", profile(2, fixture{"vcgt", be("vcgt", uint32(0), uint32(vcgtFormula), make([]byte, 35))}), "vcgt formula too short"},
+		{"zero gamma", profile(2, formulaTag(0, 0, 1, 1, 0, 1, 1, 0, 1)), "invalid gamma"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: Parse = %v, want an error containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(profile(2, descTag("DELL U2720Q"), tableTag([]uint16{0, 0x8000, 0xFFFF})))
+	f.Add(profile(4, mlucTag("Linear"), formulaTag(1, 0, 1, 1, 0, 1, 1, 0, 1)))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := Parse(data)
+		if err != nil || p.VCGT == nil {
+			return
+		}
+		for c := range 3 {
+			if len(p.VCGT.Curves[c]) < 2 {
+				t.Fatalf("channel %d has %d entries", c, len(p.VCGT.Curves[c]))
+			}
+			p.VCGT.Eval(c, 0.5)
+		}
+	})
+}
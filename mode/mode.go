@@ -0,0 +1,237 @@
+package mode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mode is a display mode as reported by the driver
+type Mode struct {
+	Width        int
+	Height       int
+	RefreshRate  int // Hz, as reported by Windows (59 for 59.94 Hz)
+	BitsPerPixel int
+	Interlaced   bool
+}
+
+func (m Mode) String() string {
+	s := fmt.Sprintf("%dx%d @ %d Hz", m.Width, m.Height, m.RefreshRate)
+	if m.Interlaced {
+		s += " (interlaced)"
+	}
+	return s
+}
+
+// Request describes the mode the user asked for. Zero fields keep the
+// current value or pick the best available one.
+type Request struct {
+	Width       int
+	Height      int
+	RefreshRate int
+}
+
+// IsZero reports whether nothing was requested
+func (r Request) IsZero() bool {
+	return r.Width == 0 && r.Height == 0 && r.RefreshRate == 0
+}
+
+func (r Request) String() string {
+	var parts []string
+	if r.Width != 0 || r.Height != 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", r.Width, r.Height))
+	}
+	if r.RefreshRate != 0 {
+		parts = append(parts, fmt.Sprintf("%d Hz", r.RefreshRate))
+	}
+	return strings.Join(parts, " @ ")
+}
+
+// ParseResolution parses a resolution like "2560x1440"
+func ParseResolution(s string) (width, height int, err error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+		if err == nil {
+			height, err = strconv.Atoi(h)
+		}
+	}
+	if !ok || err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid resolution: %q (must be like 2560x1440)", s)
+	}
+	return width, height, nil
+}
+
+// Select picks the mode from modes that best satisfies req:
+//   - a missing resolution keeps the current one
+//   - a missing refresh rate keeps the current one if available at the
+//     new resolution, otherwise the highest one is used
+//   - refresh rates match exactly first, then within 1 Hz so that 60
+//     finds 59 Hz (59.94) modes
+//   - progressive modes and the current color depth are preferred
+func Select(modes []Mode, current Mode, req Request) (Mode, error) {
+	width, height := req.Width, req.Height
+	if width == 0 || height == 0 {
+		width, height = current.Width, current.Height
+	}
+
+	var candidates []Mode
+	for _, m := range modes {
+		if m.Width == width && m.Height == height {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		return Mode{}, fmt.Errorf("resolution %dx%d is not supported (available: %s)",
+			width, height, strings.Join(Resolutions(modes), ", "))
+	}
+
+	refresh := req.RefreshRate
+	if refresh == 0 {
+		refresh = current.RefreshRate
+		if !hasRefresh(candidates, refresh, 0) {
+			refresh = maxRefresh(candidates)
+		}
+	}
+
+	var matched []Mode
+	for _, tolerance := range []int{0, 1} {
+		for _, m := range candidates {
+			if abs(m.RefreshRate-refresh) <= tolerance {
+				matched = append(matched, m)
+			}
+		}
+		if len(matched) > 0 {
+			break
+		}
+	}
+	if len(matched) == 0 {
+		return Mode{}, fmt.Errorf("%d Hz is not supported at %dx%d (available: %s)",
+			refresh, width, height, strings.Join(RefreshRates(candidates), ", "))
+	}
+
+	best := matched[0]
+	for _, m := range matched[1:] {
+		if better(m, best, current, refresh) {
+			best = m
+		}
+	}
+	return best, nil
+}
+
+// better reports whether a is a better pick than b
+func better(a, b, current Mode, refresh int) bool {
+	if a.Interlaced != b.Interlaced {
+		return !a.Interlaced
+	}
+	if da, db := abs(a.RefreshRate-refresh), abs(b.RefreshRate-refresh); da != db {
+		return da < db
+	}
+	if (a.BitsPerPixel == current.BitsPerPixel) != (b.BitsPerPixel == current.BitsPerPixel) {
+		return a.BitsPerPixel == current.BitsPerPixel
+	}
+	return a.BitsPerPixel > b.BitsPerPixel
+}
+
+// Sort orders modes by resolution then refresh rate, largest first
+func Sort(modes []Mode) {
+	sort.SliceStable(modes, func(i, j int) bool {
+		a, b := modes[i], modes[j]
+		if a.Width*a.Height != b.Width*b.Height {
+			return a.Width*a.Height > b.Width*b.Height
+		}
+		if a.Width != b.Width {
+			return a.Width > b.Width
+		}
+		if a.RefreshRate != b.RefreshRate {
+			return a.RefreshRate > b.RefreshRate
+		}
+		if a.Interlaced != b.Interlaced {
+			return !a.Interlaced
+		}
+		return a.BitsPerPixel > b.BitsPerPixel
+	})
+}
+
+// Unique removes modes that only differ by color depth, keeping the first
+// of each. Drivers list every mode once per supported depth.
+func Unique(modes []Mode) []Mode {
+	type key struct {
+		w, h, r    int
+		interlaced bool
+	}
+	seen := make(map[key]bool)
+
+	var out []Mode
+	for _, m := range modes {
+		k := key{m.Width, m.Height, m.RefreshRate, m.Interlaced}
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Resolutions returns the distinct resolutions in modes, largest first
+func Resolutions(modes []Mode) []string {
+	sorted := append([]Mode(nil), modes...)
+	Sort(sorted)
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, m := range sorted {
+		s := fmt.Sprintf("%dx%d", m.Width, m.Height)
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// RefreshRates returns the distinct refresh rates in modes, highest first
+func RefreshRates(modes []Mode) []string {
+	var rates []int
+	seen := make(map[int]bool)
+	for _, m := range modes {
+		if !seen[m.RefreshRate] {
+			seen[m.RefreshRate] = true
+			rates = append(rates, m.RefreshRate)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rates)))
+
+	out := make([]string, len(rates))
+	for i, r := range rates {
+		out[i] = fmt.Sprintf("%d Hz", r)
+	}
+	return out
+}
+
+func hasRefresh(modes []Mode, refresh, tolerance int) bool {
+	for _, m := range modes {
+		if abs(m.RefreshRate-refresh) <= tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func maxRefresh(modes []Mode) int {
+	max := 0
+	for _, m := range modes {
+		if m.RefreshRate > max {
+			max = m.RefreshRate
+		}
+	}
+	return max
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package mode
+
+import "errors"
+
+var errUnsupported = errors.New("display mode switching is only supported on Windows")
+
+// Modes returns every mode the driver supports for the display
+func Modes(deviceName string) ([]Mode, error) {
+	return nil, errUnsupported
+}
+
+// Current returns the mode the display is currently using
+func Current(deviceName string) (Mode, error) {
+	return Mode{}, errUnsupported
+}
+
+// Apply switches the display to the given mode
+func Apply(deviceName string, m Mode) error {
+	return errUnsupported
+}
@@ -0,0 +1,97 @@
+package mode
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testModes is what a 1440p 144 Hz monitor typically lists, once per color depth
+var testModes = []Mode{
+	{2560, 1440, 144, 32, false},
+	{2560, 1440, 120, 32, false},
+	{2560, 1440, 60, 32, false},
+	{2560, 1440, 59, 32, false},
+	{2560, 1440, 60, 16, false},
+	{1920, 1080, 144, 32, false},
+	{1920, 1080, 60, 32, false},
+	{1920, 1080, 60, 32, true},
+	{1920, 1080, 50, 32, false},
+	{1280, 720, 59, 32, false},
+}
+
+func TestSelect(t *testing.T) {
+	current := Mode{2560, 1440, 60, 32, false}
+
+	tests := []struct {
+		name string
+		req  Request
+		want Mode
+	}{
+		{"same resolution, best refresh", Request{RefreshRate: 144}, Mode{2560, 1440, 144, 32, false}},
+		{"resolution keeps current refresh", Request{Width: 1920, Height: 1080}, Mode{1920, 1080, 60, 32, false}},
+		{"resolution without current refresh uses the highest", Request{Width: 1280, Height: 720}, Mode{1280, 720, 59, 32, false}},
+		{"exact match", Request{Width: 1920, Height: 1080, RefreshRate: 50}, Mode{1920, 1080, 50, 32, false}},
+		{"exact refresh wins over 59.94", Request{RefreshRate: 60}, Mode{2560, 1440, 60, 32, false}},
+		{"60 finds 59.94", Request{Width: 1280, Height: 720, RefreshRate: 60}, Mode{1280, 720, 59, 32, false}},
+		{"progressive preferred", Request{Width: 1920, Height: 1080, RefreshRate: 60}, Mode{1920, 1080, 60, 32, false}},
+		{"nothing requested keeps current", Request{}, current},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Select(testModes, current, tt.req)
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Select = %v (%d bpp), want %v (%d bpp)", got, got.BitsPerPixel, tt.want, tt.want.BitsPerPixel)
+			}
+		})
+	}
+}
+
+func TestSelectCurrentDepth(t *testing.T) {
+	current := Mode{2560, 1440, 144, 16, false}
+	got, err := Select(testModes, current, Request{RefreshRate: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BitsPerPixel != 16 {
+		t.Errorf("Select kept %d bpp, want the current 16 bpp", got.BitsPerPixel)
+	}
+}
+
+func TestSelectNoMatch(t *testing.T) {
+	current := Mode{2560, 1440, 60, 32, false}
+	for _, req := range []Request{
+		{Width: 3840, Height: 2160},
+		{RefreshRate: 165},
+		{Width: 1280, Height: 720, RefreshRate: 144},
+	} {
+		if m, err := Select(testModes, current, req); err == nil {
+			t.Errorf("Select(%v) = %v, want an error", req, m)
+		}
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	if w, h, err := ParseResolution(" 2560X1440 "); err != nil || w != 2560 || h != 1440 {
+		t.Errorf("ParseResolution = %d, %d, %v", w, h, err)
+	}
+	for _, s := range []string{"", "2560", "2560x", "x1440", "0x1080", "-1x5", "axb"} {
+		if _, _, err := ParseResolution(s); err == nil {
+			t.Errorf("ParseResolution(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestListHelpers(t *testing.T) {
+	if got, want := Resolutions(testModes), []string{"2560x1440", "1920x1080", "1280x720"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolutions = %v, want %v", got, want)
+	}
+	if got, want := RefreshRates(testModes[:5]), []string{"144 Hz", "120 Hz", "60 Hz", "59 Hz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RefreshRates = %v, want %v", got, want)
+	}
+	if got := Unique(testModes); len(got) != len(testModes)-1 {
+		t.Errorf("Unique kept %d modes, want %d", len(got), len(testModes)-1)
+	}
+}
@@ -0,0 +1,192 @@
+package mode
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ENUM_CURRENT_SETTINGS = 0xFFFFFFFF
+
+	DM_BITSPERPEL       = 0x00040000
+	DM_PELSWIDTH        = 0x00080000
+	DM_PELSHEIGHT       = 0x00100000
+	DM_DISPLAYFLAGS     = 0x00200000
+	DM_DISPLAYFREQUENCY = 0x00400000
+	DM_INTERLACED       = 0x00000002
+
+	CDS_UPDATEREGISTRY = 0x00000001
+	CDS_TEST           = 0x00000002
+)
+
+// ChangeDisplaySettingsEx results
+var dispChangeErrors = map[int32]string{
+	1:  "a restart is required for the mode to take effect",
+	-1: "the display driver failed the mode change",
+	-2: "the mode is not supported",
+	-3: "unable to write settings to the registry",
+	-4: "invalid flags",
+	-5: "invalid parameter",
+	-6: "the display is part of a DualView configuration",
+}
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+
+	procEnumDisplaySettings     = user32.NewProc("EnumDisplaySettingsW")
+	procChangeDisplaySettingsEx = user32.NewProc("ChangeDisplaySettingsExW")
+)
+
+// DEVMODEW structure, display variant of the unions
+type devMode struct {
+	DeviceName         [32]uint16
+	SpecVersion        uint16
+	DriverVersion      uint16
+	Size               uint16
+	DriverExtra        uint16
+	Fields             uint32
+	PositionX          int32
+	PositionY          int32
+	DisplayOrientation uint32
+	DisplayFixedOutput uint32
+	Color              int16
+	Duplex             int16
+	YResolution        int16
+	TTOption           int16
+	Collate            int16
+	FormName           [32]uint16
+	LogPixels          uint16
+	BitsPerPel         uint32
+	PelsWidth          uint32
+	PelsHeight         uint32
+	DisplayFlags       uint32
+	DisplayFrequency   uint32
+	ICMMethod          uint32
+	ICMIntent          uint32
+	MediaType          uint32
+	DitherType         uint32
+	Reserved1          uint32
+	Reserved2          uint32
+	PanningWidth       uint32
+	PanningHeight      uint32
+}
+
+// Modes returns every mode the driver supports for the display
+func Modes(deviceName string) ([]Mode, error) {
+	namePtr, err := syscall.UTF16PtrFromString(deviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var modes []Mode
+	for index := 0; ; index++ {
+		dm := newDevMode()
+		ret, _, _ := procEnumDisplaySettings.Call(
+			uintptr(unsafe.Pointer(namePtr)),
+			uintptr(index),
+			uintptr(unsafe.Pointer(dm)),
+		)
+		if ret == 0 {
+			break
+		}
+		modes = append(modes, dm.mode())
+	}
+
+	if len(modes) == 0 {
+		return nil, fmt.Errorf("no display modes reported for %s", deviceName)
+	}
+	return modes, nil
+}
+
+// Current returns the mode the display is currently using
+func Current(deviceName string) (Mode, error) {
+	dm, err := currentDevMode(deviceName)
+	if err != nil {
+		return Mode{}, err
+	}
+	return dm.mode(), nil
+}
+
+// Apply switches the display to the given mode. The change is validated
+// with CDS_TEST first so an unsupported mode never reaches the driver.
+func Apply(deviceName string, m Mode) error {
+	namePtr, err := syscall.UTF16PtrFromString(deviceName)
+	if err != nil {
+		return err
+	}
+
+	dm, err := currentDevMode(deviceName)
+	if err != nil {
+		return err
+	}
+	dm.PelsWidth = uint32(m.Width)
+	dm.PelsHeight = uint32(m.Height)
+	dm.DisplayFrequency = uint32(m.RefreshRate)
+	dm.BitsPerPel = uint32(m.BitsPerPixel)
+	dm.DisplayFlags = 0
+	if m.Interlaced {
+		dm.DisplayFlags = DM_INTERLACED
+	}
+	dm.Fields = DM_PELSWIDTH | DM_PELSHEIGHT | DM_DISPLAYFREQUENCY | DM_BITSPERPEL | DM_DISPLAYFLAGS
+
+	if err := changeDisplaySettings(namePtr, dm, CDS_TEST); err != nil {
+		return fmt.Errorf("mode %s rejected by test: %v", m, err)
+	}
+	if err := changeDisplaySettings(namePtr, dm, CDS_UPDATEREGISTRY); err != nil {
+		return fmt.Errorf("failed to apply mode %s: %v", m, err)
+	}
+	return nil
+}
+
+func changeDisplaySettings(namePtr *uint16, dm *devMode, flags uint32) error {
+	ret, _, _ := procChangeDisplaySettingsEx.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		uintptr(unsafe.Pointer(dm)),
+		0,
+		uintptr(flags),
+		0,
+	)
+	if code := int32(ret); code != 0 {
+		if msg, ok := dispChangeErrors[code]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("ChangeDisplaySettingsEx returned %d", code)
+	}
+	return nil
+}
+
+func currentDevMode(deviceName string) (*devMode, error) {
+	namePtr, err := syscall.UTF16PtrFromString(deviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	dm := newDevMode()
+	ret, _, callErr := procEnumDisplaySettings.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		ENUM_CURRENT_SETTINGS,
+		uintptr(unsafe.Pointer(dm)),
+	)
+	if ret == 0 {
+		return nil, errors.New("failed to get current display settings: " + callErr.Error())
+	}
+	return dm, nil
+}
+
+func newDevMode() *devMode {
+	dm := &devMode{}
+	dm.Size = uint16(unsafe.Sizeof(*dm))
+	return dm
+}
+
+func (dm *devMode) mode() Mode {
+	return Mode{
+		Width:        int(dm.PelsWidth),
+		Height:       int(dm.PelsHeight),
+		RefreshRate:  int(dm.DisplayFrequency),
+		BitsPerPixel: int(dm.BitsPerPel),
+		Interlaced:   dm.DisplayFlags&DM_INTERLACED != 0,
+	}
+}
@@ -3,8 +3,6 @@ package night
 import (
 	"errors"
 	"fmt"
-
-	"golang.org/x/sys/windows/registry"
 )
 
 const (
@@ -34,52 +32,6 @@ func (nl *Lumos) Supported() bool {
 	return err == nil
 }
 
-// getStateData retrieves the Data value from the state registry key
-func (nl *Lumos) getStateData() ([]byte, error) {
-	key, err := registry.OpenKey(registry.CURRENT_USER, nl.stateKey, registry.READ)
-	if err != nil {
-		return nil, err
-	}
-	defer key.Close()
-
-	data, _, err := key.GetBinaryValue("Data")
-	return data, err
-}
-
-// getSettingsData retrieves the Data value from the settings registry key
-func (nl *Lumos) getSettingsData() ([]byte, error) {
-	key, err := registry.OpenKey(registry.CURRENT_USER, nl.settingsKey, registry.READ)
-	if err != nil {
-		return nil, err
-	}
-	defer key.Close()
-
-	data, _, err := key.GetBinaryValue("Data")
-	return data, err
-}
-
-// setStateData writes the Data value to the state registry key
-func (nl *Lumos) setStateData(data []byte) error {
-	key, err := registry.OpenKey(registry.CURRENT_USER, nl.stateKey, registry.WRITE)
-	if err != nil {
-		return err
-	}
-	defer key.Close()
-
-	return key.SetBinaryValue("Data", data)
-}
-
-// setSettingsData writes the Data value to the settings registry key
-func (nl *Lumos) setSettingsData(data []byte) error {
-	key, err := registry.OpenKey(registry.CURRENT_USER, nl.settingsKey, registry.WRITE)
-	if err != nil {
-		return err
-	}
-	defer key.Close()
-
-	return key.SetBinaryValue("Data", data)
-}
-
 // Enabled checks if Lumos is currently enabled
 func (nl *Lumos) Enabled() (bool, error) {
 	if !nl.Supported() {
@@ -98,53 +50,88 @@ func (nl *Lumos) Enabled() (bool, error) {
 	return data[18] == 0x15, nil // 21 in decimal
 }
 
+// Change is a registry write computed by one of the Plan methods. Nothing
+// is written until it is passed to Commit.
+type Change struct {
+	Key string // Registry key under HKEY_CURRENT_USER
+	Old []byte // Current Data value
+	New []byte // Data value that Commit writes
+}
+
 // Enable turns on Lumos
 func (nl *Lumos) Enable() error {
-	enabled, err := nl.Enabled()
-	if err != nil {
-		return err
-	}
-
-	if !enabled {
-		return nl.Toggle()
-	}
-	return nil
+	return nl.commitPlan(nl.PlanEnable())
 }
 
 // Disable turns off Lumos
 func (nl *Lumos) Disable() error {
+	return nl.commitPlan(nl.PlanDisable())
+}
+
+// Toggle toggles Lumos on/off
+func (nl *Lumos) Toggle() error {
+	return nl.commitPlan(nl.PlanToggle())
+}
+
+// PlanEnable computes the change that turns on Lumos, nil if it is on
+func (nl *Lumos) PlanEnable() (*Change, error) {
+	return nl.planState(true)
+}
+
+// PlanDisable computes the change that turns off Lumos, nil if it is off
+func (nl *Lumos) PlanDisable() (*Change, error) {
+	return nl.planState(false)
+}
+
+// PlanToggle computes the change that toggles Lumos on/off
+func (nl *Lumos) PlanToggle() (*Change, error) {
 	enabled, err := nl.Enabled()
 	if err != nil {
-		return err
-	}
-
-	if enabled {
-		return nl.Toggle()
+		return nil, err
 	}
-	return nil
+	return nl.planState(!enabled)
 }
 
-// Toggle toggles Lumos on/off
-func (nl *Lumos) Toggle() error {
+// planState computes the state change to enable or disable Lumos
+func (nl *Lumos) planState(enable bool) (*Change, error) {
 	enabled, err := nl.Enabled()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if enabled == enable {
+		return nil, nil
 	}
 
 	data, err := nl.getStateData()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	newData, err := toggledStateData(data, enabled)
+	if err != nil {
+		return nil, err
 	}
+	return &Change{Key: nl.stateKey, Old: data, New: newData}, nil
+}
 
+// toggledStateData returns the state data with Lumos switched to the
+// opposite of enabled
+func toggledStateData(data []byte, enabled bool) ([]byte, error) {
 	var newData []byte
 
 	if enabled {
+		if len(data) < 43 {
+			return nil, errors.New("invalid state data length")
+		}
 		// Disable: create a smaller array and copy data with gaps
 		newData = make([]byte, 41)
 		copy(newData[0:22], data[0:22])
 		copy(newData[23:], data[25:43])
 		newData[18] = 0x13
 	} else {
+		if len(data) < 41 {
+			return nil, errors.New("invalid state data length")
+		}
 		// Enable: create a larger array and copy data with gaps
 		newData = make([]byte, 43)
 		copy(newData[0:22], data[0:22])
@@ -154,15 +141,18 @@ func (nl *Lumos) Toggle() error {
 		newData[24] = 0x00
 	}
 
-	// Increment timestamp bytes
+	incrementTimestamp(newData)
+	return newData, nil
+}
+
+// incrementTimestamp bumps the change timestamp so Windows picks up the write
+func incrementTimestamp(data []byte) {
 	for i := 10; i < 15; i++ {
-		if newData[i] != 0xff {
-			newData[i]++
+		if data[i] != 0xff {
+			data[i]++
 			break
 		}
 	}
-
-	return nl.setStateData(newData)
 }
 
 // GetStrength returns the current Lumos strength as a percentage (0-100)
@@ -186,8 +176,13 @@ func (nl *Lumos) GetStrength() (float64, error) {
 
 // SetStrength sets the Lumos strength (0-100)
 func (nl *Lumos) SetStrength(percentage float64) error {
+	return nl.commitPlan(nl.PlanStrength(percentage))
+}
+
+// PlanStrength computes the change that sets the Lumos strength (0-100)
+func (nl *Lumos) PlanStrength(percentage float64) (*Change, error) {
 	if !nl.Supported() {
-		return errors.New("night light not supported")
+		return nil, errors.New("night light not supported")
 	}
 
 	// Clamp percentage between 0-100
@@ -202,11 +197,11 @@ func (nl *Lumos) SetStrength(percentage float64) error {
 
 	data, err := nl.getSettingsData()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(data) < 0x25 {
-		return errors.New("invalid settings data length")
+		return nil, errors.New("invalid settings data length")
 	}
 
 	// Calculate bytes using the PowerShell script's formula
@@ -214,18 +209,31 @@ func (nl *Lumos) SetStrength(percentage float64) error {
 	tempLo := byte(((kelvin - float64(tempHi)*64) * 2) + 128)
 
 	// Update strength bytes (indices 0x23, 0x24)
-	data[0x23] = tempLo
-	data[0x24] = tempHi
+	newData := append([]byte(nil), data...)
+	newData[0x23] = tempLo
+	newData[0x24] = tempHi
 
-	// Update timestamp bytes
-	for i := 10; i < 15; i++ {
-		if data[i] != 0xff {
-			data[i]++
-			break
-		}
+	incrementTimestamp(newData)
+	return &Change{Key: nl.settingsKey, Old: data, New: newData}, nil
+}
+
+// Commit writes a planned change to the registry
+func (nl *Lumos) Commit(c *Change) error {
+	switch c.Key {
+	case nl.stateKey:
+		return nl.setStateData(c.New)
+	case nl.settingsKey:
+		return nl.setSettingsData(c.New)
 	}
+	return fmt.Errorf("unknown night light registry key: %s", c.Key)
+}
 
-	return nl.setSettingsData(data)
+// commitPlan commits the result of a Plan method, if there is a change
+func (nl *Lumos) commitPlan(c *Change, err error) error {
+	if err != nil || c == nil {
+		return err
+	}
+	return nl.Commit(c)
 }
 
 // bytesToKelvin converts registry bytes to kelvin temperature
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package night
+
+import "errors"
+
+var errUnsupported = errors.New("night light is only supported on Windows")
+
+// getStateData retrieves the Data value from the state registry key
+func (nl *Lumos) getStateData() ([]byte, error) {
+	return nil, errUnsupported
+}
+
+// getSettingsData retrieves the Data value from the settings registry key
+func (nl *Lumos) getSettingsData() ([]byte, error) {
+	return nil, errUnsupported
+}
+
+// setStateData writes the Data value to the state registry key
+func (nl *Lumos) setStateData(data []byte) error {
+	return errUnsupported
+}
+
+// setSettingsData writes the Data value to the settings registry key
+func (nl *Lumos) setSettingsData(data []byte) error {
+	return errUnsupported
+}
@@ -0,0 +1,49 @@
+package night
+
+import "golang.org/x/sys/windows/registry"
+
+// getStateData retrieves the Data value from the state registry key
+func (nl *Lumos) getStateData() ([]byte, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, nl.stateKey, registry.READ)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	data, _, err := key.GetBinaryValue("Data")
+	return data, err
+}
+
+// getSettingsData retrieves the Data value from the settings registry key
+func (nl *Lumos) getSettingsData() ([]byte, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, nl.settingsKey, registry.READ)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	data, _, err := key.GetBinaryValue("Data")
+	return data, err
+}
+
+// setStateData writes the Data value to the state registry key
+func (nl *Lumos) setStateData(data []byte) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, nl.stateKey, registry.WRITE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	return key.SetBinaryValue("Data", data)
+}
+
+// setSettingsData writes the Data value to the settings registry key
+func (nl *Lumos) setSettingsData(data []byte) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, nl.settingsKey, registry.WRITE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	return key.SetBinaryValue("Data", data)
+}
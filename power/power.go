@@ -0,0 +1,115 @@
+package power
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jipaix/lumos/ddc"
+	"github.com/jipaix/lumos/display"
+)
+
+// State is a display power state
+type State int
+
+const (
+	On State = iota
+	Standby
+	Off
+)
+
+// ParseState parses "on", "standby" or "off"
+func ParseState(s string) (State, error) {
+	switch s {
+	case "on":
+		return On, nil
+	case "standby":
+		return Standby, nil
+	case "off":
+		return Off, nil
+	}
+	return 0, fmt.Errorf("invalid power state: %s (must be 'on', 'off', or 'standby')", s)
+}
+
+func (s State) String() string {
+	switch s {
+	case On:
+		return "on"
+	case Standby:
+		return "standby"
+	case Off:
+		return "off"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// vcpValue returns the MCCS power mode (VCP 0xD6) value for the state
+func (s State) vcpValue() uint32 {
+	switch s {
+	case Standby:
+		return 0x02
+	case Off:
+		return 0x04
+	}
+	return 0x01
+}
+
+// Result reports how a display, or all displays for broadcast methods,
+// responded to a power change
+type Result struct {
+	Display string // "Display 2 (DELL U2720Q)" or "All displays"
+	Method  string // "DDC/CI", "SC_MONITORPOWER" or "DPMS"
+	Err     error
+}
+
+// SetDDC changes the power state of the selected monitors through DDC/CI
+// VCP 0xD6. Each monitor gets its own result.
+func SetDDC(state State, sel display.Selector) ([]Result, error) {
+	displays, err := sel.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	monitors, err := ddc.Monitors()
+	if err != nil {
+		return nil, err
+	}
+	defer ddc.CloseAll(monitors)
+
+	var results []Result
+	for _, m := range monitors {
+		d, ok := display.FindByDeviceName(displays, m.DeviceName)
+		if !ok {
+			continue
+		}
+
+		results = append(results, Result{
+			Display: d.Label(),
+			Method:  "DDC/CI",
+			Err:     m.SetVCP(ddc.VCP_POWER_MODE, state.vcpValue()),
+		})
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("none of the selected displays support DDC/CI (%d DDC/CI monitors available)", len(monitors))
+	}
+
+	return results, nil
+}
+
+// SetAll changes the power state of every display at once using the
+// platform's broadcast mechanism (SC_MONITORPOWER on Windows, DPMS on X11)
+func SetAll(state State) Result {
+	method, err := setAll(state)
+	return Result{Display: "All displays", Method: method, Err: err}
+}
+
+// Failed returns an error if any result failed
+func Failed(results []Result) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Display, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !linux
+
+package power
+
+import "errors"
+
+// setAll is not available on this platform
+func setAll(state State) (string, error) {
+	return "", errors.New("display power control is not supported on this platform")
+}
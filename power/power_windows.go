@@ -0,0 +1,38 @@
+package power
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	HWND_BROADCAST  = 0xFFFF
+	WM_SYSCOMMAND   = 0x0112
+	SC_MONITORPOWER = 0xF170
+)
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+
+	procPostMessage = user32.NewProc("PostMessageW")
+)
+
+// setAll broadcasts SC_MONITORPOWER to every top-level window
+func setAll(state State) (string, error) {
+	// lParam: -1 powers on, 1 is low power, 2 shuts the display off
+	var lParam uintptr
+	switch state {
+	case On:
+		lParam = ^uintptr(0)
+	case Standby:
+		lParam = 1
+	case Off:
+		lParam = 2
+	}
+
+	ret, _, err := procPostMessage.Call(HWND_BROADCAST, WM_SYSCOMMAND, SC_MONITORPOWER, lParam)
+	if ret == 0 {
+		return "SC_MONITORPOWER", errors.New("failed to broadcast SC_MONITORPOWER: " + err.Error())
+	}
+	return "SC_MONITORPOWER", nil
+}
@@ -0,0 +1,254 @@
+package power
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// x11Conn is a minimal X11 protocol client, just enough to talk to the
+// DPMS extension without linking against Xlib
+type x11Conn struct {
+	conn net.Conn
+	seq  uint16
+}
+
+// X11 core error codes
+var x11Errors = map[byte]string{
+	1:  "BadRequest",
+	2:  "BadValue",
+	8:  "BadMatch",
+	10: "BadAccess",
+	11: "BadAlloc",
+	16: "BadLength",
+	17: "BadImplementation",
+}
+
+// dialX11 connects to the X server named by $DISPLAY
+func dialX11() (*x11Conn, error) {
+	name := os.Getenv("DISPLAY")
+	if name == "" {
+		return nil, errors.New("DISPLAY is not set (DPMS requires an X11 session)")
+	}
+
+	host, number, err := parseDisplayName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if host == "" || host == "unix" {
+		socket := "/tmp/.X11-unix/X" + number
+		conn, err = net.Dial("unix", socket)
+		if err != nil {
+			// Fall back to the abstract socket used by some servers
+			conn, err = net.Dial("unix", "@"+socket)
+		}
+	} else {
+		n, _ := strconv.Atoi(number)
+		conn, err = net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X display %s: %v", name, err)
+	}
+
+	x := &x11Conn{conn: conn}
+	authName, authData := readXauthority(host, number)
+	if err := x.setup(authName, authData); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return x, nil
+}
+
+// parseDisplayName splits "host:number.screen" into host and number
+func parseDisplayName(name string) (host, number string, err error) {
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid DISPLAY: %q", name)
+	}
+	host = name[:i]
+	number = name[i+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		number = number[:dot]
+	}
+	if _, err := strconv.Atoi(number); err != nil {
+		return "", "", fmt.Errorf("invalid DISPLAY: %q", name)
+	}
+	return host, number, nil
+}
+
+// readXauthority returns the authorization cookie for the display, if any
+func readXauthority(host, number string) (name, data []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	if host == "" || host == "unix" {
+		host, _ = os.Hostname()
+	}
+
+	const (
+		familyLocal = 256
+		familyWild  = 65535
+	)
+
+	r := bufio.NewReader(f)
+	for {
+		var family uint16
+		if err := binary.Read(r, binary.BigEndian, &family); err != nil {
+			return nil, nil
+		}
+
+		fields := make([][]byte, 4) // address, number, name, data
+		for i := range fields {
+			var length uint16
+			if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+				return nil, nil
+			}
+			fields[i] = make([]byte, length)
+			if _, err := io.ReadFull(r, fields[i]); err != nil {
+				return nil, nil
+			}
+		}
+
+		if string(fields[1]) != "" && string(fields[1]) != number {
+			continue
+		}
+		if family == familyWild || (family == familyLocal && string(fields[0]) == host) {
+			return fields[2], fields[3]
+		}
+	}
+}
+
+// setup performs the connection handshake
+func (x *x11Conn) setup(authName, authData []byte) error {
+	req := []byte{'l', 0}
+	req = binary.LittleEndian.AppendUint16(req, 11) // Protocol major version
+	req = binary.LittleEndian.AppendUint16(req, 0)  // Protocol minor version
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(authName)))
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(authData)))
+	req = append(req, 0, 0)
+	req = append(req, pad4(authName)...)
+	req = append(req, pad4(authData)...)
+
+	if _, err := x.conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send X11 setup: %v", err)
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(x.conn, header); err != nil {
+		return fmt.Errorf("failed to read X11 setup reply: %v", err)
+	}
+	extra := make([]byte, int(binary.LittleEndian.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(x.conn, extra); err != nil {
+		return fmt.Errorf("failed to read X11 setup reply: %v", err)
+	}
+
+	if header[0] != 1 {
+		reason := extra
+		if n := int(header[1]); n <= len(reason) {
+			reason = reason[:n]
+		}
+		return fmt.Errorf("X server refused connection: %s", strings.TrimSpace(string(reason)))
+	}
+
+	return nil
+}
+
+// send writes a request, filling in its length field, and returns its sequence number
+func (x *x11Conn) send(req []byte) (uint16, error) {
+	req = pad4(req)
+	binary.LittleEndian.PutUint16(req[2:], uint16(len(req)/4))
+
+	if _, err := x.conn.Write(req); err != nil {
+		return 0, fmt.Errorf("failed to send X11 request: %v", err)
+	}
+	x.seq++
+	return x.seq, nil
+}
+
+// reply waits for the reply to the request with the given sequence number.
+// Errors for that or any earlier request are returned as Go errors.
+func (x *x11Conn) reply(seq uint16) ([]byte, error) {
+	for {
+		packet := make([]byte, 32)
+		if _, err := io.ReadFull(x.conn, packet); err != nil {
+			return nil, fmt.Errorf("failed to read X11 reply: %v", err)
+		}
+
+		switch packet[0] {
+		case 0: // Error
+			name, ok := x11Errors[packet[1]]
+			if !ok {
+				name = fmt.Sprintf("error %d", packet[1])
+			}
+			return nil, fmt.Errorf("X server returned %s", name)
+		case 1: // Reply
+			extra := binary.LittleEndian.Uint32(packet[4:])
+			if extra > 0 {
+				body := make([]byte, int(extra)*4)
+				if _, err := io.ReadFull(x.conn, body); err != nil {
+					return nil, fmt.Errorf("failed to read X11 reply: %v", err)
+				}
+				packet = append(packet, body...)
+			}
+			if binary.LittleEndian.Uint16(packet[2:]) == seq {
+				return packet, nil
+			}
+		}
+		// Events and replies to other requests are ignored
+	}
+}
+
+// queryExtension returns the major opcode of a server extension
+func (x *x11Conn) queryExtension(name string) (byte, error) {
+	req := []byte{98, 0, 0, 0} // QueryExtension
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(name)))
+	req = append(req, 0, 0)
+	req = append(req, name...)
+
+	seq, err := x.send(req)
+	if err != nil {
+		return 0, err
+	}
+	reply, err := x.reply(seq)
+	if err != nil {
+		return 0, err
+	}
+	if reply[8] == 0 {
+		return 0, fmt.Errorf("X server does not support the %s extension", name)
+	}
+	return reply[9], nil
+}
+
+func (x *x11Conn) Close() error {
+	return x.conn.Close()
+}
+
+// pad4 pads b with zeros to a multiple of four bytes
+func pad4(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+package power
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDisplayName(t *testing.T) {
+	tests := []struct {
+		name         string
+		host, number string
+		ok           bool
+	}{
+		{":0", "", "0", true},
+		{":1", "", "1", true},
+		{"host:1.0", "host", "1", true},
+		{"unix:0", "unix", "0", true},
+		{"[::1]:2.1", "[::1]", "2", true},
+		{"", "", "", false},
+		{"0", "", "", false},
+		{":", "", "", false},
+		{"host:", "", "", false},
+		{":x.0", "", "", false},
+	}
+	for _, tt := range tests {
+		host, number, err := parseDisplayName(tt.name)
+		if (err == nil) != tt.ok || host != tt.host || number != tt.number {
+			t.Errorf("parseDisplayName(%q) = %q, %q, %v, want %q, %q", tt.name, host, number, err, tt.host, tt.number)
+		}
+	}
+}
+
+// xauthEntry is one record of an Xauthority file
+type xauthEntry struct {
+	family                      uint16
+	address, number, name, data string
+}
+
+// writeXauthority writes the entries to a file and points $XAUTHORITY at it
+func writeXauthority(t *testing.T, entries ...xauthEntry) {
+	t.Helper()
+	var b []byte
+	for _, e := range entries {
+		b = binary.BigEndian.AppendUint16(b, e.family)
+		for _, field := range []string{e.address, e.number, e.name, e.data} {
+			b = binary.BigEndian.AppendUint16(b, uint16(len(field)))
+			b = append(b, field...)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "Xauthority")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XAUTHORITY", path)
+}
+
+func TestReadXauthority(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	const cookie = "MIT-MAGIC-COOKIE-1"
+	writeXauthority(t,
+		xauthEntry{256, "otherhost", "0", cookie, "other"},
+		xauthEntry{256, hostname, "1", cookie, "local1"},
+		xauthEntry{256, hostname, "0", cookie, "local0"},
+		xauthEntry{256, "remote", "", cookie, "remote-any"},
+		xauthEntry{65535, "", "7", cookie, "wild7"},
+	)
+
+	tests := []struct {
+		host, number string
+		data         string
+	}{
+		{"", "0", "local0"},
+		{"unix", "1", "local1"},
+		{"remote", "3", "remote-any"}, // An empty number matches any display
+		{"elsewhere", "7", "wild7"},
+		{"elsewhere", "0", ""},
+		{"", "5", ""},
+	}
+	for _, tt := range tests {
+		name, data := readXauthority(tt.host, tt.number)
+		if string(data) != tt.data || (tt.data != "" && string(name) != cookie) {
+			t.Errorf("readXauthority(%q, %q) = %q, %q, want %q", tt.host, tt.number, name, data, tt.data)
+		}
+	}
+}
+
+func TestReadXauthorityInvalid(t *testing.T) {
+	t.Setenv("XAUTHORITY", filepath.Join(t.TempDir(), "missing"))
+	if name, data := readXauthority("", "0"); name != nil || data != nil {
+		t.Errorf("missing file: got %q, %q", name, data)
+	}
+
+	// A record cut short after the family
+	path := filepath.Join(t.TempDir(), "Xauthority")
+	if err := os.WriteFile(path, []byte{0xFF, 0xFF, 0, 5, 'a'}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XAUTHORITY", path)
+	if name, data := readXauthority("", "0"); name != nil || data != nil {
+		t.Errorf("truncated file: got %q, %q", name, data)
+	}
+}
@@ -0,0 +1,251 @@
+package revert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jipaix/lumos/gamma"
+	"github.com/jipaix/lumos/mode"
+)
+
+// ErrNoPending is returned when there is no pending revert
+var ErrNoPending = errors.New("no pending revert")
+
+// Snapshot is the state to restore when a temporary change expires
+type Snapshot struct {
+	Token    string       `json:"token"` // Identifies the helper process waiting for this snapshot
+	Created  time.Time    `json:"created"`
+	RevertAt time.Time    `json:"revert_at"`
+	Changes  []string     `json:"changes"` // What was changed, e.g. "gamma 100%"
+	Confirm  bool         `json:"confirm"` // Waiting for "lumos confirm" rather than a --for timer
+	Modes    []ModeState  `json:"modes,omitempty"`
+	HDR      []HDRState   `json:"hdr,omitempty"`
+	Gamma    []GammaState `json:"gamma,omitempty"`
+	Night    *NightState  `json:"night,omitempty"`
+}
+
+// ModeState is the previous display mode of a display
+type ModeState struct {
+	ID   string    `json:"id"` // Stable display ID
+	Mode mode.Mode `json:"mode"`
+}
+
+// HDRState is the previous HDR state of a display
+type HDRState struct {
+	ID      string `json:"id"` // Stable display ID
+	Enabled bool   `json:"enabled"`
+}
+
+// GammaState is the previous gamma ramp and pipeline layers of a display
+type GammaState struct {
+	ID       string          `json:"id"` // Stable display ID
+	Ramp     gamma.GammaRamp `json:"ramp"`
+	Pipeline *gamma.Pipeline `json:"pipeline,omitempty"` // Nil when no layers were set
+}
+
+// NightState is the previous night light state
+type NightState struct {
+	Enabled  bool    `json:"enabled"`
+	Strength float64 `json:"strength"`
+}
+
+// NewSnapshot creates an empty snapshot that is due after d
+func NewSnapshot(now time.Time, d time.Duration) (*Snapshot, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to create revert token: %v", err)
+	}
+	return &Snapshot{
+		Token:    hex.EncodeToString(token),
+		Created:  now,
+		RevertAt: now.Add(d),
+	}, nil
+}
+
+// Remaining returns how long until the snapshot is due, zero when it is
+func (s *Snapshot) Remaining(now time.Time) time.Duration {
+	return max(s.RevertAt.Sub(now), 0)
+}
+
+// Path returns where the pending snapshot is stored
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lumos", "revert.json"), nil
+}
+
+// Save stores the snapshot at path, creating its directory
+func (s *Snapshot) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so Load never sees a partial snapshot
+	tmp := path + "." + rand.Text() + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Load reads the pending snapshot at path. It returns ErrNoPending when
+// there is none.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid revert file %s: %v", path, err)
+	}
+	return &s, nil
+}
+
+// Take removes the pending snapshot at path and returns it, so that only
+// one process restores it. With a token, only that snapshot is taken.
+func Take(path, token string) (*Snapshot, error) {
+	if token != "" {
+		s, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if s.Token != token {
+			return nil, ErrNoPending
+		}
+	}
+
+	// Renaming is atomic: of several processes taking the snapshot at the
+	// same time, exactly one moves it away and the others find nothing
+	taken := path + "." + rand.Text() + ".taken"
+	if err := os.Rename(path, taken); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoPending
+		}
+		return nil, err
+	}
+	defer os.Remove(taken)
+
+	s, err := Load(taken)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" && s.Token != token {
+		// Replaced after the check above: put the newer snapshot back
+		// unless an even newer one was saved meanwhile
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			os.Rename(taken, path)
+		}
+		return nil, ErrNoPending
+	}
+	return s, nil
+}
+
+// Clock provides time to Wait so it can run on a fake clock in tests
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// SystemClock is the real time Clock
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// ConfirmGrace is how long the helper waits past the deadline of a
+// confirmation before reverting itself. Until then the process asking for
+// confirmation reverts, the helper only steps in when that process died.
+const ConfirmGrace = 5 * time.Second
+
+// PollInterval is how often Wait checks whether the snapshot was canceled
+// or replaced, and catches up on time lost while the machine slept
+const PollInterval = 30 * time.Second
+
+// Wait blocks until the snapshot with token at path is due and takes it.
+// It returns ErrNoPending when the snapshot is canceled, reverted by
+// someone else or replaced by a newer one before it is due.
+func Wait(clock Clock, path, token string, poll time.Duration) (*Snapshot, error) {
+	for {
+		s, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if s.Token != token {
+			return nil, ErrNoPending
+		}
+
+		due := s.RevertAt
+		if s.Confirm {
+			due = due.Add(ConfirmGrace)
+		}
+		remaining := due.Sub(clock.Now())
+		if remaining <= 0 {
+			return Take(path, token)
+		}
+		clock.Sleep(min(remaining, poll))
+	}
+}
+
+// Confirm waits for the change with token at path to be confirmed, either
+// through confirmed or by another process taking the snapshot ("lumos
+// confirm"). It returns nil when the change was confirmed, and the taken
+// snapshot, to be restored by the caller, when the deadline passes first.
+// tick is called once per poll with the time left.
+func Confirm(clock Clock, path, token string, confirmed <-chan struct{}, poll time.Duration, tick func(left time.Duration)) (*Snapshot, error) {
+	for {
+		select {
+		case <-confirmed:
+			_, err := Take(path, token)
+			if errors.Is(err, ErrNoPending) {
+				err = nil
+			}
+			return nil, err
+		default:
+		}
+
+		s, err := Load(path)
+		if errors.Is(err, ErrNoPending) || (err == nil && s.Token != token) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		left := s.Remaining(clock.Now())
+		if left == 0 {
+			s, err := Take(path, token)
+			if errors.Is(err, ErrNoPending) {
+				return nil, nil // Confirmed at the last moment
+			}
+			return s, err
+		}
+		if tick != nil {
+			tick(left)
+		}
+		clock.Sleep(min(left, poll))
+	}
+}
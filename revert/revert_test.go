@@ -0,0 +1,332 @@
+package revert
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose Sleep advances the time instantly. jump, when
+// set, is added on the first Sleep as if the machine had been suspended.
+type fakeClock struct {
+	now    time.Time
+	jump   time.Duration
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d + c.jump)
+	c.jump = 0
+}
+
+var start = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// pending saves a snapshot due after d and returns it with its path
+func pending(t *testing.T, d time.Duration) (*Snapshot, string) {
+	t.Helper()
+	s, err := NewSnapshot(start, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Changes = []string{"gamma 80%"}
+	s.HDR = []HDRState{{ID: "DEL-40B4-1A2B3C4D", Enabled: true}}
+
+	path := filepath.Join(t.TempDir(), "lumos", "revert.json")
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+// leftovers returns the files next to the snapshot other than itself
+func leftovers(t *testing.T, path string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != filepath.Base(path) {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+func TestSaveLoad(t *testing.T) {
+	s, path := pending(t, time.Minute)
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != s.Token || !got.RevertAt.Equal(start.Add(time.Minute)) || !slices.Equal(got.Changes, s.Changes) || len(got.HDR) != 1 || !got.HDR[0].Enabled {
+		t.Errorf("Load = %+v, want %+v", got, s)
+	}
+	if names := leftovers(t, path); len(names) != 0 {
+		t.Errorf("Save left %v behind", names)
+	}
+	if got.Remaining(start) != time.Minute || got.Remaining(start.Add(time.Hour)) != 0 {
+		t.Errorf("Remaining = %v at creation, %v after an hour", got.Remaining(start), got.Remaining(start.Add(time.Hour)))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "revert.json")); !errors.Is(err, ErrNoPending) {
+		t.Errorf("Load without a file = %v, want ErrNoPending", err)
+	}
+}
+
+func TestTake(t *testing.T) {
+	s, path := pending(t, time.Minute)
+
+	if _, err := Take(path, "other"); !errors.Is(err, ErrNoPending) {
+		t.Errorf("Take with another token = %v, want ErrNoPending", err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Fatalf("snapshot gone after taking another token: %v", err)
+	}
+
+	got, err := Take(path, s.Token)
+	if err != nil || got.Token != s.Token {
+		t.Fatalf("Take = %+v, %v, want the snapshot", got, err)
+	}
+	if _, err := Take(path, ""); !errors.Is(err, ErrNoPending) {
+		t.Errorf("second Take = %v, want ErrNoPending", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("snapshot still there after Take: %v", err)
+	}
+	if names := leftovers(t, path); len(names) != 0 {
+		t.Errorf("Take left %v behind", names)
+	}
+}
+
+func TestTakeConcurrent(t *testing.T) {
+	s, path := pending(t, time.Minute)
+
+	const takers = 16
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		taken int
+		errs  []error
+	)
+	for i := 0; i < takers; i++ {
+		token := s.Token
+		if i%2 == 1 {
+			token = "" // Like "lumos revert"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := Take(path, token)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil && got.Token == s.Token:
+				taken++
+			case !errors.Is(err, ErrNoPending):
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if taken != 1 || len(errs) != 0 {
+		t.Errorf("snapshot taken %d times with errors %v, want exactly once", taken, errs)
+	}
+}
+
+func TestWait(t *testing.T) {
+	s, path := pending(t, 90*time.Second)
+	clock := &fakeClock{now: start}
+
+	got, err := Wait(clock, path, s.Token, PollInterval)
+	if err != nil || got.Token != s.Token {
+		t.Fatalf("Wait = %+v, %v, want the snapshot", got, err)
+	}
+	if want := []time.Duration{30 * time.Second, 30 * time.Second, 30 * time.Second}; !slices.Equal(clock.sleeps, want) {
+		t.Errorf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+	if !clock.now.Equal(s.RevertAt) {
+		t.Errorf("reverted at %v, want %v", clock.now, s.RevertAt)
+	}
+
+	// The snapshot is restored once: a second waiter finds nothing
+	if _, err := Wait(clock, path, s.Token, PollInterval); !errors.Is(err, ErrNoPending) {
+		t.Errorf("second Wait = %v, want ErrNoPending", err)
+	}
+}
+
+func TestWaitSuspended(t *testing.T) {
+	s, path := pending(t, 10*time.Minute)
+
+	// The machine sleeps through the deadline during the first poll and
+	// several helpers wake up at once: only one of them restores it
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		taken []*Snapshot
+		errs  []error
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clock := &fakeClock{now: start, jump: time.Hour}
+			got, err := Wait(clock, path, s.Token, PollInterval)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				taken = append(taken, got)
+			} else if !errors.Is(err, ErrNoPending) {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(taken) != 1 || len(errs) != 0 {
+		t.Errorf("snapshot restored %d times with errors %v, want exactly once", len(taken), errs)
+	}
+}
+
+func TestWaitReplaced(t *testing.T) {
+	s, path := pending(t, time.Minute)
+	clock := &fakeClock{now: start}
+
+	newer, err := NewSnapshot(start, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := newer.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Wait(clock, path, s.Token, PollInterval); !errors.Is(err, ErrNoPending) {
+		t.Errorf("Wait for a replaced snapshot = %v, want ErrNoPending", err)
+	}
+	if got, err := Load(path); err != nil || got.Token != newer.Token {
+		t.Errorf("newer snapshot = %+v, %v, want it left in place", got, err)
+	}
+}
+
+func TestWaitConfirmGrace(t *testing.T) {
+	s, path := pending(t, 20*time.Second)
+	s.Confirm = true
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: start}
+
+	if _, err := Wait(clock, path, s.Token, PollInterval); err != nil {
+		t.Fatal(err)
+	}
+	if want := s.RevertAt.Add(ConfirmGrace); !clock.now.Equal(want) {
+		t.Errorf("reverted at %v, want %v", clock.now, want)
+	}
+}
+
+func TestConfirmDeadline(t *testing.T) {
+	s, path := pending(t, 10*time.Second)
+	clock := &fakeClock{now: start}
+	var ticks []time.Duration
+
+	got, err := Confirm(clock, path, s.Token, nil, 4*time.Second, func(left time.Duration) {
+		ticks = append(ticks, left)
+	})
+	if err != nil || got == nil || got.Token != s.Token {
+		t.Fatalf("Confirm = %+v, %v, want the snapshot to restore", got, err)
+	}
+	if want := []time.Duration{10 * time.Second, 6 * time.Second, 2 * time.Second}; !slices.Equal(ticks, want) {
+		t.Errorf("ticks = %v, want %v", ticks, want)
+	}
+	if want := []time.Duration{4 * time.Second, 4 * time.Second, 2 * time.Second}; !slices.Equal(clock.sleeps, want) {
+		t.Errorf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+	if _, err := Load(path); !errors.Is(err, ErrNoPending) {
+		t.Errorf("snapshot still pending after the deadline: %v", err)
+	}
+}
+
+func TestConfirmChannel(t *testing.T) {
+	s, path := pending(t, 15*time.Second)
+	clock := &fakeClock{now: start}
+	confirmed := make(chan struct{})
+	var ticks []time.Duration
+
+	// Confirmed from the prompt on the second tick
+	got, err := Confirm(clock, path, s.Token, confirmed, time.Second, func(left time.Duration) {
+		ticks = append(ticks, left)
+		if len(ticks) == 2 {
+			close(confirmed)
+		}
+	})
+	if err != nil || got != nil {
+		t.Fatalf("Confirm = %+v, %v, want confirmed", got, err)
+	}
+	if want := []time.Duration{15 * time.Second, 14 * time.Second}; !slices.Equal(ticks, want) {
+		t.Errorf("ticks = %v, want %v", ticks, want)
+	}
+	if _, err := Load(path); !errors.Is(err, ErrNoPending) {
+		t.Errorf("snapshot still pending after confirming: %v", err)
+	}
+	if names := leftovers(t, path); len(names) != 0 {
+		t.Errorf("Confirm left %v behind", names)
+	}
+}
+
+func TestConfirmTaken(t *testing.T) {
+	s, path := pending(t, 15*time.Second)
+	clock := &fakeClock{now: start}
+	var taken *Snapshot
+
+	// "lumos confirm" in another process takes the snapshot meanwhile
+	got, err := Confirm(clock, path, s.Token, nil, time.Second, func(left time.Duration) {
+		if left == 12*time.Second {
+			var err error
+			if taken, err = Take(path, ""); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	if err != nil || got != nil {
+		t.Fatalf("Confirm = %+v, %v, want confirmed", got, err)
+	}
+	if taken == nil || taken.Token != s.Token {
+		t.Errorf("taken by the other process: %+v", taken)
+	}
+	if !clock.now.Equal(start.Add(4 * time.Second)) {
+		t.Errorf("confirmed at %v, want 4s after the start", clock.now.Sub(start))
+	}
+}
+
+func TestConfirmReplaced(t *testing.T) {
+	s, path := pending(t, 15*time.Second)
+	clock := &fakeClock{now: start}
+
+	newer, err := NewSnapshot(start, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Confirm(clock, path, s.Token, nil, time.Second, func(left time.Duration) {
+		if left == 10*time.Second {
+			if err := newer.Save(path); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	if err != nil || got != nil {
+		t.Fatalf("Confirm = %+v, %v, want nothing to restore", got, err)
+	}
+	if got, err := Load(path); err != nil || got.Token != newer.Token {
+		t.Errorf("newer snapshot = %+v, %v, want it left in place", got, err)
+	}
+}
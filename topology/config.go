@@ -0,0 +1,411 @@
+package topology
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jipaix/lumos/display"
+	dc "github.com/jipaix/lumos/displayconfig"
+)
+
+// configVersion is written to saved files so the format can evolve
+const configVersion = 1
+
+// Config is an exact display configuration as returned by
+// QueryDisplayConfig, with the identity of the monitor on each path so it
+// can be restored after adapter LUIDs and target IDs change
+type Config struct {
+	Version int    `json:"version"`
+	Paths   []Path `json:"paths"`
+	Modes   []Mode `json:"modes"`
+}
+
+// Path is an active display path and the monitor it drives
+type Path struct {
+	dc.DISPLAYCONFIG_PATH_INFO
+	Monitor Monitor `json:"monitor"`
+}
+
+// Mode is a DISPLAYCONFIG_MODE_INFO with its union spelled out
+type Mode struct {
+	InfoType  uint32                               `json:"infoType"`
+	Id        uint32                               `json:"id"`
+	AdapterId dc.LUID                              `json:"adapterId"`
+	Source    *dc.DISPLAYCONFIG_SOURCE_MODE        `json:"source,omitempty"`
+	Target    *dc.DISPLAYCONFIG_TARGET_MODE        `json:"target,omitempty"`
+	Desktop   *dc.DISPLAYCONFIG_DESKTOP_IMAGE_INFO `json:"desktop,omitempty"`
+}
+
+// Monitor identifies the monitor behind a display target
+type Monitor struct {
+	ID                string `json:"id,omitempty"` // Stable ID from the EDID, as in display.Display
+	Name              string `json:"name,omitempty"`
+	DevicePath        string `json:"devicePath,omitempty"`
+	ManufacturerId    uint16 `json:"manufacturerId"`
+	ProductCode       uint16 `json:"productCode"`
+	ConnectorInstance uint32 `json:"connectorInstance"`
+}
+
+// Target is a display target that can currently be driven and the monitor on it
+type Target struct {
+	AdapterId dc.LUID
+	Id        uint32
+	Monitor   Monitor
+}
+
+// NewMonitor extracts the monitor identity from a target device name
+func NewMonitor(name *dc.DISPLAYCONFIG_TARGET_DEVICE_NAME) Monitor {
+	return Monitor{
+		ID:                display.IDForDevicePath(name.DevicePath()),
+		Name:              name.FriendlyName(),
+		DevicePath:        name.DevicePath(),
+		ManufacturerId:    name.EdidManufactureId,
+		ProductCode:       name.EdidProductCodeId,
+		ConnectorInstance: name.ConnectorInstance,
+	}
+}
+
+func (m Monitor) String() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return fmt.Sprintf("monitor %04X:%04X", m.ManufacturerId, m.ProductCode)
+}
+
+// NewConfig builds a Config from raw QueryDisplayConfig output. monitors
+// holds the monitor of each path, in the same order.
+func NewConfig(paths []dc.DISPLAYCONFIG_PATH_INFO, modes []dc.DISPLAYCONFIG_MODE_INFO, monitors []Monitor) *Config {
+	c := &Config{Version: configVersion}
+
+	for i, p := range paths {
+		path := Path{DISPLAYCONFIG_PATH_INFO: p}
+		if i < len(monitors) {
+			path.Monitor = monitors[i]
+		}
+		c.Paths = append(c.Paths, path)
+	}
+
+	for i := range modes {
+		m := &modes[i]
+		mode := Mode{InfoType: m.InfoType, Id: m.Id, AdapterId: m.AdapterId}
+		switch m.InfoType {
+		case dc.DISPLAYCONFIG_MODE_INFO_TYPE_SOURCE:
+			src := *m.SourceMode()
+			mode.Source = &src
+		case dc.DISPLAYCONFIG_MODE_INFO_TYPE_TARGET:
+			tgt := *m.TargetMode()
+			mode.Target = &tgt
+		case dc.DISPLAYCONFIG_MODE_INFO_TYPE_DESKTOP_IMAGE:
+			img := *m.DesktopImageInfo()
+			mode.Desktop = &img
+		}
+		c.Modes = append(c.Modes, mode)
+	}
+
+	return c
+}
+
+// Raw converts the configuration back to SetDisplayConfig arguments
+func (c *Config) Raw() ([]dc.DISPLAYCONFIG_PATH_INFO, []dc.DISPLAYCONFIG_MODE_INFO, error) {
+	paths := make([]dc.DISPLAYCONFIG_PATH_INFO, len(c.Paths))
+	for i, p := range c.Paths {
+		for _, idx := range []uint32{p.SourceInfo.ModeInfoIdx, p.TargetInfo.ModeInfoIdx} {
+			if idx != dc.DISPLAYCONFIG_PATH_MODE_IDX_INVALID && int(idx) >= len(c.Modes) {
+				return nil, nil, fmt.Errorf("path %d references mode %d, but only %d modes exist", i, idx, len(c.Modes))
+			}
+		}
+		paths[i] = p.DISPLAYCONFIG_PATH_INFO
+	}
+
+	modes := make([]dc.DISPLAYCONFIG_MODE_INFO, len(c.Modes))
+	for i, m := range c.Modes {
+		raw := &modes[i]
+		raw.InfoType = m.InfoType
+		raw.Id = m.Id
+		raw.AdapterId = m.AdapterId
+
+		switch {
+		case m.InfoType == dc.DISPLAYCONFIG_MODE_INFO_TYPE_SOURCE && m.Source != nil:
+			*raw.SourceMode() = *m.Source
+		case m.InfoType == dc.DISPLAYCONFIG_MODE_INFO_TYPE_TARGET && m.Target != nil:
+			*raw.TargetMode() = *m.Target
+		case m.InfoType == dc.DISPLAYCONFIG_MODE_INFO_TYPE_DESKTOP_IMAGE && m.Desktop != nil:
+			*raw.DesktopImageInfo() = *m.Desktop
+		default:
+			return nil, nil, fmt.Errorf("mode %d has type %d but no matching data", i, m.InfoType)
+		}
+	}
+
+	return paths, modes, nil
+}
+
+type targetKey struct {
+	adapter dc.LUID
+	id      uint32
+}
+
+// Remap returns a copy of the configuration whose paths and modes point
+// at the given targets, matching monitors by identity rather than by path
+// index: first by device path, then by EDID IDs and connector, then by
+// EDID IDs alone when that is unambiguous. Each source moves to the
+// adapter of the first target it drives, so sources follow their monitors
+// to another GPU, and is renumbered when its ID is taken on that adapter.
+func (c *Config) Remap(targets []Target) (*Config, error) {
+	used := make([]bool, len(targets))
+	targetMap := make(map[targetKey]targetKey)
+	var sources []targetKey // In path order
+	sourceAdapter := make(map[targetKey]dc.LUID)
+
+	// Old adapters whose targets all moved to one new adapter, for modes
+	// that aren't tied to a mapped source or target
+	adapterMap := make(map[dc.LUID]dc.LUID)
+	ambiguous := make(map[dc.LUID]bool)
+
+	for _, p := range c.Paths {
+		old := targetKey{p.TargetInfo.AdapterId, p.TargetInfo.Id}
+		t, done := targetMap[old]
+		if !done {
+			// Paths of the same target, e.g. from QDC_ALL_PATHS, share one match
+			idx := matchMonitor(p.Monitor, targets, used)
+			if idx < 0 {
+				return nil, fmt.Errorf("%s is not connected", p.Monitor)
+			}
+			used[idx] = true
+			t = targetKey{targets[idx].AdapterId, targets[idx].Id}
+			targetMap[old] = t
+		}
+
+		src := targetKey{p.SourceInfo.AdapterId, p.SourceInfo.Id}
+		if _, ok := sourceAdapter[src]; !ok {
+			sourceAdapter[src] = t.adapter
+			sources = append(sources, src)
+		}
+
+		if mapped, ok := adapterMap[old.adapter]; !ok {
+			adapterMap[old.adapter] = t.adapter
+		} else if mapped != t.adapter {
+			ambiguous[old.adapter] = true
+		}
+	}
+
+	sourceMap := renumberSources(sources, sourceAdapter)
+
+	mapAdapter := func(luid dc.LUID) dc.LUID {
+		if mapped, ok := adapterMap[luid]; ok && !ambiguous[luid] {
+			return mapped
+		}
+		return luid
+	}
+
+	out := &Config{Version: c.Version}
+	for _, p := range c.Paths {
+		t := targetMap[targetKey{p.TargetInfo.AdapterId, p.TargetInfo.Id}]
+		p.TargetInfo.AdapterId = t.adapter
+		p.TargetInfo.Id = t.id
+		src := sourceMap[targetKey{p.SourceInfo.AdapterId, p.SourceInfo.Id}]
+		p.SourceInfo.AdapterId = src.adapter
+		p.SourceInfo.Id = src.id
+		out.Paths = append(out.Paths, p)
+	}
+
+	for _, m := range c.Modes {
+		key := targetKey{m.AdapterId, m.Id}
+		switch m.InfoType {
+		case dc.DISPLAYCONFIG_MODE_INFO_TYPE_SOURCE:
+			if src, ok := sourceMap[key]; ok {
+				m.AdapterId = src.adapter
+				m.Id = src.id
+			} else {
+				m.AdapterId = mapAdapter(m.AdapterId)
+			}
+		case dc.DISPLAYCONFIG_MODE_INFO_TYPE_TARGET, dc.DISPLAYCONFIG_MODE_INFO_TYPE_DESKTOP_IMAGE:
+			// Target and desktop image modes are keyed by target ID
+			if t, ok := targetMap[key]; ok {
+				m.AdapterId = t.adapter
+				m.Id = t.id
+			} else {
+				m.AdapterId = mapAdapter(m.AdapterId)
+			}
+		default:
+			m.AdapterId = mapAdapter(m.AdapterId)
+		}
+		out.Modes = append(out.Modes, m)
+	}
+
+	return out, nil
+}
+
+// renumberSources returns the new adapter and ID of each source. Sources
+// that stay on their adapter keep their IDs; the ones that move keep theirs
+// when it is free there and otherwise take the lowest free ID, so sources
+// from two GPUs never share an ID on one adapter.
+func renumberSources(sources []targetKey, adapters map[targetKey]dc.LUID) map[targetKey]targetKey {
+	out := make(map[targetKey]targetKey, len(sources))
+	taken := make(map[targetKey]bool, len(sources))
+	for _, src := range sources {
+		if adapters[src] == src.adapter {
+			out[src] = src
+			taken[src] = true
+		}
+	}
+	for _, src := range sources {
+		if _, ok := out[src]; ok {
+			continue
+		}
+		key := targetKey{adapters[src], src.id}
+		if taken[key] {
+			key.id = 0
+			for taken[key] {
+				key.id++
+			}
+		}
+		out[src] = key
+		taken[key] = true
+	}
+	return out
+}
+
+// matchMonitor returns the index of the unused target showing the monitor, or -1
+func matchMonitor(m Monitor, targets []Target, used []bool) int {
+	find := func(match func(t Monitor) bool) []int {
+		var found []int
+		for i, t := range targets {
+			if !used[i] && match(t.Monitor) {
+				found = append(found, i)
+			}
+		}
+		return found
+	}
+
+	sameEDID := func(t Monitor) bool {
+		return t.ManufacturerId == m.ManufacturerId && t.ProductCode == m.ProductCode
+	}
+
+	// The stable ID follows a monitor to another port, but identical
+	// monitors without serial numbers share it
+	if m.ID != "" {
+		if found := find(func(t Monitor) bool { return t.ID == m.ID }); len(found) == 1 {
+			return found[0]
+		}
+	}
+	if m.DevicePath != "" {
+		if found := find(func(t Monitor) bool { return t.DevicePath == m.DevicePath }); len(found) > 0 {
+			return found[0]
+		}
+	}
+	if found := find(func(t Monitor) bool { return sameEDID(t) && t.ConnectorInstance == m.ConnectorInstance }); len(found) > 0 {
+		return found[0]
+	}
+	if found := find(sameEDID); len(found) == 1 {
+		return found[0]
+	}
+	return -1
+}
+
+// Marshal encodes the configuration as indented JSON
+func (c *Config) Marshal() ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// Unmarshal decodes a configuration written by Marshal
+func Unmarshal(data []byte) (*Config, error) {
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid display configuration: %v", err)
+	}
+	if c.Version != configVersion {
+		return nil, fmt.Errorf("unsupported display configuration version %d", c.Version)
+	}
+	if len(c.Paths) == 0 {
+		return nil, errors.New("display configuration has no paths")
+	}
+	return &c, nil
+}
+
+// Save writes the configuration to a file
+func (c *Config) Save(path string) error {
+	data, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Load reads a configuration saved with Save
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(data)
+}
+
+// Capture returns the active display configuration
+func Capture() (*Config, error) {
+	paths, modes, _, err := dc.Query(dc.QDC_ONLY_ACTIVE_PATHS)
+	if err != nil {
+		return nil, err
+	}
+
+	monitors := make([]Monitor, len(paths))
+	for i, p := range paths {
+		name, err := dc.TargetName(p.TargetInfo.AdapterId, p.TargetInfo.Id)
+		if err != nil {
+			return nil, err
+		}
+		monitors[i] = NewMonitor(name)
+	}
+
+	return NewConfig(paths, modes, monitors), nil
+}
+
+// AvailableTargets returns every target that currently has a monitor attached
+func AvailableTargets() ([]Target, error) {
+	paths, _, _, err := dc.Query(dc.QDC_ALL_PATHS)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	seen := make(map[targetKey]bool)
+	for _, p := range paths {
+		key := targetKey{p.TargetInfo.AdapterId, p.TargetInfo.Id}
+		if p.TargetInfo.TargetAvailable == 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		name, err := dc.TargetName(key.adapter, key.id)
+		if err != nil {
+			continue
+		}
+		targets = append(targets, Target{AdapterId: key.adapter, Id: key.id, Monitor: NewMonitor(name)})
+	}
+	return targets, nil
+}
+
+// Restore applies a saved configuration to the monitors that are
+// connected now, validating it before committing
+func Restore(c *Config) error {
+	targets, err := AvailableTargets()
+	if err != nil {
+		return err
+	}
+
+	remapped, err := c.Remap(targets)
+	if err != nil {
+		return err
+	}
+
+	paths, modes, err := remapped.Raw()
+	if err != nil {
+		return err
+	}
+
+	if err := dc.Set(paths, modes, dc.SDC_VALIDATE|dc.SDC_USE_SUPPLIED_DISPLAY_CONFIG); err != nil {
+		return fmt.Errorf("saved configuration rejected by validation: %v", err)
+	}
+	return dc.Set(paths, modes, dc.SDC_APPLY|dc.SDC_USE_SUPPLIED_DISPLAY_CONFIG|dc.SDC_SAVE_TO_DATABASE|dc.SDC_ALLOW_CHANGES)
+}
@@ -0,0 +1,323 @@
+package topology
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	dc "github.com/jipaix/lumos/displayconfig"
+)
+
+var (
+	gpu0 = dc.LUID{LowPart: 0x1000}
+	gpu1 = dc.LUID{LowPart: 0x2000}
+	gpu2 = dc.LUID{LowPart: 0x3000}
+
+	dell = Monitor{Name: "DELL U2720Q", DevicePath: `\\?\DISPLAY#DELA0B4#5&1a2b3c&0&UID4353`, ManufacturerId: 0xAC10, ProductCode: 0xA0B4, ConnectorInstance: 1}
+	lg   = Monitor{Name: "LG ULTRAGEAR", DevicePath: `\\?\DISPLAY#GSM5B7F#5&2b3c4d&0&UID4354`, ManufacturerId: 0x6D1E, ProductCode: 0x5B7F, ConnectorInstance: 1}
+)
+
+// testRaw returns an extended two monitor configuration on two GPUs, as
+// QueryDisplayConfig reports it: one source, target and desktop image mode
+// per path
+func testRaw() ([]dc.DISPLAYCONFIG_PATH_INFO, []dc.DISPLAYCONFIG_MODE_INFO, []Monitor) {
+	paths := make([]dc.DISPLAYCONFIG_PATH_INFO, 2)
+	modes := make([]dc.DISPLAYCONFIG_MODE_INFO, 6)
+
+	for i, adapter := range []dc.LUID{gpu0, gpu1} {
+		p := &paths[i]
+		p.Flags = dc.DISPLAYCONFIG_PATH_ACTIVE
+		p.SourceInfo.AdapterId = adapter
+		p.SourceInfo.Id = 0
+		p.SourceInfo.ModeInfoIdx = uint32(3 * i)
+		p.TargetInfo.AdapterId = adapter
+		p.TargetInfo.Id = uint32(0x1100 + i)
+		p.TargetInfo.ModeInfoIdx = uint32(3*i + 1)
+		p.TargetInfo.RefreshRate = dc.DISPLAYCONFIG_RATIONAL{Numerator: 144000, Denominator: 1000}
+		p.TargetInfo.TargetAvailable = 1
+
+		src := &modes[3*i]
+		src.InfoType = dc.DISPLAYCONFIG_MODE_INFO_TYPE_SOURCE
+		src.AdapterId = adapter
+		*src.SourceMode() = dc.DISPLAYCONFIG_SOURCE_MODE{Width: 2560, Height: 1440, PixelFormat: 4, Position: dc.POINTL{X: int32(2560 * i)}}
+
+		tgt := &modes[3*i+1]
+		tgt.InfoType = dc.DISPLAYCONFIG_MODE_INFO_TYPE_TARGET
+		tgt.AdapterId = adapter
+		tgt.Id = p.TargetInfo.Id
+		signal := &tgt.TargetMode().TargetVideoSignalInfo
+		signal.PixelRate = 580_000_000
+		signal.VSyncFreq = dc.DISPLAYCONFIG_RATIONAL{Numerator: 144000, Denominator: 1000}
+		signal.ActiveSize = dc.DISPLAYCONFIG_2DREGION{Cx: 2560, Cy: 1440}
+
+		img := &modes[3*i+2]
+		img.InfoType = dc.DISPLAYCONFIG_MODE_INFO_TYPE_DESKTOP_IMAGE
+		img.AdapterId = adapter
+		img.Id = p.TargetInfo.Id
+		img.DesktopImageInfo().DesktopImageRegion = dc.RECTL{Right: 2560, Bottom: 1440}
+	}
+	return paths, modes, []Monitor{dell, lg}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	paths, modes, monitors := testRaw()
+	c := NewConfig(paths, modes, monitors)
+
+	data, err := c.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, c) {
+		t.Errorf("Unmarshal(Marshal(c)) differs:\n%s", data)
+	}
+
+	gotPaths, gotModes, err := decoded.Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotPaths, paths) {
+		t.Errorf("paths differ after the round trip")
+	}
+	if !reflect.DeepEqual(gotModes, modes) {
+		t.Errorf("modes differ after the round trip")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	paths, modes, monitors := testRaw()
+	c := NewConfig(paths, modes, monitors)
+
+	file := filepath.Join(t.TempDir(), "docked.json")
+	if err := c.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, c) {
+		t.Error("Load(Save(c)) differs")
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"version": 2, "paths": [{}]}`,
+		`{"version": 1, "paths": []}`,
+	} {
+		if _, err := Unmarshal([]byte(data)); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestRawInvalid(t *testing.T) {
+	paths, modes, monitors := testRaw()
+
+	c := NewConfig(paths, modes, monitors)
+	c.Paths[0].TargetInfo.ModeInfoIdx = 42
+	if _, _, err := c.Raw(); err == nil {
+		t.Error("Raw accepted a path referencing a missing mode")
+	}
+
+	c = NewConfig(paths, modes, monitors)
+	c.Modes[1].Target = nil
+	if _, _, err := c.Raw(); err == nil {
+		t.Error("Raw accepted a target mode without data")
+	}
+}
+
+func TestRemap(t *testing.T) {
+	paths, modes, monitors := testRaw()
+	c := NewConfig(paths, modes, monitors)
+
+	// After redocking, the LG moved to the first GPU and the Dell to a new
+	// one, and the target IDs changed
+	targets := []Target{
+		{AdapterId: gpu0, Id: 0x2201, Monitor: lg},
+		{AdapterId: gpu2, Id: 0x2202, Monitor: dell},
+	}
+	remapped, err := c.Remap(targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		adapter dc.LUID
+		id      uint32
+	}{{gpu2, 0x2202}, {gpu0, 0x2201}}
+	for i, p := range remapped.Paths {
+		if p.TargetInfo.AdapterId != want[i].adapter || p.TargetInfo.Id != want[i].id {
+			t.Errorf("path %d (%s) targets %v/%x, want %v/%x", i, p.Monitor, p.TargetInfo.AdapterId, p.TargetInfo.Id, want[i].adapter, want[i].id)
+		}
+		if p.SourceInfo.AdapterId != want[i].adapter {
+			t.Errorf("path %d (%s) source is on %v, want %v", i, p.Monitor, p.SourceInfo.AdapterId, want[i].adapter)
+		}
+	}
+	for i, m := range remapped.Modes {
+		if m.AdapterId != want[i/3].adapter {
+			t.Errorf("mode %d is on %v, want %v", i, m.AdapterId, want[i/3].adapter)
+		}
+		if m.InfoType != dc.DISPLAYCONFIG_MODE_INFO_TYPE_SOURCE && m.Id != want[i/3].id {
+			t.Errorf("mode %d has id %x, want %x", i, m.Id, want[i/3].id)
+		}
+	}
+
+	// The original is untouched
+	if c.Paths[0].TargetInfo.AdapterId != gpu0 {
+		t.Error("Remap changed the original configuration")
+	}
+}
+
+func TestRemapSplitAdapter(t *testing.T) {
+	paths, modes, monitors := testRaw()
+	// Both monitors were on the first GPU, now each has its own
+	for i := range paths {
+		paths[i].SourceInfo.AdapterId = gpu0
+		paths[i].SourceInfo.Id = uint32(i)
+		paths[i].TargetInfo.AdapterId = gpu0
+	}
+	for i := range modes {
+		modes[i].AdapterId = gpu0
+	}
+	modes[3].Id = 1
+	c := NewConfig(paths, modes, monitors)
+
+	remapped, err := c.Remap([]Target{
+		{AdapterId: gpu1, Id: 1, Monitor: dell},
+		{AdapterId: gpu2, Id: 2, Monitor: lg},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []dc.LUID{gpu1, gpu2} {
+		if got := remapped.Paths[i].SourceInfo.AdapterId; got != want {
+			t.Errorf("path %d source is on %v, want %v", i, got, want)
+		}
+		if got := remapped.Modes[3*i].AdapterId; got != want {
+			t.Errorf("source mode %d is on %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestRemapClone(t *testing.T) {
+	paths, modes, monitors := testRaw()
+	// Both paths show the first source
+	paths[1].SourceInfo = paths[0].SourceInfo
+	c := NewConfig(paths, modes[:2], monitors)
+	c.Paths[1].TargetInfo.ModeInfoIdx = dc.DISPLAYCONFIG_PATH_MODE_IDX_INVALID
+
+	remapped, err := c.Remap([]Target{
+		{AdapterId: gpu1, Id: 7, Monitor: dell},
+		{AdapterId: gpu1, Id: 8, Monitor: lg},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range remapped.Paths {
+		if p.SourceInfo.AdapterId != gpu1 {
+			t.Errorf("path %d source is on %v, want %v", i, p.SourceInfo.AdapterId, gpu1)
+		}
+	}
+	if remapped.Modes[0].AdapterId != gpu1 {
+		t.Errorf("source mode is on %v, want %v", remapped.Modes[0].AdapterId, gpu1)
+	}
+}
+
+func TestRemapMergeAdapters(t *testing.T) {
+	paths, modes, monitors := testRaw()
+	// A third source already on the new GPU, numbered 1
+	paths = append(paths, paths[1])
+	paths[2].SourceInfo.AdapterId = gpu2
+	paths[2].SourceInfo.Id = 1
+	paths[2].SourceInfo.ModeInfoIdx = 6
+	paths[2].TargetInfo.AdapterId = gpu2
+	paths[2].TargetInfo.Id = 0x3300
+	paths[2].TargetInfo.ModeInfoIdx = dc.DISPLAYCONFIG_PATH_MODE_IDX_INVALID
+	modes = append(modes, modes[3])
+	modes[6].AdapterId = gpu2
+	modes[6].Id = 1
+	third := Monitor{Name: "BenQ PD2700U", DevicePath: `\\?\DISPLAY#BNQ7F4A#5&3c4d5e&0&UID4355`, ManufacturerId: 0xD109, ProductCode: 0x7F4A, ConnectorInstance: 1}
+	monitors = append(monitors, third)
+	c := NewConfig(paths, modes, monitors)
+
+	// Every monitor is now on the new GPU: the sources that were 0 on gpu0
+	// and gpu1 can't both stay 0, and 1 is kept by the source already there
+	remapped, err := c.Remap([]Target{
+		{AdapterId: gpu2, Id: 0x3301, Monitor: dell},
+		{AdapterId: gpu2, Id: 0x3302, Monitor: lg},
+		{AdapterId: gpu2, Id: 0x3300, Monitor: third},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[uint32]bool)
+	for i, want := range []uint32{0, 2, 1} {
+		src := remapped.Paths[i].SourceInfo
+		if src.AdapterId != gpu2 || src.Id != want {
+			t.Errorf("path %d source is %v/%d, want %v/%d", i, src.AdapterId, src.Id, gpu2, want)
+		}
+		if seen[src.Id] {
+			t.Errorf("path %d shares source %d", i, src.Id)
+		}
+		seen[src.Id] = true
+	}
+	for _, i := range []int{0, 3, 6} {
+		m := remapped.Modes[i]
+		want := remapped.Paths[i/3].SourceInfo.Id
+		if m.AdapterId != gpu2 || m.Id != want {
+			t.Errorf("source mode %d is %v/%d, want %v/%d", i, m.AdapterId, m.Id, gpu2, want)
+		}
+	}
+}
+
+func TestMatchMonitor(t *testing.T) {
+	twin := dell
+	twin.DevicePath = `\\?\DISPLAY#DELA0B4#5&9f9f9f&0&UID4400`
+	twin.ConnectorInstance = 2
+
+	// Identical monitors with serial numbers swapped between two ports
+	moved := dell
+	moved.ID = "DEL-A0B4-AAAA"
+	swapped := []Monitor{dell, twin}
+	swapped[0].ID = "DEL-A0B4-BBBB"
+	swapped[1].ID = "DEL-A0B4-AAAA"
+	shared := []Monitor{twin, dell}
+	shared[0].ID = "DEL-A0B4"
+	shared[1].ID = "DEL-A0B4"
+
+	tests := []struct {
+		name    string
+		m       Monitor
+		targets []Monitor
+		want    int
+	}{
+		{"stable ID", moved, swapped, 1},
+		{"shared stable ID", Monitor{ID: "DEL-A0B4", DevicePath: dell.DevicePath}, shared, 1},
+		{"device path", dell, []Monitor{lg, twin, dell}, 2},
+		{"EDID and connector", Monitor{ManufacturerId: dell.ManufacturerId, ProductCode: dell.ProductCode, ConnectorInstance: 2}, []Monitor{dell, twin}, 1},
+		{"unique EDID", Monitor{ManufacturerId: lg.ManufacturerId, ProductCode: lg.ProductCode, ConnectorInstance: 9}, []Monitor{dell, lg}, 1},
+		{"ambiguous EDID", Monitor{ManufacturerId: dell.ManufacturerId, ProductCode: dell.ProductCode, ConnectorInstance: 9}, []Monitor{dell, twin}, -1},
+		{"not connected", lg, []Monitor{dell, twin}, -1},
+	}
+	for _, tt := range tests {
+		targets := make([]Target, len(tt.targets))
+		for i, m := range tt.targets {
+			targets[i] = Target{Monitor: m}
+		}
+		if got := matchMonitor(tt.m, targets, make([]bool, len(targets))); got != tt.want {
+			t.Errorf("%s: matchMonitor = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	_, err := (&Config{Version: configVersion, Paths: []Path{{Monitor: lg}}}).Remap(nil)
+	if err == nil || !strings.Contains(err.Error(), "not connected") {
+		t.Errorf("Remap without the monitor: got %v, want a not connected error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package topology
+
+import (
+	"fmt"
+
+	dc "github.com/jipaix/lumos/displayconfig"
+)
+
+// Topology is one of the Windows display presets (Win+P)
+type Topology uint32
+
+const (
+	Internal Topology = dc.DISPLAYCONFIG_TOPOLOGY_INTERNAL
+	Clone    Topology = dc.DISPLAYCONFIG_TOPOLOGY_CLONE
+	Extend   Topology = dc.DISPLAYCONFIG_TOPOLOGY_EXTEND
+	External Topology = dc.DISPLAYCONFIG_TOPOLOGY_EXTERNAL
+)
+
+// Parse parses "internal", "clone", "extend" or "external"
+func Parse(s string) (Topology, error) {
+	switch s {
+	case "internal":
+		return Internal, nil
+	case "clone":
+		return Clone, nil
+	case "extend":
+		return Extend, nil
+	case "external":
+		return External, nil
+	}
+	return 0, fmt.Errorf("invalid topology: %s (must be 'extend', 'clone', 'internal', or 'external')", s)
+}
+
+func (t Topology) String() string {
+	switch t {
+	case Internal:
+		return "internal"
+	case Clone:
+		return "clone"
+	case Extend:
+		return "extend"
+	case External:
+		return "external"
+	}
+	return fmt.Sprintf("Topology(%d)", uint32(t))
+}
+
+// sdcFlag returns the SetDisplayConfig flag selecting the topology.
+// The SDC_TOPOLOGY_* values match the DISPLAYCONFIG_TOPOLOGY_* IDs.
+func (t Topology) sdcFlag() uint32 {
+	return uint32(t)
+}
+
+// Current returns the topology preset currently in use
+func Current() (Topology, error) {
+	_, _, id, err := dc.Query(dc.QDC_DATABASE_CURRENT)
+	if err != nil {
+		return 0, err
+	}
+	return Topology(id), nil
+}
+
+// Apply switches to a topology preset after validating it
+func Apply(t Topology) error {
+	if err := dc.Set(nil, nil, dc.SDC_VALIDATE|t.sdcFlag()); err != nil {
+		return fmt.Errorf("%s topology is not available: %v", t, err)
+	}
+	return dc.Set(nil, nil, dc.SDC_APPLY|t.sdcFlag())
+}